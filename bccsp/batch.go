@@ -0,0 +1,58 @@
+package bccsp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// VerifyItem 是批量验证中的一项，各个字段与Verify方法的参数相同。
+type VerifyItem struct {
+	Key       Key
+	Signature []byte
+	Digest    []byte
+	Opts      SignerOpts
+}
+
+// VerifyBatchOpts 包含批量验证签名的选项。
+type VerifyBatchOpts struct {
+	// Workers 是并发验证签名的协程数上限，不大于0时由实现决定。
+	Workers int
+	// FailFast 为true时，一旦有一项验证失败，尚未开始验证的项将被跳过，它们的结果为false。适用于只要有一个签名
+	// 不合法整批就不合法的场景，例如区块验证。
+	FailFast bool
+}
+
+// BatchVerifier 是BCCSP可以选择实现的接口，用于并行验证大量的签名。没有实现该接口的BCCSP可以通过VerifyBatch
+// 函数逐个验证。
+type BatchVerifier interface {
+	// VerifyBatch 验证items中的所有签名，返回的valid与items一一对应。验证出错的项的结果为false，err报告
+	// 其中下标最小的一项的错误，其他项的结果不受影响。opts为空时使用默认的选项。
+	VerifyBatch(items []VerifyItem, opts *VerifyBatchOpts) (valid []bool, err error)
+}
+
+// VerifyBatch 批量验证items中的签名，如果csp实现了BatchVerifier接口，则交给csp并行验证，否则依次调用
+// csp.Verify验证每一项。返回值的含义与BatchVerifier.VerifyBatch相同。
+func VerifyBatch(csp BCCSP, items []VerifyItem, opts *VerifyBatchOpts) (valid []bool, err error) {
+	if csp == nil {
+		return nil, errors.New("invalid BCCSP, it must not be nil")
+	}
+	if bv, ok := csp.(BatchVerifier); ok {
+		return bv.VerifyBatch(items, opts)
+	}
+	if opts == nil {
+		opts = &VerifyBatchOpts{}
+	}
+
+	valid = make([]bool, len(items))
+	for i, item := range items {
+		ok, verr := csp.Verify(item.Key, item.Signature, item.Digest, item.Opts)
+		if verr != nil && err == nil {
+			err = fmt.Errorf("failed verifying item [%d]: [%w]", i, verr)
+		}
+		valid[i] = ok && verr == nil
+		if !valid[i] && opts.FailFast {
+			break
+		}
+	}
+	return valid, err
+}
@@ -67,6 +67,12 @@ type EncrypterOpts interface{}
 // DecrypterOpts 包含用密码方案服务提供商(Cryptographic Service Provider, CSP)进行解密的选项。
 type DecrypterOpts interface{}
 
+// AggregateOpts 包含用密码方案服务提供商(Cryptographic Service Provider, CSP)聚合签名和公钥的选项。
+type AggregateOpts interface {
+	// Algorithm 返回聚合算法的标识符。
+	Algorithm() string
+}
+
 // BCCSP 是区块链加密服务提供商，提供加密标准和算法的实施。
 type BCCSP interface {
 	// KeyGen 根据给定的密钥生成选项生成一个密钥。
@@ -82,7 +88,7 @@ type BCCSP interface {
 	GetKey(ski []byte) (k Key, err error)
 
 	// Hash 根据给定的哈希算法选项求给定的消息的哈希值，如果选项是空的，则采用默认的哈希算法求哈希值。
-	Hash(msg []byte, opts HashOpts)
+	Hash(msg []byte, opts HashOpts) (hash []byte, err error)
 
 	// GetHash 根据给定的选项返回hash.Hash实例，如果选项是空的，则返回默认的哈希函数。
 	GetHash(opts HashOpts) (h hash.Hash, err error)
@@ -98,4 +104,23 @@ type BCCSP interface {
 
 	// Decrypt 利用给定的密钥k解密密文得到明文。
 	Decrypt(k Key, ciphertext []byte, opts DecrypterOpts) (plaintext []byte, err error)
+
+	// AggregateSignatures 根据聚合选项opts将多个签名聚合成一个签名，聚合算法由选项opts决定。
+	AggregateSignatures(signatures [][]byte, opts AggregateOpts) (signature []byte, err error)
+
+	// AggregatePublicKeys 根据聚合选项opts将多个公钥聚合成一个公钥，聚合后的公钥可以交给Verify验证这些公钥
+	// 对同一个摘要的聚合签名。
+	AggregatePublicKeys(keys []Key, opts AggregateOpts) (k Key, err error)
+
+	// FastAggregateVerify 验证多个公钥对同一个摘要digest的聚合签名signature，无论有多少个签名者，都只需要
+	// 验证一次签名。
+	FastAggregateVerify(keys []Key, signature, digest []byte, opts AggregateOpts) (valid bool, err error)
+}
+
+// KeyExporter 是BCCSP可以选择实现的接口，用于导出私钥和对称密钥的未加密的PEM编码，导出的PEM可以通过
+// PEMKeyImportOpts重新导入，例如用于密钥的备份与恢复。保存在硬件中的密钥无法导出，因此基于硬件的BCCSP不会
+// 实现该接口。不可导出的对称密钥（Bytes方法返回错误的密钥）同样不能通过该接口导出。
+type KeyExporter interface {
+	// KeyExport 返回密钥k的未加密的PEM编码。
+	KeyExport(k Key) (raw []byte, err error)
 }
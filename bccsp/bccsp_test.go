@@ -1,6 +1,10 @@
 package bccsp
 
 import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -46,7 +50,7 @@ func TestECDSAOpts(t *testing.T) {
 }
 
 func TestHashOpts(t *testing.T) {
-	for _, opts := range []HashOpts{&SHA256Opts{}, &SHA384Opts{}, &SHA3_256Opts{}, &SHA3_384Opts{}} {
+	for _, opts := range []HashOpts{&SHA256Opts{}, &SHA384Opts{}, &SHA3_256Opts{}, &SHA3_384Opts{}, &SM3Opts{}} {
 		s := strings.Replace(reflect.TypeOf(opts).String(), "*bccsp.", "", -1)
 		algorithm := strings.Replace(s, "Opts", "", -1)
 		require.Equal(t, algorithm, opts.Algorithm())
@@ -100,4 +104,110 @@ func TestKeyGenOpts(t *testing.T) {
 
 	test(true)
 	test(false)
-}
\ No newline at end of file
+}
+
+func TestECDHOpts(t *testing.T) {
+	opts := &ECDHKeyDerivOpts{}
+	require.False(t, opts.Ephemeral())
+	opts.Temporary = true
+	require.True(t, opts.Ephemeral())
+	require.Equal(t, "ECDH", opts.Algorithm())
+
+	// ANSI X9.63 KDF的测试向量（SHA-256，无共享信息）。
+	z, _ := hex.DecodeString("96c05619d56c328ab95fe84b18264b08725b85e33fd34f08")
+	require.Equal(t, "443024c3dae66b95e6f5670601558f71", hex.EncodeToString(ansiX963KDF(z, nil, 16)))
+
+	key, err := opts.DeriveKey(z)
+	require.NoError(t, err)
+	require.Len(t, key, 32)
+	require.Equal(t, ansiX963KDF(z, nil, 32), key)
+
+	opts.SharedInfo = []byte("gossip")
+	key2, err := opts.DeriveKey(z)
+	require.NoError(t, err)
+	require.NotEqual(t, key, key2)
+
+	opts.KDF = func(secret []byte) ([]byte, error) { return secret[:16], nil }
+	_, err = opts.DeriveKey(z)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid derived key length [16], must be 32 bytes")
+}
+
+func TestBLS12381Opts(t *testing.T) {
+	opts := &BLS12381KeyGenOpts{}
+	require.False(t, opts.Ephemeral())
+	opts.Temporary = true
+	require.True(t, opts.Ephemeral())
+	require.Equal(t, "BLS12381", opts.Algorithm())
+	require.Equal(t, "BLS12381", (&BLS12381AggregateOpts{}).Algorithm())
+	require.Equal(t, crypto.Hash(0), (&BLS12381ProofOfPossessionOpts{}).HashFunc())
+}
+
+func TestSchnorrOpts(t *testing.T) {
+	require.Equal(t, crypto.Hash(0), (&SchnorrSignerOpts{}).HashFunc())
+	require.Equal(t, crypto.Hash(0), (&MuSig2SignerOpts{}).HashFunc())
+	importOpts := &SchnorrPublicKeyImportOpts{}
+	require.True(t, importOpts.Ephemeral())
+	require.Equal(t, "SCHNORR", importOpts.Algorithm())
+	nonceOpts := &MuSig2NonceGenOpts{}
+	require.True(t, nonceOpts.Ephemeral())
+	require.Equal(t, "MUSIG2", nonceOpts.Algorithm())
+	require.Equal(t, "MUSIG2", (&MuSig2AggregateOpts{}).Algorithm())
+}
+
+func TestPEMKeyImportOpts(t *testing.T) {
+	opts := &PEMKeyImportOpts{}
+	require.False(t, opts.Ephemeral())
+	opts.Temporary = true
+	require.True(t, opts.Ephemeral())
+	require.Equal(t, "PEM", opts.Algorithm())
+}
+
+func TestHKDFOpts(t *testing.T) {
+	opts := &HKDFKeyDerivOpts{}
+	require.False(t, opts.Ephemeral())
+	opts.Temporary = true
+	require.True(t, opts.Ephemeral())
+	require.Equal(t, "HKDF", opts.Algorithm())
+}
+
+// serialVerifier 没有实现BatchVerifier接口，签名等于摘要时验证通过。
+type serialVerifier struct {
+	BCCSP
+	calls int
+}
+
+func (v *serialVerifier) Verify(k Key, signature, digest []byte, opts SignerOpts) (bool, error) {
+	v.calls++
+	if k == nil {
+		return false, errors.New("invalid Key, it must not be nil")
+	}
+	return bytes.Equal(signature, digest), nil
+}
+
+type testKey struct{ Key }
+
+func TestVerifyBatchFallback(t *testing.T) {
+	csp := &serialVerifier{}
+	items := []VerifyItem{
+		{Key: testKey{}, Signature: []byte("a"), Digest: []byte("a")},
+		{Key: testKey{}, Signature: []byte("b"), Digest: []byte("c")},
+		{Signature: []byte("d"), Digest: []byte("d")},
+		{Key: testKey{}, Signature: []byte("e"), Digest: []byte("e")},
+	}
+
+	valid, err := VerifyBatch(csp, items, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed verifying item [2]")
+	require.Equal(t, []bool{true, false, false, true}, valid)
+	require.Equal(t, 4, csp.calls)
+
+	csp.calls = 0
+	valid, err = VerifyBatch(csp, items, &VerifyBatchOpts{FailFast: true})
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false, false, false}, valid)
+	require.Equal(t, 2, csp.calls)
+
+	_, err = VerifyBatch(nil, items, nil)
+	require.Error(t, err)
+}
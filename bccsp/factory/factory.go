@@ -0,0 +1,120 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+var (
+	// defaultBCCSP 是通过InitFactories初始化的默认BCCSP，读写都需要持有factoriesLock。
+	defaultBCCSP  bccsp.BCCSP
+	factoriesLock sync.RWMutex
+	// bootBCCSP 是在InitFactories被调用之前就需要使用BCCSP时的后备BCCSP。
+	bootBCCSP bccsp.BCCSP
+
+	factoriesInitOnce sync.Once
+	bootBCCSPInitOnce sync.Once
+
+	factoriesInitError error
+)
+
+// BCCSPFactory 用于创建BCCSP实例。
+type BCCSPFactory interface {
+	// Name 返回工厂的名字。
+	Name() string
+
+	// Get 根据选项opts创建一个BCCSP实例。
+	Get(opts *FactoryOpts) (bccsp.BCCSP, error)
+}
+
+// GetDefault 返回默认的BCCSP，如果InitFactories还没有被调用过，则返回一个以默认选项创建的基于软件的BCCSP。
+func GetDefault() bccsp.BCCSP {
+	factoriesLock.RLock()
+	csp := defaultBCCSP
+	factoriesLock.RUnlock()
+
+	if csp == nil {
+		bootBCCSPInitOnce.Do(func() {
+			var err error
+			bootBCCSP, err = (&SWFactory{}).Get(GetDefaultOpts())
+			if err != nil {
+				panic(fmt.Sprintf("BCCSP internal error, failed initialization with GetDefaultOpts: %s", err))
+			}
+		})
+		return bootBCCSP
+	}
+	return csp
+}
+
+// InitFactories 根据选项config初始化默认的BCCSP，该函数只有第一次被调用时才会生效，之后的调用都会返回
+// 第一次初始化的结果。config为空时使用GetDefaultOpts返回的选项。
+func InitFactories(config *FactoryOpts) error {
+	factoriesInitOnce.Do(func() {
+		factoriesInitError = initFactories(config)
+	})
+
+	return factoriesInitError
+}
+
+func initFactories(config *FactoryOpts) error {
+	config = completeOpts(config)
+
+	csp, err := GetBCCSPFromOpts(config)
+	if err != nil {
+		return fmt.Errorf("failed initializing BCCSP factories: [%w]", err)
+	}
+
+	factoriesLock.Lock()
+	defaultBCCSP = csp
+	factoriesLock.Unlock()
+
+	return nil
+}
+
+// GetBCCSPFromOpts 根据选项config创建一个新的BCCSP实例，它不会影响默认的BCCSP。
+func GetBCCSPFromOpts(config *FactoryOpts) (bccsp.BCCSP, error) {
+	config = completeOpts(config)
+
+	var f BCCSPFactory
+	switch config.Default {
+	case SoftwareBasedFactoryName:
+		f = &SWFactory{}
+	case PKCS11BasedFactoryName:
+		f = &PKCS11Factory{}
+	default:
+		return nil, fmt.Errorf("could not find BCCSP, no '%s' provider", config.Default)
+	}
+
+	return initBCCSP(f, config)
+}
+
+func initBCCSP(f BCCSPFactory, config *FactoryOpts) (bccsp.BCCSP, error) {
+	csp, err := f.Get(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize BCCSP %s: [%w]", f.Name(), err)
+	}
+	if csp == nil {
+		return nil, errors.New("factory returned a nil BCCSP")
+	}
+
+	return csp, nil
+}
+
+// completeOpts 为选项中缺失的部分填上默认值。
+func completeOpts(config *FactoryOpts) *FactoryOpts {
+	if config == nil {
+		return GetDefaultOpts()
+	}
+
+	completed := *config
+	if completed.Default == "" {
+		completed.Default = SoftwareBasedFactoryName
+	}
+	if completed.SW == nil {
+		completed.SW = GetDefaultOpts().SW
+	}
+	return &completed
+}
@@ -0,0 +1,167 @@
+package factory
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+// resetFactories 撤销InitFactories的效果，使得测试可以重复执行。
+func resetFactories() {
+	factoriesLock.Lock()
+	defaultBCCSP = nil
+	factoriesLock.Unlock()
+	factoriesInitOnce = sync.Once{}
+	factoriesInitError = nil
+}
+
+func TestGetDefaultBeforeInit(t *testing.T) {
+	csp := GetDefault()
+	require.NotNil(t, csp)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	require.True(t, k.Private())
+}
+
+func TestInitFactories(t *testing.T) {
+	t.Cleanup(resetFactories)
+	dir := t.TempDir()
+	opts := &FactoryOpts{
+		Default: "SW",
+		SW: &SwOpts{
+			Security:     384,
+			Hash:         bccsp.SHA3,
+			FileKeystore: &FileKeystoreOpts{KeyStorePath: dir},
+		},
+	}
+	require.NoError(t, InitFactories(opts))
+
+	csp := GetDefault()
+	require.NotNil(t, csp)
+	require.NotEqual(t, bootBCCSP, csp)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	k2, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k2.SKI())
+
+	// 只有第一次调用会生效。
+	require.NoError(t, InitFactories(&FactoryOpts{Default: "unknown"}))
+	require.Equal(t, csp, GetDefault())
+}
+
+func TestGetDefaultConcurrentInit(t *testing.T) {
+	t.Cleanup(resetFactories)
+
+	var (
+		wg      sync.WaitGroup
+		nils    int
+		initErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if GetDefault() == nil {
+				nils++
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		initErr = InitFactories(nil)
+	}()
+	wg.Wait()
+	require.NoError(t, initErr)
+	require.Zero(t, nils)
+	require.NotEqual(t, bootBCCSP, GetDefault())
+}
+
+func TestGetBCCSPFromOpts(t *testing.T) {
+	csp, err := GetBCCSPFromOpts(nil)
+	require.NoError(t, err)
+	require.NotNil(t, csp)
+
+	csp, err = GetBCCSPFromOpts(&FactoryOpts{})
+	require.NoError(t, err)
+	require.NotNil(t, csp)
+
+	_, err = GetBCCSPFromOpts(&FactoryOpts{Default: "unknown"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "could not find BCCSP, no 'unknown' provider")
+
+	_, err = GetBCCSPFromOpts(&FactoryOpts{Default: "SW", SW: &SwOpts{Security: 128, Hash: bccsp.SHA2}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "could not initialize BCCSP SW")
+
+	// 两次创建的BCCSP互不影响。
+	csp1, err := GetBCCSPFromOpts(GetDefaultOpts())
+	require.NoError(t, err)
+	csp2, err := GetBCCSPFromOpts(GetDefaultOpts())
+	require.NoError(t, err)
+	k, err := csp1.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	_, err = csp2.GetKey(k.SKI())
+	require.Error(t, err)
+}
+
+func TestReadFactoryOpts(t *testing.T) {
+	yamlOpts := []byte(`
+default: SW
+SW:
+  security: 384
+  hash: SHA3
+  filekeystore:
+    keystore: /tmp/keystore
+PKCS11:
+  security: 256
+  hash: SHA2
+  library: /usr/lib/softhsm/libsofthsm2.so
+  label: ForLark
+  pin: "98765432"
+  softwareverify: true
+`)
+	opts, err := ReadFactoryOptsFromYAML(yamlOpts)
+	require.NoError(t, err)
+	require.Equal(t, "SW", opts.FactoryName())
+	require.Equal(t, 384, opts.SW.Security)
+	require.Equal(t, bccsp.SHA3, opts.SW.Hash)
+	require.Equal(t, "/tmp/keystore", opts.SW.FileKeystore.KeyStorePath)
+	require.Equal(t, "/usr/lib/softhsm/libsofthsm2.so", opts.PKCS11.Library)
+	require.Equal(t, "ForLark", opts.PKCS11.Label)
+	require.Equal(t, "98765432", opts.PKCS11.Pin)
+	require.True(t, opts.PKCS11.SoftwareVerify)
+
+	jsonOpts := []byte(`{"default":"SW","SW":{"security":256,"hash":"SHA2","filekeystore":{"keystore":"/tmp/keystore"}}}`)
+	opts, err = ReadFactoryOptsFromJSON(jsonOpts)
+	require.NoError(t, err)
+	require.Equal(t, 256, opts.SW.Security)
+	require.Equal(t, bccsp.SHA2, opts.SW.Hash)
+	require.Equal(t, "/tmp/keystore", opts.SW.FileKeystore.KeyStorePath)
+	require.Nil(t, opts.PKCS11)
+
+	_, err = ReadFactoryOptsFromYAML([]byte("default: [SW"))
+	require.Error(t, err)
+	_, err = ReadFactoryOptsFromJSON([]byte("{"))
+	require.Error(t, err)
+}
+
+func TestPKCS11FactoryInvalidOpts(t *testing.T) {
+	f := &PKCS11Factory{}
+	require.Equal(t, PKCS11BasedFactoryName, f.Name())
+
+	_, err := f.Get(&FactoryOpts{Default: PKCS11BasedFactoryName})
+	require.Error(t, err)
+
+	_, err = GetBCCSPFromOpts(&FactoryOpts{
+		Default: PKCS11BasedFactoryName,
+		PKCS11:  &pkcs11.PKCS11Opts{Security: 256, Hash: bccsp.SHA2, Library: "/no/such/library.so", Label: "ForLark"},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "could not initialize BCCSP PKCS11")
+}
@@ -0,0 +1,59 @@
+package factory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/pkcs11"
+	"gopkg.in/yaml.v3"
+)
+
+// FactoryOpts 包含初始化BCCSP工厂的选项，Default指定默认使用的BCCSP的名字，SW和PKCS11分别是基于
+// 软件的BCCSP和基于PKCS#11的BCCSP的选项。
+type FactoryOpts struct {
+	Default string             `json:"default" yaml:"default"`
+	SW      *SwOpts            `json:"SW,omitempty" yaml:"SW,omitempty"`
+	PKCS11  *pkcs11.PKCS11Opts `json:"PKCS11,omitempty" yaml:"PKCS11,omitempty"`
+}
+
+// GetDefaultOpts 返回默认的选项：基于软件的BCCSP，安全级别为256，哈希族为SHA2，密钥存储在内存中。
+func GetDefaultOpts() *FactoryOpts {
+	return &FactoryOpts{
+		Default: SoftwareBasedFactoryName,
+		SW: &SwOpts{
+			Security: 256,
+			Hash:     bccsp.SHA2,
+		},
+	}
+}
+
+// FactoryName 返回工厂的名字，它等于Default字段的值。
+func (o *FactoryOpts) FactoryName() string {
+	return o.Default
+}
+
+// ReadFactoryOptsFromYAML 从YAML格式的配置中解析出FactoryOpts，例如：
+//
+//	default: SW
+//	SW:
+//	  security: 256
+//	  hash: SHA2
+//	  filekeystore:
+//	    keystore: /var/lark/msp/keystore
+func ReadFactoryOptsFromYAML(raw []byte) (*FactoryOpts, error) {
+	opts := &FactoryOpts{}
+	if err := yaml.Unmarshal(raw, opts); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling YAML factory options: [%w]", err)
+	}
+	return opts, nil
+}
+
+// ReadFactoryOptsFromJSON 从JSON格式的配置中解析出FactoryOpts。
+func ReadFactoryOptsFromJSON(raw []byte) (*FactoryOpts, error) {
+	opts := &FactoryOpts{}
+	if err := json.Unmarshal(raw, opts); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling JSON factory options: [%w]", err)
+	}
+	return opts, nil
+}
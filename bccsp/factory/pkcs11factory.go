@@ -0,0 +1,37 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/pkcs11"
+	"github.com/232425wxy/lark/bccsp/sw"
+)
+
+const (
+	// PKCS11BasedFactoryName 是基于PKCS#11的BCCSP工厂的名字。
+	PKCS11BasedFactoryName = "PKCS11"
+)
+
+// PKCS11Factory 是创建基于PKCS#11的BCCSP的工厂。
+type PKCS11Factory struct{}
+
+// Name 返回工厂的名字。
+func (f *PKCS11Factory) Name() string {
+	return PKCS11BasedFactoryName
+}
+
+// Get 根据选项中的PKCS11部分创建一个基于PKCS#11的BCCSP，交给软件实现处理的密钥存储在内存中。
+func (f *PKCS11Factory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config == nil || config.PKCS11 == nil {
+		return nil, errors.New("invalid config, it must not be nil")
+	}
+
+	csp, err := pkcs11.New(*config.PKCS11, sw.NewInMemoryKeyStore())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PKCS11 BCCSP: [%w]", err)
+	}
+
+	return csp, nil
+}
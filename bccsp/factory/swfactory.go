@@ -0,0 +1,62 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/sw"
+)
+
+const (
+	// SoftwareBasedFactoryName 是基于软件的BCCSP工厂的名字。
+	SoftwareBasedFactoryName = "SW"
+)
+
+// SWFactory 是创建基于软件的BCCSP的工厂。
+type SWFactory struct{}
+
+// Name 返回工厂的名字。
+func (f *SWFactory) Name() string {
+	return SoftwareBasedFactoryName
+}
+
+// Get 根据选项中的SW部分创建一个基于软件的BCCSP。如果配置了FileKeystore，密钥存储在指定的目录下，
+// 否则密钥存储在内存中。
+func (f *SWFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config == nil || config.SW == nil {
+		return nil, errors.New("invalid config, it must not be nil")
+	}
+
+	swOpts := config.SW
+
+	var ks bccsp.KeyStore
+	switch {
+	case swOpts.FileKeystore != nil:
+		fks, err := sw.NewFileBasedKeyStore(swOpts.FileKeystore.KeyStorePath, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize software key store: [%w]", err)
+		}
+		ks = fks
+	default:
+		ks = sw.NewInMemoryKeyStore()
+	}
+
+	return sw.NewWithParams(swOpts.Security, swOpts.Hash, ks)
+}
+
+// SwOpts 包含基于软件的BCCSP的选项。
+type SwOpts struct {
+	// Security 是安全级别，取值为256或384。
+	Security int `json:"security" yaml:"security"`
+	// Hash 是哈希族，取值为SHA2或SHA3。
+	Hash string `json:"hash" yaml:"hash"`
+	// FileKeystore 不为空时，密钥存储在文件系统中。
+	FileKeystore *FileKeystoreOpts `json:"filekeystore,omitempty" yaml:"filekeystore,omitempty"`
+}
+
+// FileKeystoreOpts 包含基于文件系统的KeyStore的选项。
+type FileKeystoreOpts struct {
+	// KeyStorePath 是存放密钥文件的目录。
+	KeyStorePath string `json:"keystore" yaml:"keystore"`
+}
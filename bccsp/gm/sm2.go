@@ -0,0 +1,179 @@
+package gm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// DefaultUID 是GM/T 0009-2012规定的默认用户标识，在没有指定用户标识时用于计算Z值。
+var DefaultUID = []byte("1234567812345678")
+
+var (
+	sm2P256     *elliptic.CurveParams
+	sm2P256Once sync.Once
+)
+
+func initSM2P256() {
+	sm2P256 = &elliptic.CurveParams{Name: "SM2-P-256", BitSize: 256}
+	sm2P256.P, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF", 16)
+	sm2P256.N, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+	sm2P256.B, _ = new(big.Int).SetString("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93", 16)
+	sm2P256.Gx, _ = new(big.Int).SetString("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7", 16)
+	sm2P256.Gy, _ = new(big.Int).SetString("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0", 16)
+}
+
+// P256SM2 返回GB/T 32918-2016推荐的256位椭圆曲线sm2p256v1。该曲线的参数a等于p-3，所以可以直接使用
+// elliptic.CurveParams提供的通用实现。
+func P256SM2() elliptic.Curve {
+	sm2P256Once.Do(initSM2P256)
+	return sm2P256
+}
+
+// IsSM2Curve 判断curve是否是sm2p256v1曲线。
+func IsSM2Curve(curve elliptic.Curve) bool {
+	return curve != nil && curve.Params() == P256SM2().Params()
+}
+
+// GenerateKey 生成一个SM2私钥，私钥d的取值范围是[1, n-2]，以保证1+d可逆。
+func GenerateKey(rand io.Reader) (*ecdsa.PrivateKey, error) {
+	c := P256SM2()
+	n := new(big.Int).Sub(c.Params().N, big.NewInt(1))
+	d, err := randScalar(rand, n)
+	if err != nil {
+		return nil, err
+	}
+
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.PublicKey.Curve = c
+	priv.PublicKey.X, priv.PublicKey.Y = c.ScalarBaseMult(d.Bytes())
+	return priv, nil
+}
+
+// randScalar 返回[1, max-1]范围内的随机数。多读取的64比特使得取模以后的偏差可以忽略不计。
+func randScalar(rand io.Reader, max *big.Int) (*big.Int, error) {
+	b := make([]byte, max.BitLen()/8+8)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+
+	k := new(big.Int).SetBytes(b)
+	k.Mod(k, new(big.Int).Sub(max, big.NewInt(1)))
+	k.Add(k, big.NewInt(1))
+	return k, nil
+}
+
+// ZA 计算用户标识为uid、公钥为pub的用户的杂凑值：
+//
+//	ZA = SM3(ENTLA || IDA || a || b || xG || yG || xA || yA)
+//
+// 其中ENTLA是用两个字节表示的uid的比特长度。uid为空时使用DefaultUID。
+func ZA(pub *ecdsa.PublicKey, uid []byte) ([]byte, error) {
+	if pub == nil || !IsSM2Curve(pub.Curve) {
+		return nil, errors.New("invalid public key, it must be on the SM2 curve")
+	}
+	if len(uid) == 0 {
+		uid = DefaultUID
+	}
+	if len(uid) >= 1<<13 {
+		return nil, errors.New("invalid uid, it is too long")
+	}
+
+	params := pub.Curve.Params()
+	a := new(big.Int).Sub(params.P, big.NewInt(3))
+
+	h := NewSM3()
+	entl := len(uid) * 8
+	h.Write([]byte{byte(entl >> 8), byte(entl)})
+	h.Write(uid)
+	for _, v := range []*big.Int{a, params.B, params.Gx, params.Gy, pub.X, pub.Y} {
+		h.Write(v.FillBytes(make([]byte, 32)))
+	}
+	return h.Sum(nil), nil
+}
+
+// MessageDigest 计算待签名的摘要e = SM3(ZA || msg)。
+func MessageDigest(pub *ecdsa.PublicKey, uid, msg []byte) ([]byte, error) {
+	za, err := ZA(pub, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	h := NewSM3()
+	h.Write(za)
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+// Sign 利用SM2私钥对摘要digest进行签名，digest通常由MessageDigest计算得到。
+func Sign(rand io.Reader, priv *ecdsa.PrivateKey, digest []byte) (r, s *big.Int, err error) {
+	if priv == nil || !IsSM2Curve(priv.Curve) {
+		return nil, nil, errors.New("invalid private key, it must be on the SM2 curve")
+	}
+
+	c := priv.Curve
+	n := c.Params().N
+	// 私钥的取值范围是[1, n-2]，d = n-1时1 + d不可逆。
+	if priv.D == nil || priv.D.Sign() <= 0 || priv.D.Cmp(new(big.Int).Sub(n, big.NewInt(1))) >= 0 {
+		return nil, nil, errors.New("invalid private key value, it must be in [1, n-2]")
+	}
+	e := new(big.Int).SetBytes(digest)
+	dInv := new(big.Int).Add(priv.D, big.NewInt(1))
+	dInv.ModInverse(dInv, n)
+
+	for {
+		k, err := randScalar(rand, n)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		x1, _ := c.ScalarBaseMult(k.Bytes())
+		r = new(big.Int).Add(e, x1)
+		r.Mod(r, n)
+		if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+			continue
+		}
+
+		// s = (1 + d)^-1 * (k - r * d) mod n
+		s = new(big.Int).Mul(r, priv.D)
+		s.Sub(k, s)
+		s.Mul(s, dInv)
+		s.Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+
+		return r, s, nil
+	}
+}
+
+// Verify 利用SM2公钥验证摘要digest的签名(r, s)。
+func Verify(pub *ecdsa.PublicKey, digest []byte, r, s *big.Int) bool {
+	if pub == nil || !IsSM2Curve(pub.Curve) || r == nil || s == nil {
+		return false
+	}
+
+	c := pub.Curve
+	n := c.Params().N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+
+	x1, y1 := c.ScalarBaseMult(s.Bytes())
+	x2, y2 := c.ScalarMult(pub.X, pub.Y, t.Bytes())
+	x, _ := c.Add(x1, y1, x2, y2)
+
+	e := new(big.Int).SetBytes(digest)
+	e.Add(e, x)
+	e.Mod(e, n)
+	return e.Cmp(r) == 0
+}
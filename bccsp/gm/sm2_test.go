@@ -0,0 +1,173 @@
+package gm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM2SignVerify(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.True(t, IsSM2Curve(priv.Curve))
+	require.True(t, priv.Curve.IsOnCurve(priv.X, priv.Y))
+
+	msg := []byte("message digest")
+	digest, err := MessageDigest(&priv.PublicKey, nil, msg)
+	require.NoError(t, err)
+
+	r, s, err := Sign(rand.Reader, priv, digest)
+	require.NoError(t, err)
+	require.True(t, Verify(&priv.PublicKey, digest, r, s))
+
+	// 不同的用户标识得到不同的摘要，签名无法通过验证。
+	other, err := MessageDigest(&priv.PublicKey, []byte("ALICE123@YAHOO.COM"), msg)
+	require.NoError(t, err)
+	require.NotEqual(t, digest, other)
+	require.False(t, Verify(&priv.PublicKey, other, r, s))
+
+	// 省略用户标识等价于使用默认的用户标识。
+	withDefault, err := MessageDigest(&priv.PublicKey, DefaultUID, msg)
+	require.NoError(t, err)
+	require.Equal(t, digest, withDefault)
+
+	require.False(t, Verify(&priv.PublicKey, digest, s, r))
+	require.False(t, Verify(&priv.PublicKey, digest, r, new(big.Int).Neg(s)))
+	require.False(t, Verify(&priv.PublicKey, digest, r, priv.Curve.Params().N))
+
+	another, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.False(t, Verify(&another.PublicKey, digest, r, s))
+
+	// 其他曲线上的密钥不能用于SM2。
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, _, err = Sign(rand.Reader, p256, digest)
+	require.Error(t, err)
+	_, err = ZA(&p256.PublicKey, nil)
+	require.Error(t, err)
+
+	// d = n-1时1 + d不可逆，这样的私钥不能用于签名。
+	invalid := *priv
+	invalid.D = new(big.Int).Sub(priv.Curve.Params().N, big.NewInt(1))
+	_, _, err = Sign(rand.Reader, &invalid, digest)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid private key value")
+}
+
+// TestSM2KnownAnswer 使用GM/T 0003.5附录A中sm2p256v1曲线上的签名示例。
+func TestSM2KnownAnswer(t *testing.T) {
+	hexInt := func(s string) *big.Int {
+		v, ok := new(big.Int).SetString(s, 16)
+		require.True(t, ok)
+		return v
+	}
+
+	c := P256SM2()
+	priv := &ecdsa.PrivateKey{D: hexInt("3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8")}
+	priv.Curve = c
+	priv.X, priv.Y = c.ScalarBaseMult(priv.D.Bytes())
+	require.Equal(t, hexInt("09F9DF311E5421A150DD7D161E4BC5C672179FAD1833FC076BB08FF356F35020"), priv.X)
+	require.Equal(t, hexInt("CCEA490CE26775A52DC6EA718CC1AA600AED05FBF35E084A6632F6072DA9AD13"), priv.Y)
+
+	uid := []byte("1234567812345678")
+	za, err := ZA(&priv.PublicKey, uid)
+	require.NoError(t, err)
+	require.Equal(t, "b2e14c5c79c6df5b85f4fe7ed8db7a262b9da7e07ccb0ea9f4747b8ccda8a4f3", hex.EncodeToString(za))
+	digest, err := MessageDigest(&priv.PublicKey, uid, []byte("message digest"))
+	require.NoError(t, err)
+	require.Equal(t, "f0b43e94ba45accaace692ed534382eb17e6ab5a19ce7b31f4486fdfc0d28640", hex.EncodeToString(digest))
+
+	r := hexInt("F5A03B0648D2C4630EEAC513E1BB81A15944DA3827D5B74143AC7EACEEE720B3")
+	s := hexInt("B1B6AA29DF212FD8763182BC0D421CA1BB9038FD1F7F42D4840B69C485BBC1AA")
+	require.True(t, Verify(&priv.PublicKey, digest, r, s))
+
+	// 固定随机数k时签名是确定的。randScalar返回读取的数模(n-1)再加1，所以读取k-1得到的就是k。
+	k := hexInt("59276E27D506861A16680F3AD9C02DCCEF3CC1FA3CDBE4CE6D54B80DEAC1BC21")
+	kReader := bytes.NewReader(new(big.Int).Sub(k, big.NewInt(1)).FillBytes(make([]byte, c.Params().N.BitLen()/8+8)))
+	r2, s2, err := Sign(kReader, priv, digest)
+	require.NoError(t, err)
+	require.Equal(t, r, r2)
+	require.Equal(t, s, s2)
+}
+
+func TestSM2Encoding(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+	pub, err := ParsePKIXPublicKey(der)
+	require.NoError(t, err)
+	require.Equal(t, priv.X, pub.X)
+	require.Equal(t, priv.Y, pub.Y)
+
+	der, err = MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	parsed, err := ParsePKCS8PrivateKey(der)
+	require.NoError(t, err)
+	require.Equal(t, priv.D, parsed.D)
+	require.Equal(t, priv.X, parsed.X)
+
+	// 标准库无法解析SM2密钥，SM2的解析函数也不接受其他曲线上的密钥。
+	_, err = x509.ParsePKCS8PrivateKey(der)
+	require.Error(t, err)
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p256DER, err := x509.MarshalPKIXPublicKey(&p256.PublicKey)
+	require.NoError(t, err)
+	_, err = ParsePKIXPublicKey(p256DER)
+	require.Error(t, err)
+	_, err = MarshalPKIXPublicKey(&p256.PublicKey)
+	require.Error(t, err)
+}
+
+func TestParseCertificatePublicKey(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	spki, err := MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+
+	name, err := asn1.Marshal(pkix.Name{CommonName: "lark"}.ToRDNSequence())
+	require.NoError(t, err)
+	validity, err := asn1.Marshal(struct{ NotBefore, NotAfter asn1.RawValue }{
+		asn1.RawValue{Tag: asn1.TagUTCTime, Bytes: []byte("250101000000Z")},
+		asn1.RawValue{Tag: asn1.TagUTCTime, Bytes: []byte("350101000000Z")},
+	})
+	require.NoError(t, err)
+	sigAlg := pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}}
+	certDER, err := asn1.Marshal(certificate{
+		TBSCertificate: tbsCertificate{
+			Version:            2,
+			SerialNumber:       big.NewInt(1),
+			SignatureAlgorithm: sigAlg,
+			Issuer:             asn1.RawValue{FullBytes: name},
+			Validity:           asn1.RawValue{FullBytes: validity},
+			Subject:            asn1.RawValue{FullBytes: name},
+			PublicKey:          asn1.RawValue{FullBytes: spki},
+		},
+		SignatureAlgorithm: sigAlg,
+		SignatureValue:     asn1.BitString{Bytes: []byte{0}, BitLength: 8},
+	})
+	require.NoError(t, err)
+
+	_, err = x509.ParseCertificate(certDER)
+	require.Error(t, err)
+
+	pub, err := ParseCertificatePublicKey(certDER)
+	require.NoError(t, err)
+	require.Equal(t, priv.X, pub.X)
+	require.Equal(t, priv.Y, pub.Y)
+
+	_, err = ParseCertificatePublicKey([]byte{1, 2, 3})
+	require.Error(t, err)
+}
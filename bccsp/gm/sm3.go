@@ -0,0 +1,155 @@
+package gm
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+const (
+	// SM3Size SM3哈希值的字节长度。
+	SM3Size = 32
+
+	// SM3BlockSize SM3算法分组的字节长度。
+	SM3BlockSize = 64
+)
+
+var sm3IV = [8]uint32{
+	0x7380166f, 0x4914b2b9, 0x172442d7, 0xda8a0600,
+	0xa96f30bc, 0x163138aa, 0xe38dee4d, 0xb0fb0e4e,
+}
+
+// sm3Digest 是GB/T 32905-2016定义的SM3密码杂凑算法的实现。
+type sm3Digest struct {
+	h   [8]uint32
+	x   [SM3BlockSize]byte
+	nx  int
+	len uint64
+}
+
+// NewSM3 返回一个计算SM3哈希值的hash.Hash。
+func NewSM3() hash.Hash {
+	d := new(sm3Digest)
+	d.Reset()
+	return d
+}
+
+// SumSM3 返回数据data的SM3哈希值。
+func SumSM3(data []byte) [SM3Size]byte {
+	d := new(sm3Digest)
+	d.Reset()
+	d.Write(data)
+	var sum [SM3Size]byte
+	d.checkSum(sum[:0])
+	return sum
+}
+
+func (d *sm3Digest) Reset() {
+	d.h = sm3IV
+	d.nx = 0
+	d.len = 0
+}
+
+func (d *sm3Digest) Size() int { return SM3Size }
+
+func (d *sm3Digest) BlockSize() int { return SM3BlockSize }
+
+func (d *sm3Digest) Write(p []byte) (n int, err error) {
+	n = len(p)
+	d.len += uint64(n)
+	if d.nx > 0 {
+		c := copy(d.x[d.nx:], p)
+		d.nx += c
+		if d.nx == SM3BlockSize {
+			d.block(d.x[:])
+			d.nx = 0
+		}
+		p = p[c:]
+	}
+	for len(p) >= SM3BlockSize {
+		d.block(p[:SM3BlockSize])
+		p = p[SM3BlockSize:]
+	}
+	if len(p) > 0 {
+		d.nx = copy(d.x[:], p)
+	}
+	return n, nil
+}
+
+func (d *sm3Digest) Sum(in []byte) []byte {
+	// 在副本上完成填充，使得调用者可以继续写入数据。
+	d0 := *d
+	return d0.checkSum(in)
+}
+
+func (d *sm3Digest) checkSum(in []byte) []byte {
+	length := d.len
+	var tmp [SM3BlockSize + 8]byte
+	tmp[0] = 0x80
+	padLen := 56 - length%64
+	if length%64 >= 56 {
+		padLen += 64
+	}
+	binary.BigEndian.PutUint64(tmp[padLen:], length<<3)
+	d.Write(tmp[:padLen+8])
+
+	var out [SM3Size]byte
+	for i, v := range d.h {
+		binary.BigEndian.PutUint32(out[i*4:], v)
+	}
+	return append(in, out[:]...)
+}
+
+func sm3P0(x uint32) uint32 { return x ^ bits.RotateLeft32(x, 9) ^ bits.RotateLeft32(x, 17) }
+
+func sm3P1(x uint32) uint32 { return x ^ bits.RotateLeft32(x, 15) ^ bits.RotateLeft32(x, 23) }
+
+// block 对一个64字节的分组执行压缩函数。
+func (d *sm3Digest) block(p []byte) {
+	var w [68]uint32
+	var w1 [64]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[i*4:])
+	}
+	for i := 16; i < 68; i++ {
+		w[i] = sm3P1(w[i-16]^w[i-9]^bits.RotateLeft32(w[i-3], 15)) ^ bits.RotateLeft32(w[i-13], 7) ^ w[i-6]
+	}
+	for i := 0; i < 64; i++ {
+		w1[i] = w[i] ^ w[i+4]
+	}
+
+	a, b, c, dd, e, f, g, h := d.h[0], d.h[1], d.h[2], d.h[3], d.h[4], d.h[5], d.h[6], d.h[7]
+	for i := 0; i < 64; i++ {
+		var t, ff, gg uint32
+		if i < 16 {
+			t = 0x79cc4519
+			ff = a ^ b ^ c
+			gg = e ^ f ^ g
+		} else {
+			t = 0x7a879d8a
+			ff = (a & b) | (a & c) | (b & c)
+			gg = (e & f) | (^e & g)
+		}
+		ss1 := bits.RotateLeft32(bits.RotateLeft32(a, 12)+e+bits.RotateLeft32(t, i%32), 7)
+		ss2 := ss1 ^ bits.RotateLeft32(a, 12)
+		tt1 := ff + dd + ss2 + w1[i]
+		tt2 := gg + h + ss1 + w[i]
+		dd = c
+		c = bits.RotateLeft32(b, 9)
+		b = a
+		a = tt1
+		h = g
+		g = bits.RotateLeft32(f, 19)
+		f = e
+		e = sm3P0(tt2)
+	}
+
+	d.h[0] ^= a
+	d.h[1] ^= b
+	d.h[2] ^= c
+	d.h[3] ^= dd
+	d.h[4] ^= e
+	d.h[5] ^= f
+	d.h[6] ^= g
+	d.h[7] ^= h
+}
@@ -0,0 +1,37 @@
+package gm
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM3(t *testing.T) {
+	// GB/T 32905-2016 附录A中的示例。
+	for _, tc := range []struct {
+		msg      string
+		expected string
+	}{
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	} {
+		sum := SumSM3([]byte(tc.msg))
+		require.Equal(t, tc.expected, hex.EncodeToString(sum[:]))
+
+		// 分多次写入的结果与一次写入相同，并且Sum不影响后续的写入。
+		h := NewSM3()
+		for i := 0; i < len(tc.msg); i++ {
+			h.Write([]byte{tc.msg[i]})
+			h.Sum(nil)
+		}
+		require.Equal(t, tc.expected, hex.EncodeToString(h.Sum(nil)))
+
+		h.Reset()
+		h.Write([]byte(tc.msg))
+		require.Equal(t, tc.expected, hex.EncodeToString(h.Sum(nil)))
+	}
+	require.Equal(t, SM3Size, NewSM3().Size())
+	require.Equal(t, SM3BlockSize, NewSM3().BlockSize())
+}
@@ -0,0 +1,32 @@
+package gm
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM4(t *testing.T) {
+	// GB/T 32907-2016 附录A中的示例。
+	key, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	c, err := NewSM4Cipher(key)
+	require.NoError(t, err)
+	require.Equal(t, SM4BlockSize, c.BlockSize())
+
+	dst := make([]byte, SM4BlockSize)
+	c.Encrypt(dst, key)
+	require.Equal(t, "681edf34d206965e86b3e94f536e4246", hex.EncodeToString(dst))
+	c.Decrypt(dst, dst)
+	require.Equal(t, key, dst)
+
+	// 用同一个密钥加密1000000次。
+	copy(dst, key)
+	for i := 0; i < 1000000; i++ {
+		c.Encrypt(dst, dst)
+	}
+	require.Equal(t, "595298c7c6fd271f0402f804c33d3f66", hex.EncodeToString(dst))
+
+	_, err = NewSM4Cipher(key[:8])
+	require.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package gm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// OIDNamedCurveSM2 是sm2p256v1曲线的对象标识符。
+	OIDNamedCurveSM2 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+// Go标准库的crypto/x509不认识sm2p256v1曲线，所以SM2密钥的PKIX和PKCS#8编码需要在这里实现，编码的格式与
+// ECDSA密钥相同，只是曲线的对象标识符不同。
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type certificate struct {
+	TBSCertificate     tbsCertificate
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type tbsCertificate struct {
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+}
+
+func sm2AlgorithmIdentifier() (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(OIDNamedCurveSM2)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+func checkSM2AlgorithmIdentifier(algo pkix.AlgorithmIdentifier) error {
+	if !algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		return fmt.Errorf("unexpected public key algorithm [%s]", algo.Algorithm)
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &curve); err != nil {
+		return fmt.Errorf("failed parsing named curve [%w]", err)
+	}
+	if !curve.Equal(OIDNamedCurveSM2) {
+		return fmt.Errorf("unexpected named curve [%s], expected SM2", curve)
+	}
+	return nil
+}
+
+// MarshalPKIXPublicKey 将SM2公钥编码成PKIX格式。
+func MarshalPKIXPublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	if pub == nil || !IsSM2Curve(pub.Curve) {
+		return nil, errors.New("invalid public key, it must be on the SM2 curve")
+	}
+
+	algo, err := sm2AlgorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	raw := elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: algo,
+		PublicKey: asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)},
+	})
+}
+
+// ParsePKIXPublicKey 解析PKIX格式的SM2公钥。
+func ParsePKIXPublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, fmt.Errorf("failed parsing PKIX public key [%w]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after PKIX public key")
+	}
+	if err := checkSM2AlgorithmIdentifier(spki.Algorithm); err != nil {
+		return nil, err
+	}
+
+	return unmarshalPublicKey(spki.PublicKey.RightAlign())
+}
+
+func unmarshalPublicKey(raw []byte) (*ecdsa.PublicKey, error) {
+	c := P256SM2()
+	x, y := elliptic.Unmarshal(c, raw)
+	if x == nil {
+		return nil, errors.New("invalid SM2 public key, the point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
+// MarshalPKCS8PrivateKey 将SM2私钥编码成PKCS#8格式。
+func MarshalPKCS8PrivateKey(priv *ecdsa.PrivateKey) ([]byte, error) {
+	if priv == nil || !IsSM2Curve(priv.Curve) {
+		return nil, errors.New("invalid private key, it must be on the SM2 curve")
+	}
+
+	algo, err := sm2AlgorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	pub := elliptic.Marshal(priv.Curve, priv.X, priv.Y)
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: priv.D.FillBytes(make([]byte, 32)),
+		PublicKey:  asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8{Algo: algo, PrivateKey: inner})
+}
+
+// ParsePKCS8PrivateKey 解析PKCS#8格式的SM2私钥。
+func ParsePKCS8PrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	var p pkcs8
+	if rest, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("failed parsing PKCS#8 private key [%w]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after PKCS#8 private key")
+	}
+	if err := checkSM2AlgorithmIdentifier(p.Algo); err != nil {
+		return nil, err
+	}
+
+	var key ecPrivateKey
+	if _, err := asn1.Unmarshal(p.PrivateKey, &key); err != nil {
+		return nil, fmt.Errorf("failed parsing EC private key [%w]", err)
+	}
+
+	c := P256SM2()
+	d := new(big.Int).SetBytes(key.PrivateKey)
+	if d.Sign() <= 0 || d.Cmp(new(big.Int).Sub(c.Params().N, big.NewInt(1))) >= 0 {
+		return nil, errors.New("invalid SM2 private key value")
+	}
+
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.PublicKey.Curve = c
+	priv.PublicKey.X, priv.PublicKey.Y = c.ScalarBaseMult(key.PrivateKey)
+	return priv, nil
+}
+
+// ParseCertificatePublicKey 从DER编码的X.509证书中取出SM2公钥。Go标准库无法解析公钥位于sm2p256v1曲线上
+// 的证书，所以这里只解析证书中的SubjectPublicKeyInfo。
+func ParseCertificatePublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var cert certificate
+	if _, err := asn1.Unmarshal(der, &cert); err != nil {
+		return nil, fmt.Errorf("failed parsing certificate [%w]", err)
+	}
+
+	return ParsePKIXPublicKey(cert.TBSCertificate.PublicKey.FullBytes)
+}
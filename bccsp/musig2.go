@@ -0,0 +1,117 @@
+package bccsp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MuSig2Session 封装了一个签名者参与MuSig2签名的两轮交互：第一轮通过PublicNonce生成公开nonce并发送给其他
+// 签名者，第二轮在收集到所有签名者的公开nonce以后通过Sign生成部分签名。任意一方在收集到所有部分签名以后都可以
+// 通过Aggregate得到BIP-340签名，该签名可以用AggregatePublicKey返回的聚合公钥验证。
+//
+// 每个会话只能签名一次，nonce在签名以后即失效，不能在多个会话之间复用。
+type MuSig2Session struct {
+	csp          BCCSP
+	key          Key
+	publicKeys   []Key
+	digest       []byte
+	nonce        Key
+	publicNonces [][]byte
+}
+
+// NewMuSig2Session 为签名者的私钥k创建一个对digest签名的会话，publicKeys是所有签名者的公钥（包括签名者自己），
+// 它的顺序在所有签名者之间必须一致。
+func NewMuSig2Session(csp BCCSP, k Key, publicKeys []Key, digest []byte) (*MuSig2Session, error) {
+	if csp == nil {
+		return nil, errors.New("invalid BCCSP, it must not be nil")
+	}
+	if k == nil || !k.Private() {
+		return nil, errors.New("invalid key, it must be a private key")
+	}
+	if len(publicKeys) == 0 {
+		return nil, errors.New("invalid public keys, at least one key is required")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("invalid digest, cannot be empty")
+	}
+
+	return &MuSig2Session{
+		csp:        csp,
+		key:        k,
+		publicKeys: publicKeys,
+		digest:     digest,
+	}, nil
+}
+
+// PublicNonce 生成本方的nonce并返回66字节的公开nonce，重复调用返回相同的公开nonce。聚合公钥和digest会被混入
+// nonce中。
+func (s *MuSig2Session) PublicNonce() ([]byte, error) {
+	if s.nonce == nil {
+		aggKey, err := s.AggregatePublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed aggregating public keys [%w]", err)
+		}
+		nonce, err := s.csp.KeyGen(&MuSig2NonceGenOpts{PublicKey: s.key, AggregateKey: aggKey, Message: s.digest})
+		if err != nil {
+			return nil, fmt.Errorf("failed generating nonce [%w]", err)
+		}
+		s.nonce = nonce
+	}
+
+	pub, err := s.nonce.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.Bytes()
+}
+
+// Sign 利用所有签名者的公开nonce生成本方的部分签名，publicNonces与publicKeys一一对应。
+func (s *MuSig2Session) Sign(publicNonces [][]byte) ([]byte, error) {
+	if s.nonce == nil {
+		return nil, errors.New("invalid session, public nonce has not been generated")
+	}
+	if len(publicNonces) != len(s.publicKeys) {
+		return nil, fmt.Errorf("invalid public nonces, expected [%d] nonces, but got [%d]", len(s.publicKeys), len(publicNonces))
+	}
+	s.publicNonces = publicNonces
+
+	return s.csp.Sign(s.key, s.digest, s.signerOpts(s.nonce))
+}
+
+// VerifyPartial 验证第i个签名者的部分签名，必须在Sign之后调用。
+func (s *MuSig2Session) VerifyPartial(i int, partial []byte) (bool, error) {
+	if s.publicNonces == nil {
+		return false, errors.New("invalid session, public nonces have not been collected")
+	}
+	if i < 0 || i >= len(s.publicKeys) {
+		return false, fmt.Errorf("invalid signer index [%d]", i)
+	}
+
+	return s.csp.Verify(s.publicKeys[i], partial, s.digest, s.signerOpts(nil))
+}
+
+// Aggregate 将所有签名者的部分签名聚合成BIP-340签名，partials与publicKeys一一对应。
+func (s *MuSig2Session) Aggregate(partials [][]byte) ([]byte, error) {
+	if s.publicNonces == nil {
+		return nil, errors.New("invalid session, public nonces have not been collected")
+	}
+
+	return s.csp.AggregateSignatures(partials, &MuSig2AggregateOpts{
+		PublicKeys:   s.publicKeys,
+		PublicNonces: s.publicNonces,
+		Digest:       s.digest,
+	})
+}
+
+// AggregatePublicKey 返回所有签名者的聚合公钥，它可以验证Aggregate返回的签名。
+func (s *MuSig2Session) AggregatePublicKey() (Key, error) {
+	return s.csp.AggregatePublicKeys(s.publicKeys, &MuSig2AggregateOpts{})
+}
+
+func (s *MuSig2Session) signerOpts(nonce Key) *MuSig2SignerOpts {
+	return &MuSig2SignerOpts{
+		PublicKeys:   s.publicKeys,
+		PublicNonces: s.publicNonces,
+		Nonce:        nonce,
+	}
+}
@@ -2,6 +2,9 @@ package bccsp
 
 import (
 	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -10,16 +13,54 @@ const (
 	// ECDSA 代表默认安全级别的椭圆曲线数字签名算法(KeyGen, Import, Sign, Verify)。
 	ECDSA = "ECDSA"
 
+	// ECDSAP224 代表P-224曲线上的椭圆曲线数字签名算法。
+	ECDSAP224 = "ECDSAP224"
+
 	// ECDSAP256 代表P-256曲线上的椭圆曲线数字签名算法。
 	ECDSAP256 = "ECDSAP256"
 
 	// ECDSAP384 代表P-384曲线上的椭圆曲线数字签名算法。
 	ECDSAP384 = "ECDSAP384"
 
-	// ECDSAReRand 密钥重新随机化。
-	// TODO 什么是密钥重新随机化？猜测是密钥派生。
+	// ECDSAP521 代表P-521曲线上的椭圆曲线数字签名算法。
+	ECDSAP521 = "ECDSAP521"
+
+	// ECDSASecp256k1 代表secp256k1曲线上的椭圆曲线数字签名算法，比特币和以太坊使用的就是这条曲线。
+	ECDSASecp256k1 = "ECDSASECP256K1"
+
+	// ECDSAReRand ECDSA密钥重新随机化，即根据扩展值从已有的ECDSA密钥派生出新的密钥，派生出的公钥不需要私钥
+	// 就可以计算出来。
 	ECDSAReRand = "ECDSA_RERAND"
 
+	// ED25519 代表Ed25519数字签名算法(KeyGen, Import, Sign, Verify)，签名时直接对完整的消息进行签名，
+	// 不需要预先计算消息的哈希值。
+	ED25519 = "ED25519"
+
+	// X25519 代表RFC 7748定义的X25519密钥协商算法(KeyGen, Import, KeyDeriv)。
+	X25519 = "X25519"
+
+	// BLS12381 代表基于BLS12-381曲线的BLS签名算法(KeyGen, Import, Sign, Verify, Aggregate)，公钥是G1上的点，
+	// 签名是G2上的点。
+	BLS12381 = "BLS12381"
+
+	// Schnorr 代表BIP-340定义的Schnorr签名算法(Sign, Verify, Import)，支持secp256k1和P-256曲线。
+	Schnorr = "SCHNORR"
+
+	// MuSig2 代表BIP-327定义的基于Schnorr签名的MuSig2 n-of-n多重签名方案(KeyGen, Sign, Verify, Aggregate)。
+	MuSig2 = "MUSIG2"
+
+	// HKDF 代表RFC 5869定义的基于HMAC的密钥派生函数(KeyDeriv)。
+	HKDF = "HKDF"
+
+	// ECDH 代表椭圆曲线Diffie-Hellman密钥协商，用于从本方私钥和对方公钥派生出共享的对称密钥(KeyDeriv)。
+	ECDH = "ECDH"
+
+	// RSA 代表RSA数字签名算法，目前只支持导入公钥和验证签名(Import, Verify)。
+	RSA = "RSA"
+
+	// SM2 代表GB/T 32918定义的SM2椭圆曲线数字签名算法(KeyGen, Import, Sign, Verify)。
+	SM2 = "SM2"
+
 	// AES 代表默认安全级别的AES加密算法。
 	AES = "AES"
 
@@ -32,6 +73,12 @@ const (
 	// AES256 代表256位安全级别的AES加密算法。
 	AES256 = "AES256"
 
+	// SM4 代表GB/T 32907定义的SM4分组密码算法，密钥长度为128位。
+	SM4 = "SM4"
+
+	// ChaCha20Poly1305 代表ChaCha20-Poly1305认证加密算法，它的密钥也可以用于XChaCha20-Poly1305。
+	ChaCha20Poly1305 = "CHACHA20_POLY1305"
+
 	// HMAC 代表哈希验证码。
 	HMAC = "HMAC"
 
@@ -55,9 +102,15 @@ const (
 
 	SHA3_384 = "SHA3_384"
 
+	// SM3 代表GB/T 32905定义的SM3密码杂凑算法。
+	SM3 = "SM3"
+
 	// X509Certificate 代表用于X509证书的相关操作。
 	X509Certificate = "X509Certificate"
 
+	// PEM 代表以PEM格式导入导出私钥和对称密钥。
+	PEM = "PEM"
+
 	// IDEMIX 身份混淆
 	IDEMIX = "IDEMIX"
 )
@@ -65,6 +118,8 @@ const (
 // ECDSAKeyGenOpts 包含用于ECDSA密钥生成的选项。
 type ECDSAKeyGenOpts struct {
 	Temporary bool
+	// KeyAgreement 表示密钥是否会用于ECDH密钥协商，基于PKCS#11的实现只为这样的密钥设置CKA_DERIVE。
+	KeyAgreement bool
 }
 
 // Algorithm 返回密钥生成算法的标识符。
@@ -185,6 +240,91 @@ func (opts *HMACDeriveKeyOpts) Argument() []byte {
 	return opts.Arg
 }
 
+// ECDHKeyDerivOpts 包含ECDH密钥协商的选项：用本方的私钥（ECDSA私钥或X25519私钥）和对方的公钥计算出共享
+// 秘密，再用KDF从共享秘密派生出一个32字节的AES密钥。对方的公钥必须与本方的私钥属于同一条曲线。令牌上的
+// 私钥必须在生成时设置了KeyAgreement。
+type ECDHKeyDerivOpts struct {
+	Temporary bool
+	// PeerPublicKey 是对方的公钥。
+	PeerPublicKey Key
+	// KDF 从共享秘密派生出32字节的密钥，为空时使用以SHA-256为哈希函数、以SharedInfo为共享信息的ANSI X9.63 KDF。
+	KDF func(secret []byte) ([]byte, error)
+	// SharedInfo 是默认的KDF使用的共享信息，协商的双方必须一致，可以为空。
+	SharedInfo []byte
+	// HKDF 不为空时，用HKDF代替KDF从共享秘密派生出密钥，派生出的密钥的类型和长度由HKDF决定，此时KDF和
+	// SharedInfo会被忽略。
+	HKDF *HKDFKeyDerivOpts
+}
+
+// Algorithm 返回密钥派生算法的标识符。
+func (opts *ECDHKeyDerivOpts) Algorithm() string {
+	return ECDH
+}
+
+// Ephemeral 如果派生出的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *ECDHKeyDerivOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// DeriveKey 用KDF从共享秘密secret派生出32字节的AES密钥。
+func (opts *ECDHKeyDerivOpts) DeriveKey(secret []byte) ([]byte, error) {
+	if opts.KDF == nil {
+		return ansiX963KDF(secret, opts.SharedInfo, 32), nil
+	}
+
+	key, err := opts.KDF(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid derived key length [%d], must be 32 bytes", len(key))
+	}
+	return key, nil
+}
+
+// ansiX963KDF 实现以SHA-256为哈希函数的ANSI X9.63 KDF：K = H(Z || 1 || SharedInfo) || H(Z || 2 || SharedInfo) || ...，
+// 其中计数器是4字节的大端整数。
+func ansiX963KDF(secret, sharedInfo []byte, length int) []byte {
+	var key []byte
+	var counter [4]byte
+	for i := uint32(1); len(key) < length; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha256.New()
+		h.Write(secret)
+		h.Write(counter[:])
+		h.Write(sharedInfo)
+		key = h.Sum(key)
+	}
+	return key[:length]
+}
+
+// HKDFKeyDerivOpts 包含RFC 5869定义的HKDF密钥派生的选项，可以从任意对称密钥派生出AES、HMAC或
+// ChaCha20-Poly1305密钥，也可以通过ECDHKeyDerivOpts从ECDH共享秘密派生出这些密钥。
+type HKDFKeyDerivOpts struct {
+	Temporary bool
+	// Hash 是HMAC使用的哈希函数，可以是GetHashOpt能识别的任意名称，例如SHA256、SHA3_256或SM3，为空时使用SHA256。
+	Hash string
+	// Salt 是HKDF-Extract使用的盐值，可以为空。
+	Salt []byte
+	// Info 是HKDF-Expand使用的上下文信息，可以为空。
+	Info []byte
+	// KeyType 是派生出的密钥的类型，可以是AES、HMAC或ChaCha20Poly1305，为空时派生AES密钥。
+	KeyType string
+	// Length 是派生出的密钥的字节长度，为0时AES和ChaCha20-Poly1305密钥的长度为32字节，HMAC密钥的长度
+	// 等于哈希函数的输出长度。
+	Length int
+}
+
+// Algorithm 返回密钥派生算法的标识符。
+func (opts *HKDFKeyDerivOpts) Algorithm() string {
+	return HKDF
+}
+
+// Ephemeral 如果派生出的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *HKDFKeyDerivOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
 // AES256ImportKeyOpts 包含导入AES256密钥的选项。
 type AES256ImportKeyOpts struct {
 	Temporary bool
@@ -223,7 +363,8 @@ func (opts *SHAOpts) Algorithm() string {
 	return SHA
 }
 
-// X509PublicKeyImportOpts 包含从X509证书里导入公钥的选项。
+// X509PublicKeyImportOpts 包含从X509证书里导入公钥的选项。证书既可以是*x509.Certificate，也可以是DER编码
+// 的字节序列，Go标准库无法解析的SM2证书只能以DER编码的形式导入。
 type X509PublicKeyImportOpts struct {
 	Temporary bool
 }
@@ -238,6 +379,22 @@ func (opts *X509PublicKeyImportOpts) Ephemeral() bool {
 	return opts.Temporary
 }
 
+// PEMKeyImportOpts 包含导入未加密的PEM编码的私钥或对称密钥的选项，PEM块的类型决定了密钥的种类，
+// 例如KeyExporter导出的PEM。
+type PEMKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *PEMKeyImportOpts) Algorithm() string {
+	return PEM
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *PEMKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
 // SHA256Opts 包含于SHA-256算法相关的选项。
 type SHA256Opts struct{}
 
@@ -270,6 +427,14 @@ func (opts *SHA3_384Opts) Algorithm() string {
 	return SHA3_384
 }
 
+// SM3Opts 包含与SM3算法相关的选项。
+type SM3Opts struct{}
+
+// Algorithm 返回哈希算法的标识符。
+func (opts *SM3Opts) Algorithm() string {
+	return SM3
+}
+
 // GetHashOpt 根据给定的哈希函数名，返回对应的哈希算法选项。
 func GetHashOpt(hashFunction string) (HashOpts, error) {
 	switch hashFunction {
@@ -281,6 +446,8 @@ func GetHashOpt(hashFunction string) (HashOpts, error) {
 		return &SHA3_256Opts{}, nil
 	case SHA3_384:
 		return &SHA3_384Opts{}, nil
+	case SM3:
+		return &SM3Opts{}, nil
 	default:
 		return nil, fmt.Errorf("hash function not recognized [%s]", hashFunction)
 	}
@@ -532,9 +699,28 @@ func (opts *IdemixCRISignerOpts) HashFunc() crypto.Hash {
 	return opts.H
 }
 
+// ECDSAP224KeyGenOpts 包含用于生成具有P-224曲线的ECDSA密钥的选项。
+type ECDSAP224KeyGenOpts struct {
+	Temporary bool
+	// KeyAgreement 表示密钥是否会用于ECDH密钥协商，基于PKCS#11的实现只为这样的密钥设置CKA_DERIVE。
+	KeyAgreement bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *ECDSAP224KeyGenOpts) Algorithm() string {
+	return ECDSAP224
+}
+
+// Ephemeral 如果派生出的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *ECDSAP224KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
 // ECDSAP256KeyGenOpts 包含用于生成具有P-256曲线的ECDSA密钥的选项。
 type ECDSAP256KeyGenOpts struct {
 	Temporary bool
+	// KeyAgreement 表示密钥是否会用于ECDH密钥协商，基于PKCS#11的实现只为这样的密钥设置CKA_DERIVE。
+	KeyAgreement bool
 }
 
 // Algorithm 返回密钥生成算法的标识符。
@@ -550,6 +736,8 @@ func (opts *ECDSAP256KeyGenOpts) Ephemeral() bool {
 // ECDSAP384KeyGenOpts 包含用于生成具有P-384曲线的ECDSA密钥的选项。
 type ECDSAP384KeyGenOpts struct {
 	Temporary bool
+	// KeyAgreement 表示密钥是否会用于ECDH密钥协商，基于PKCS#11的实现只为这样的密钥设置CKA_DERIVE。
+	KeyAgreement bool
 }
 
 // Algorithm 返回密钥生成算法的标识符。
@@ -562,6 +750,366 @@ func (opts *ECDSAP384KeyGenOpts) Ephemeral() bool {
 	return opts.Temporary
 }
 
+// ECDSAP521KeyGenOpts 包含用于生成具有P-521曲线的ECDSA密钥的选项。
+type ECDSAP521KeyGenOpts struct {
+	Temporary bool
+	// KeyAgreement 表示密钥是否会用于ECDH密钥协商，基于PKCS#11的实现只为这样的密钥设置CKA_DERIVE。
+	KeyAgreement bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *ECDSAP521KeyGenOpts) Algorithm() string {
+	return ECDSAP521
+}
+
+// Ephemeral 如果派生出的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *ECDSAP521KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// ECDSASecp256k1KeyGenOpts 包含用于生成具有secp256k1曲线的ECDSA密钥的选项。
+type ECDSASecp256k1KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *ECDSASecp256k1KeyGenOpts) Algorithm() string {
+	return ECDSASecp256k1
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *ECDSASecp256k1KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// ECDSASecp256k1PublicKeyImportOpts 包含导入SEC 1格式编码的secp256k1公钥的选项，公钥可以是65字节的非压缩
+// 格式，也可以是比特币和以太坊钱包常用的33字节的压缩格式。PKIX格式的secp256k1公钥可以通过
+// ECDSAPKIXPublicKeyImportOpts导入。
+type ECDSASecp256k1PublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *ECDSASecp256k1PublicKeyImportOpts) Algorithm() string {
+	return ECDSASecp256k1
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *ECDSASecp256k1PublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// X25519KeyGenOpts 包含用于生成X25519密钥的选项。
+type X25519KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *X25519KeyGenOpts) Algorithm() string {
+	return X25519
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *X25519KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// X25519PublicKeyImportOpts 包含导入X25519公钥的选项，公钥可以是RFC 7748定义的32字节编码，也可以是PKIX格式。
+type X25519PublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *X25519PublicKeyImportOpts) Algorithm() string {
+	return X25519
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *X25519PublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// SchnorrSignerOpts 包含BIP-340 Schnorr签名的选项，可以与secp256k1或P-256曲线上的ECDSA密钥一起使用。签名是
+// 64字节的R.x || s，验证时只使用公钥的x坐标。使用该选项时，digest是被签名的消息，BIP-340建议使用32字节的摘要。
+type SchnorrSignerOpts struct {
+	// AuxRand 是签名时混入nonce的32字节辅助随机数，为空时从crypto/rand读取。
+	AuxRand []byte
+}
+
+// HashFunc Schnorr签名直接对digest签名，不需要预先计算哈希值，所以返回0。
+func (opts *SchnorrSignerOpts) HashFunc() crypto.Hash {
+	return 0
+}
+
+// SchnorrPublicKeyImportOpts 包含导入BIP-340定义的32字节x-only公钥的选项，导入的公钥只能用于验证Schnorr签名。
+// x-only公钥没有标准的PKIX编码，所以导入的公钥总是暂时的，不会被保存到KeyStore中。
+type SchnorrPublicKeyImportOpts struct {
+	// Curve 是公钥所在曲线在曲线注册表中的名称，只能是"secp256k1"或"P-256"，为空时使用secp256k1。
+	Curve string
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *SchnorrPublicKeyImportOpts) Algorithm() string {
+	return Schnorr
+}
+
+// Ephemeral 导入的x-only公钥总是暂时的，所以返回true。
+func (opts *SchnorrPublicKeyImportOpts) Ephemeral() bool {
+	return true
+}
+
+// MuSig2NonceGenOpts 包含生成MuSig2签名nonce的选项。生成的nonce是一个私钥，它对应的公钥的Bytes方法返回66字节
+// 的公开nonce，需要在第一轮交互中发送给其他签名者。同一个nonce只能用于一次签名，所以它总是暂时的，不会被保存
+// 到KeyStore中。
+type MuSig2NonceGenOpts struct {
+	// PublicKey 是将要使用这个nonce签名的ECDSA密钥，nonce与它在同一条曲线上。如果它是私钥，私钥也会被混入nonce中。
+	PublicKey Key
+	// AggregateKey 是所有签名者的聚合公钥，可以为空，不为空时会被混入nonce中。
+	AggregateKey Key
+	// Message 是将要被签名的消息，可以为空，不为空时会被混入nonce中。
+	Message []byte
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *MuSig2NonceGenOpts) Algorithm() string {
+	return MuSig2
+}
+
+// Ephemeral nonce不能重复使用，所以总是返回true。
+func (opts *MuSig2NonceGenOpts) Ephemeral() bool {
+	return true
+}
+
+// MuSig2SignerOpts 包含生成和验证MuSig2部分签名的选项，PublicKeys和PublicNonces的顺序在所有签名者之间必须一致。
+// 生成的部分签名是32字节的标量，验证部分签名时传给Verify的密钥是产生该部分签名的签名者的公钥。
+type MuSig2SignerOpts struct {
+	// PublicKeys 是所有签名者的公钥。
+	PublicKeys []Key
+	// PublicNonces 是所有签名者的公开nonce，与PublicKeys一一对应。
+	PublicNonces [][]byte
+	// Nonce 是本方用MuSig2NonceGenOpts生成的nonce，只在签名时使用，签名以后即失效。
+	Nonce Key
+}
+
+// HashFunc MuSig2直接对digest签名，不需要预先计算哈希值，所以返回0。
+func (opts *MuSig2SignerOpts) HashFunc() crypto.Hash {
+	return 0
+}
+
+// MuSig2AggregateOpts 包含聚合MuSig2公钥和部分签名的选项。聚合公钥时不需要设置任何字段，得到的聚合公钥可以
+// 按照BIP-340验证聚合签名；聚合部分签名时需要提供与签名时相同的PublicKeys、PublicNonces和Digest。
+type MuSig2AggregateOpts struct {
+	// PublicKeys 是所有签名者的公钥。
+	PublicKeys []Key
+	// PublicNonces 是所有签名者的公开nonce，与PublicKeys一一对应。
+	PublicNonces [][]byte
+	// Digest 是被签名的消息。
+	Digest []byte
+}
+
+// Algorithm 返回聚合算法的标识符。
+func (opts *MuSig2AggregateOpts) Algorithm() string {
+	return MuSig2
+}
+
+// BLS12381KeyGenOpts 包含用于生成BLS12-381签名密钥的选项。
+type BLS12381KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *BLS12381KeyGenOpts) Algorithm() string {
+	return BLS12381
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *BLS12381KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// BLS12381PrivateKeyImportOpts 包含导入BLS12-381私钥的选项，私钥是32字节大端序编码的标量。
+type BLS12381PrivateKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *BLS12381PrivateKeyImportOpts) Algorithm() string {
+	return BLS12381
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *BLS12381PrivateKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// BLS12381PublicKeyImportOpts 包含导入BLS12-381公钥的选项，公钥是G1上的点按照ZCash格式压缩后的48字节编码。
+type BLS12381PublicKeyImportOpts struct {
+	Temporary bool
+	// ProofOfPossession 是公钥的持有证明，不为空时导入公钥前先验证它。只有持有证明验证通过的公钥才能被聚合。
+	ProofOfPossession []byte
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *BLS12381PublicKeyImportOpts) Algorithm() string {
+	return BLS12381
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *BLS12381PublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// BLS12381ProofOfPossessionOpts 包含生成和验证BLS12-381公钥持有证明(proof of possession)的选项。使用该
+// 选项时，传给Sign和Verify的digest必须是公钥的48字节编码。聚合公钥之前必须验证每个公钥的持有证明，否则
+// 攻击者可以构造恶意的公钥(rogue key)伪造聚合签名，因此从外部得到的公钥要通过BLS12381PublicKeyImportOpts
+// 带着持有证明导入才能被聚合。
+type BLS12381ProofOfPossessionOpts struct{}
+
+// HashFunc 持有证明直接对公钥签名，不需要预先计算哈希值，所以返回0。
+func (opts *BLS12381ProofOfPossessionOpts) HashFunc() crypto.Hash {
+	return 0
+}
+
+// BLS12381AggregateOpts 包含聚合BLS12-381签名和公钥的选项。
+type BLS12381AggregateOpts struct{}
+
+// Algorithm 返回聚合算法的标识符。
+func (opts *BLS12381AggregateOpts) Algorithm() string {
+	return BLS12381
+}
+
+// Ed25519KeyGenOpts 包含用于生成Ed25519密钥的选项。
+type Ed25519KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *Ed25519KeyGenOpts) Algorithm() string {
+	return ED25519
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *Ed25519KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// Ed25519PKIXPublicKeyImportOpts 包含用于以PKIX格式导入Ed25519公钥的选项。
+type Ed25519PKIXPublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *Ed25519PKIXPublicKeyImportOpts) Algorithm() string {
+	return ED25519
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *Ed25519PKIXPublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// Ed25519GoPublicKeyImportOpts 包含从ed25519.PublicKey导入Ed25519公钥的选项。
+type Ed25519GoPublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *Ed25519GoPublicKeyImportOpts) Algorithm() string {
+	return ED25519
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *Ed25519GoPublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// RSAPKIXPublicKeyImportOpts 包含用于以PKIX格式导入RSA公钥的选项。
+type RSAPKIXPublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *RSAPKIXPublicKeyImportOpts) Algorithm() string {
+	return RSA
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *RSAPKIXPublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// RSAGoPublicKeyImportOpts 包含从rsa.PublicKey导入RSA公钥的选项。
+type RSAGoPublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *RSAGoPublicKeyImportOpts) Algorithm() string {
+	return RSA
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *RSAGoPublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// SM2KeyGenOpts 包含用于生成SM2密钥的选项。
+type SM2KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *SM2KeyGenOpts) Algorithm() string {
+	return SM2
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *SM2KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// SM2PKIXPublicKeyImportOpts 包含用于以PKIX格式导入SM2公钥的选项。
+type SM2PKIXPublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *SM2PKIXPublicKeyImportOpts) Algorithm() string {
+	return SM2
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *SM2PKIXPublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// SM2GoPublicKeyImportOpts 包含从曲线为sm2p256v1的ecdsa.PublicKey导入SM2公钥的选项。
+type SM2GoPublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *SM2GoPublicKeyImportOpts) Algorithm() string {
+	return SM2
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *SM2GoPublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// SM2SignerOpts 包含SM2签名的选项。使用该选项时，传给Sign和Verify的digest是原始的消息，BCCSP会按照
+// GM/T 0009计算e = SM3(ZA || M)以后再签名或验证；不使用该选项时，digest被当作已经计算好的e。
+type SM2SignerOpts struct {
+	// UID 是签名者的用户标识，为空时使用默认的用户标识"1234567812345678"。
+	UID []byte
+}
+
+// HashFunc SM2签名的摘要由SM3计算，不对应crypto包中的哈希函数，所以返回0。
+func (opts *SM2SignerOpts) HashFunc() crypto.Hash {
+	return 0
+}
+
 // AES128KeyGenOpts 包含128安全级别的AES密钥生成选项.
 type AES128KeyGenOpts struct {
 	Temporary bool
@@ -607,6 +1155,122 @@ func (opts *AES256KeyGenOpts) Ephemeral() bool {
 	return opts.Temporary
 }
 
+// ErrAuthenticationFailed 表示认证加密模式下的密文或附加数据没有通过认证，密文可能被篡改，或者使用了错误的
+// 密钥、附加数据。
+var ErrAuthenticationFailed = errors.New("message authentication failed")
+
+// AESGCMModeOpts 包含GCM模式下AES认证加密的选项。加密的结果为nonce || ciphertext || tag，其中nonce
+// 为12字节，tag为16字节。Nonce和PRNG都为nil时，BCCSP的实现应该使用一个加密安全的PRNG生成nonce；二者
+// 不能同时不为nil。解密时只会用到AdditionalData。
+type AESGCMModeOpts struct {
+	// Nonce 是调用者指定的nonce，长度必须为12字节。同一个密钥下的nonce绝对不能重复使用。
+	Nonce []byte
+	// PRNG 用于生成随机的nonce，只有当它不为nil时才会被使用。
+	PRNG io.Reader
+	// AdditionalData 是需要认证但不需要加密的附加数据，解密时必须提供相同的附加数据。
+	AdditionalData []byte
+}
+
+// ChaCha20Poly1305KeyGenOpts 包含ChaCha20-Poly1305密钥生成的选项，生成的密钥长度为32字节。
+type ChaCha20Poly1305KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *ChaCha20Poly1305KeyGenOpts) Algorithm() string {
+	return ChaCha20Poly1305
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *ChaCha20Poly1305KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// ChaCha20Poly1305ImportKeyOpts 包含导入ChaCha20-Poly1305密钥的选项，导入的密钥长度必须为32字节。
+type ChaCha20Poly1305ImportKeyOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *ChaCha20Poly1305ImportKeyOpts) Algorithm() string {
+	return ChaCha20Poly1305
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *ChaCha20Poly1305ImportKeyOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// ChaCha20Poly1305ModeOpts 包含ChaCha20-Poly1305认证加密的选项，nonce为12字节，其余的约定与AESGCMModeOpts
+// 相同：加密的结果为nonce || ciphertext || tag，Nonce和PRNG不能同时不为nil。
+type ChaCha20Poly1305ModeOpts struct {
+	// Nonce 是调用者指定的nonce，长度必须为12字节。同一个密钥下的nonce绝对不能重复使用。
+	Nonce []byte
+	// PRNG 用于生成随机的nonce，只有当它不为nil时才会被使用。
+	PRNG io.Reader
+	// AdditionalData 是需要认证但不需要加密的附加数据，解密时必须提供相同的附加数据。
+	AdditionalData []byte
+}
+
+// XChaCha20Poly1305ModeOpts 包含XChaCha20-Poly1305认证加密的选项，nonce扩展为24字节，随机生成的nonce
+// 重复的概率可以忽略不计，适合用同一个密钥加密大量的消息。
+type XChaCha20Poly1305ModeOpts struct {
+	// Nonce 是调用者指定的nonce，长度必须为24字节。
+	Nonce []byte
+	// PRNG 用于生成随机的nonce，只有当它不为nil时才会被使用。
+	PRNG io.Reader
+	// AdditionalData 是需要认证但不需要加密的附加数据，解密时必须提供相同的附加数据。
+	AdditionalData []byte
+}
+
+// SM4KeyGenOpts 包含SM4密钥生成的选项，生成的密钥长度为16字节。
+type SM4KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥生成算法的标识符。
+func (opts *SM4KeyGenOpts) Algorithm() string {
+	return SM4
+}
+
+// Ephemeral 如果生成的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *SM4KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// SM4ImportKeyOpts 包含导入SM4密钥的选项，导入的密钥长度必须为16字节。
+type SM4ImportKeyOpts struct {
+	Temporary bool
+}
+
+// Algorithm 返回密钥导入算法的标识符。
+func (opts *SM4ImportKeyOpts) Algorithm() string {
+	return SM4
+}
+
+// Ephemeral 如果导入的密钥必须是暂时的，则该方法返回true，否则返回false。
+func (opts *SM4ImportKeyOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// SM4CBCPKCS7ModeOpts 包含CBC模式下的SM4加密和PKCS7填充的选项，IV和PRNG的约定与AESCBCPKCS7ModeOpts相同。
+type SM4CBCPKCS7ModeOpts struct {
+	// IV 是初始化向量，长度必须为16字节，只有当它不为nil时才会被使用。
+	IV []byte
+	// PRNG 用于生成随机的IV，只有当它不为nil时才会被使用。
+	PRNG io.Reader
+}
+
+// SM4GCMModeOpts 包含GCM模式下的SM4认证加密的选项，Nonce、PRNG和AdditionalData的约定与AESGCMModeOpts相同。
+type SM4GCMModeOpts struct {
+	// Nonce 是调用者指定的nonce，长度必须为12字节。同一个密钥下的nonce绝对不能重复使用。
+	Nonce []byte
+	// PRNG 用于生成随机的nonce，只有当它不为nil时才会被使用。
+	PRNG io.Reader
+	// AdditionalData 是需要认证但不需要加密的附加数据，解密时必须提供相同的附加数据。
+	AdditionalData []byte
+}
+
 // AESCBCPKCS7ModeOpts 包含CBC模式下的AES加密和PKCS7填充的选项。注意，IV和
 // PRNG都可以为零。在这种情况下，BCCSP的实现应该使用一个加密安全的PRNG对IV进
 // 行采样。还要注意的是，IV或PRNG可以与nil不同。
@@ -0,0 +1,438 @@
+package pkcs11
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/sw"
+	"github.com/232425wxy/lark/bccsp/utils"
+	"github.com/232425wxy/lark/common/metrics/disabled"
+	"github.com/miekg/pkcs11"
+)
+
+// Provider 是基于PKCS#11实现的BCCSP，ECDSA密钥的生成、查找和签名都在硬件安全模块(HSM)的令牌上完成，
+// 其余的操作（哈希、对称加密、公钥导入等）交给内嵌的基于软件的BCCSP完成。
+type Provider struct {
+	bccsp.BCCSP
+
+	ctx        *pkcs11.Ctx
+	tokens     []*token
+	curve      asn1.ObjectIdentifier
+	softVerify bool
+	immutable  bool
+	altID      string
+
+	cacheLock sync.RWMutex
+	keyCache  map[string]bccsp.Key
+	keyIDs    map[string]KeyIDMapping
+	// keyTokens 记录了与SKI相关联的密钥位于哪个令牌上。
+	keyTokens map[string]*token
+}
+
+// 确保Provider实现了bccsp.BCCSP接口。
+var _ bccsp.BCCSP = (*Provider)(nil)
+
+// New 根据选项opts创建一个基于PKCS#11的BCCSP：加载opts.Library指定的PKCS#11库，根据标签、序列号或插槽ID
+// 找到令牌，并用PIN登录。除了opts指定的令牌以外，opts.Tokens中的令牌也会被使用，新的密钥在opts指定的令牌上
+// 生成，已有的密钥根据SKI在所有的令牌上查找。keyStore用于存储交给软件实现处理的密钥。
+func New(opts PKCS11Opts, keyStore bccsp.KeyStore) (*Provider, error) {
+	curve, err := curveForSecurityLevel(opts.Security)
+	if err != nil {
+		return nil, fmt.Errorf("failed initializing configuration: [%w]", err)
+	}
+
+	swCSP, err := sw.NewWithParams(opts.Security, opts.Hash, keyStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed initializing fallback SW BCCSP: [%w]", err)
+	}
+
+	if opts.sessionCacheSize == 0 {
+		opts.sessionCacheSize = defaultSessionCacheSize
+	}
+	if opts.createSessionRetries == 0 {
+		opts.createSessionRetries = defaultCreateSessionRetries
+	}
+	if opts.createSessionRetryDelay == 0 {
+		opts.createSessionRetryDelay = defaultCreateSessionRetryDelay
+	}
+
+	keyIDs, err := keyIDMappings(opts.KeyIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed initializing configuration: [%w]", err)
+	}
+
+	for _, tokenOpts := range opts.tokenOpts() {
+		if err = tokenOpts.validate(); err != nil {
+			return nil, fmt.Errorf("failed initializing configuration: [%w]", err)
+		}
+	}
+
+	csp := &Provider{
+		BCCSP:      swCSP,
+		curve:      curve,
+		keyCache:   map[string]bccsp.Key{},
+		keyIDs:     keyIDs,
+		keyTokens:  map[string]*token{},
+		softVerify: opts.SoftwareVerify,
+		immutable:  opts.Immutable,
+		altID:      opts.AltID,
+	}
+
+	return csp.initialize(opts)
+}
+
+// keyIDMappings 检查KeyIDs选项，并将其转换为以SKI为键的映射表。
+func keyIDMappings(mappings []KeyIDMapping) (map[string]KeyIDMapping, error) {
+	keyIDs := map[string]KeyIDMapping{}
+	for _, m := range mappings {
+		ski, err := hex.DecodeString(m.SKI)
+		if err != nil || len(ski) == 0 {
+			return nil, fmt.Errorf("invalid SKI in key ID mapping [%s]", m.SKI)
+		}
+		if m.ID == "" && m.Label == "" {
+			return nil, fmt.Errorf("key ID mapping for SKI [%s] must specify an ID or a label", m.SKI)
+		}
+		if _, ok := keyIDs[string(ski)]; ok {
+			return nil, fmt.Errorf("duplicate key ID mapping for SKI [%s]", m.SKI)
+		}
+		keyIDs[string(ski)] = m
+	}
+	return keyIDs, nil
+}
+
+func (csp *Provider) initialize(opts PKCS11Opts) (*Provider, error) {
+	if opts.Library == "" {
+		return nil, errors.New("pkcs11: library path not provided")
+	}
+
+	ctx := pkcs11.New(opts.Library)
+	if ctx == nil {
+		return nil, fmt.Errorf("pkcs11: instantiation failed for %s", opts.Library)
+	}
+	if err := ctx.Initialize(); err != nil && !isCKR(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, fmt.Errorf("pkcs11: initialization failed for %s: [%w]", opts.Library, err)
+	}
+	csp.ctx = ctx
+
+	metricsProvider := opts.MetricsProvider
+	if metricsProvider == nil {
+		metricsProvider = &disabled.Provider{}
+	}
+	m := NewMetrics(metricsProvider)
+
+	slots := map[uint]bool{}
+	for _, tokenOpts := range opts.tokenOpts() {
+		slot, err := findSlot(ctx, tokenOpts)
+		if err != nil {
+			csp.Close()
+			return nil, err
+		}
+		if slots[slot] {
+			csp.Close()
+			return nil, fmt.Errorf("pkcs11: token with %s is configured more than once", tokenOpts)
+		}
+		slots[slot] = true
+
+		tok := &token{
+			ctx:                     ctx,
+			opts:                    tokenOpts,
+			slot:                    slot,
+			sessions:                map[pkcs11.SessionHandle]struct{}{},
+			handleCache:             map[string]pkcs11.ObjectHandle{},
+			createSessionRetries:    opts.createSessionRetries,
+			createSessionRetryDelay: opts.createSessionRetryDelay,
+			metrics:                 m.forToken(tokenName(ctx, slot, tokenOpts)),
+		}
+		if opts.sessionCacheSize > 0 {
+			tok.sessPool = make(chan pkcs11.SessionHandle, opts.sessionCacheSize)
+		}
+		csp.tokens = append(csp.tokens, tok)
+
+		session, gen, err := tok.createSession()
+		if err != nil {
+			csp.Close()
+			return nil, err
+		}
+		tok.returnSession(session, gen)
+	}
+
+	return csp, nil
+}
+
+// Close 关闭所有令牌上打开的会话，并释放PKCS#11库。
+func (csp *Provider) Close() error {
+	if csp.ctx == nil {
+		return nil
+	}
+
+	for _, tok := range csp.tokens {
+		tok.resetSessions()
+	}
+
+	csp.cacheLock.Lock()
+	csp.keyCache = map[string]bccsp.Key{}
+	csp.keyTokens = map[string]*token{}
+	csp.cacheLock.Unlock()
+
+	err := csp.ctx.Finalize()
+	csp.ctx.Destroy()
+	csp.ctx = nil
+	return err
+}
+
+// KeyGen 在令牌上生成ECDSA密钥对，其他类型的密钥交给软件实现生成。
+func (csp *Provider) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts parameter, it must not be nil")
+	}
+
+	var (
+		curve  asn1.ObjectIdentifier
+		derive bool
+	)
+	switch o := opts.(type) {
+	case *bccsp.ECDSAKeyGenOpts:
+		curve, derive = csp.curve, o.KeyAgreement
+	case *bccsp.ECDSAP224KeyGenOpts:
+		curve, err = oidFromNamedCurve(elliptic.P224())
+		derive = o.KeyAgreement
+	case *bccsp.ECDSAP256KeyGenOpts:
+		curve, err = oidFromNamedCurve(elliptic.P256())
+		derive = o.KeyAgreement
+	case *bccsp.ECDSAP384KeyGenOpts:
+		curve, err = oidFromNamedCurve(elliptic.P384())
+		derive = o.KeyAgreement
+	case *bccsp.ECDSAP521KeyGenOpts:
+		curve, err = oidFromNamedCurve(elliptic.P521())
+		derive = o.KeyAgreement
+	default:
+		return csp.BCCSP.KeyGen(opts)
+	}
+	if err == nil {
+		k, err = csp.generateECDSAKey(curve, opts.Ephemeral(), derive)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed generating ECDSA key: [%w]", err)
+	}
+
+	return k, nil
+}
+
+func (csp *Provider) generateECDSAKey(curve asn1.ObjectIdentifier, ephemeral, derive bool) (bccsp.Key, error) {
+	ski, pub, err := csp.generateECKey(curve, ephemeral, derive)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsaPrivateKey{ski, ecdsaPublicKey{ski, pub}}, nil
+}
+
+// GetKey 先在令牌上查找与ski相关联的ECDSA密钥，如果找不到，再交给软件实现去查找。
+func (csp *Provider) GetKey(ski []byte) (bccsp.Key, error) {
+	if key, ok := csp.cachedKey(ski); ok {
+		return key, nil
+	}
+
+	pubKey, isPriv, err := csp.getECKey(ski)
+	if err != nil {
+		return csp.BCCSP.GetKey(ski)
+	}
+
+	var key bccsp.Key = &ecdsaPublicKey{ski, pubKey}
+	if isPriv {
+		key = &ecdsaPrivateKey{ski, ecdsaPublicKey{ski, pubKey}}
+	}
+
+	csp.cacheKey(ski, key)
+	return key, nil
+}
+
+// Sign 利用令牌上的私钥对摘要进行ECDSA签名，其他类型的密钥交给软件实现处理。
+func (csp *Provider) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("invalid Key, it must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("invalid digest, cannot be empty")
+	}
+
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		if isSchnorrOpts(opts) {
+			// 令牌只能生成ECDSA签名，不能在这种情况下悄悄返回ECDSA签名。
+			return nil, fmt.Errorf("signer opts [%T] not supported for keys stored on the token", opts)
+		}
+		return csp.signECDSA(*key, digest, opts)
+	default:
+		return csp.BCCSP.Sign(key, digest, opts)
+	}
+}
+
+// Verify 验证ECDSA签名，如果SoftwareVerify为true，则在软件中完成验证，否则在令牌上完成验证。
+func (csp *Provider) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if k == nil {
+		return false, errors.New("invalid Key, it must not be nil")
+	}
+	if len(signature) == 0 {
+		return false, errors.New("invalid signature, cannot be empty")
+	}
+	if len(digest) == 0 {
+		return false, errors.New("invalid digest, cannot be empty")
+	}
+
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		if isSchnorrOpts(opts) {
+			return csp.verifySchnorr(key.pub, signature, digest, opts)
+		}
+		return csp.verifyECDSA(key.pub, signature, digest, opts)
+	case *ecdsaPublicKey:
+		if isSchnorrOpts(opts) {
+			return csp.verifySchnorr(*key, signature, digest, opts)
+		}
+		return csp.verifyECDSA(*key, signature, digest, opts)
+	default:
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+}
+
+// isSchnorrOpts 判断签名选项是否要求Schnorr签名或MuSig2部分签名，令牌不支持这两种签名。
+func isSchnorrOpts(opts bccsp.SignerOpts) bool {
+	switch opts.(type) {
+	case *bccsp.SchnorrSignerOpts, *bccsp.MuSig2SignerOpts:
+		return true
+	default:
+		return false
+	}
+}
+
+// verifySchnorr 将令牌上的公钥导入到软件实现中，然后在软件中验证Schnorr签名或MuSig2部分签名。
+func (csp *Provider) verifySchnorr(pub ecdsaPublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	k, err := csp.BCCSP.KeyImport(pub.pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return false, fmt.Errorf("failed importing public key [%w]", err)
+	}
+	return csp.BCCSP.Verify(k, signature, digest, opts)
+}
+
+// KeyDeriv 利用令牌上的私钥与对方的公钥进行ECDH密钥协商，派生出共享的对称密钥，其他情况交给软件实现处理。
+// 共享秘密经过KDF以后作为AES-256密钥导入到软件实现中（或者经过HKDF由软件实现派生出密钥），因此派生出的
+// 密钥可以直接用于软件实现的加解密。
+func (csp *Provider) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	if k == nil {
+		return nil, errors.New("invalid Key, it must not be nil")
+	}
+
+	key, ok := k.(*ecdsaPrivateKey)
+	ecdhOpts, isECDH := opts.(*bccsp.ECDHKeyDerivOpts)
+	if !ok || !isECDH {
+		return csp.BCCSP.KeyDeriv(k, opts)
+	}
+
+	secret, err := csp.deriveECDH(*key, ecdhOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed deriving key with opts [%v]: [%w]", opts, err)
+	}
+
+	// 指定了HKDF时，将共享秘密作为暂时的对称密钥导入，再交给软件实现用HKDF派生出最终的密钥。
+	if ecdhOpts.HKDF != nil {
+		ikm, err := csp.BCCSP.KeyImport(secret, &bccsp.HMACImportKeyOpts{Temporary: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed importing shared secret [%w]", err)
+		}
+		hkdfOpts := *ecdhOpts.HKDF
+		hkdfOpts.Temporary = opts.Ephemeral()
+		return csp.BCCSP.KeyDeriv(ikm, &hkdfOpts)
+	}
+
+	raw, err := ecdhOpts.DeriveKey(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed deriving key with opts [%v]: [%w]", opts, err)
+	}
+
+	return csp.BCCSP.KeyImport(raw, &bccsp.AES256ImportKeyOpts{Temporary: opts.Ephemeral()})
+}
+
+func (csp *Provider) deriveECDH(k ecdsaPrivateKey, opts *bccsp.ECDHKeyDerivOpts) ([]byte, error) {
+	peerKey := opts.PeerPublicKey
+	if peerKey == nil {
+		return nil, errors.New("invalid peer public key, it must not be nil")
+	}
+	if peerKey.Private() {
+		var err error
+		if peerKey, err = peerKey.PublicKey(); err != nil {
+			return nil, fmt.Errorf("failed getting peer public key [%w]", err)
+		}
+	}
+	raw, err := peerKey.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling peer public key [%w]", err)
+	}
+	peer, err := utils.ParseECPKIXPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer public key, expected an ECDSA public key [%w]", err)
+	}
+
+	curve := k.pub.pub.Curve
+	if peer.Curve.Params().Name != curve.Params().Name {
+		return nil, fmt.Errorf("invalid peer public key, curve [%s] does not match [%s]", peer.Curve.Params().Name, curve.Params().Name)
+	}
+
+	return csp.deriveP11ECDH(k.ski, elliptic.Marshal(curve, peer.X, peer.Y), (curve.Params().BitSize+7)/8)
+}
+
+func (csp *Provider) signECDSA(k ecdsaPrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	r, s, err := csp.signP11ECDSA(k.ski, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err = utils.ToLowS(k.pub.pub, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.MarshalECDSASignature(r, s)
+}
+
+func (csp *Provider) verifyECDSA(k ecdsaPublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	r, s, err := utils.UnmarshalECDSASignature(signature)
+	if err != nil {
+		return false, fmt.Errorf("failed unmashalling signature [%s]", err)
+	}
+
+	lowS, err := utils.IsLowS(k.pub, s)
+	if err != nil {
+		return false, err
+	}
+	if !lowS {
+		return false, fmt.Errorf("invalid S, must be smaller than half the order [%s][%s]", s, utils.GetCurveHalfOrderAt(k.pub.Curve))
+	}
+
+	if csp.softVerify {
+		return ecdsa.Verify(k.pub, digest, r, s), nil
+	}
+
+	return csp.verifyP11ECDSA(k.ski, digest, r, s, (k.pub.Curve.Params().BitSize+7)/8)
+}
+
+func (csp *Provider) cachedKey(ski []byte) (bccsp.Key, bool) {
+	csp.cacheLock.RLock()
+	defer csp.cacheLock.RUnlock()
+
+	key, ok := csp.keyCache[string(ski)]
+	return key, ok
+}
+
+func (csp *Provider) cacheKey(ski []byte, key bccsp.Key) {
+	csp.cacheLock.Lock()
+	defer csp.cacheLock.Unlock()
+
+	csp.keyCache[string(ski)] = key
+}
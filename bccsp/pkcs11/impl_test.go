@@ -0,0 +1,581 @@
+package pkcs11
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/sw"
+	"github.com/232425wxy/lark/common/metrics"
+	"github.com/232425wxy/lark/common/metrics/disabled"
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+// softHSMLibraries 是SoftHSMv2常见的安装路径。
+var softHSMLibraries = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+// testOpts 返回测试使用的选项，PKCS11_LIB、PKCS11_PIN和PKCS11_LABEL环境变量可以覆盖默认值。如果找不到
+// PKCS#11库，则跳过测试。
+func testOpts(t *testing.T) PKCS11Opts {
+	lib := os.Getenv("PKCS11_LIB")
+	if lib == "" {
+		for _, path := range softHSMLibraries {
+			if _, err := os.Stat(path); err == nil {
+				lib = path
+				break
+			}
+		}
+	}
+	if lib == "" {
+		t.Skip("PKCS#11 library not found, set PKCS11_LIB to run the test")
+	}
+
+	pin := os.Getenv("PKCS11_PIN")
+	if pin == "" {
+		pin = "98765432"
+	}
+	label := os.Getenv("PKCS11_LABEL")
+	if label == "" {
+		label = "ForLark"
+	}
+
+	return PKCS11Opts{
+		Security: 256,
+		Hash:     bccsp.SHA2,
+		Library:  lib,
+		Label:    label,
+		Pin:      pin,
+	}
+}
+
+func newTestProvider(t *testing.T, opts PKCS11Opts) *Provider {
+	csp, err := New(opts, sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	t.Cleanup(func() { csp.Close() })
+	return csp
+}
+
+func TestNewInvalidOpts(t *testing.T) {
+	_, err := New(PKCS11Opts{Security: 128, Hash: bccsp.SHA2}, sw.NewInMemoryKeyStore())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "security level not supported")
+
+	_, err = New(PKCS11Opts{Security: 256, Hash: bccsp.SHA2, Label: "ForLark"}, sw.NewInMemoryKeyStore())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "library path not provided")
+
+	_, err = New(PKCS11Opts{Security: 256, Hash: bccsp.SHA2, Library: "/no/such/library.so", Label: "ForLark"}, sw.NewInMemoryKeyStore())
+	require.Error(t, err)
+}
+
+func TestKeyGenSignVerify(t *testing.T) {
+	opts := testOpts(t)
+
+	for _, softVerify := range []bool{false, true} {
+		opts.SoftwareVerify = softVerify
+		csp := newTestProvider(t, opts)
+
+		k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+		require.NoError(t, err)
+		require.True(t, k.Private())
+		require.IsType(t, &ecdsaPrivateKey{}, k)
+
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+		raw, err := pk.Bytes()
+		require.NoError(t, err)
+		require.NotEmpty(t, raw)
+
+		digest := sha256.Sum256([]byte("hello world"))
+		signature, err := csp.Sign(k, digest[:], nil)
+		require.NoError(t, err)
+
+		valid, err := csp.Verify(k, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+		valid, err = csp.Verify(pk, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+
+		other := sha256.Sum256([]byte("another message"))
+		valid, err = csp.Verify(pk, signature, other[:], nil)
+		require.NoError(t, err)
+		require.False(t, valid)
+
+		// 签名可以用软件实现的BCCSP验证。
+		imported, err := csp.KeyImport(raw, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+		require.NoError(t, err)
+		valid, err = csp.Verify(imported, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+}
+
+func TestECDHKeyDeriv(t *testing.T) {
+	csp := newTestProvider(t, testOpts(t))
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true, KeyAgreement: true})
+	require.NoError(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+
+	peer, err := csp.BCCSP.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	peerPub, err := peer.PublicKey()
+	require.NoError(t, err)
+
+	// 令牌上的私钥与软件实现的私钥协商出的密钥相同。
+	shared, err := csp.KeyDeriv(k, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: peerPub})
+	require.NoError(t, err)
+	peerShared, err := csp.KeyDeriv(peer, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: pk})
+	require.NoError(t, err)
+	require.Equal(t, shared.SKI(), peerShared.SKI())
+
+	// 没有声明用于密钥协商的密钥不能进行ECDH。
+	signer, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = csp.KeyDeriv(signer, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: peerPub})
+	require.Error(t, err)
+}
+
+func TestGetKeyFromToken(t *testing.T) {
+	opts := testOpts(t)
+	csp := newTestProvider(t, opts)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+
+	// 用新的Provider查找，确保密钥是从令牌上找到的，而不是来自缓存。
+	csp2 := newTestProvider(t, opts)
+	k2, err := csp2.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.True(t, k2.Private())
+	require.Equal(t, k.SKI(), k2.SKI())
+
+	digest := sha256.Sum256([]byte("hello world"))
+	signature, err := csp2.Sign(k2, digest[:], nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	_, err = csp2.GetKey([]byte("no such key"))
+	require.Error(t, err)
+}
+
+func TestImmutableKey(t *testing.T) {
+	opts := testOpts(t)
+	opts.Immutable = true
+	csp := newTestProvider(t, opts)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("hello world"))
+	signature, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+func TestDelegateToSoftware(t *testing.T) {
+	csp := newTestProvider(t, testOpts(t))
+
+	k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	k2, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+
+	ct, err := csp.Encrypt(k, []byte("hello world"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	pt, err := csp.Decrypt(k2, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello world"), pt)
+
+	digest, err := csp.Hash([]byte("hello world"), &bccsp.SHA256Opts{})
+	require.NoError(t, err)
+	expected := sha256.Sum256([]byte("hello world"))
+	require.Equal(t, expected[:], digest)
+}
+
+func TestSessionCache(t *testing.T) {
+	opts := testOpts(t)
+	opts.sessionCacheSize = 2
+	csp := newTestProvider(t, opts)
+
+	tok := csp.tokens[0]
+
+	var sessions []pkcs11.SessionHandle
+	var gen uint64
+	for i := 0; i < 4; i++ {
+		session, g, err := tok.getSession()
+		require.NoError(t, err)
+		sessions = append(sessions, session)
+		gen = g
+	}
+	for _, session := range sessions {
+		tok.returnSession(session, gen)
+	}
+
+	// 缓存已满时，多余的会话会被关闭。
+	require.Len(t, tok.sessPool, 2)
+	tok.sessLock.Lock()
+	require.Len(t, tok.sessions, 2)
+	tok.sessLock.Unlock()
+
+	// 取出以后所有的会话被丢弃过，归还的会话不会再放回缓存。
+	session, gen, err := tok.getSession()
+	require.NoError(t, err)
+	tok.resetSessions()
+	tok.returnSession(session, gen)
+	require.Len(t, tok.sessPool, 0)
+}
+
+func TestReturnStaleSession(t *testing.T) {
+	tok := &token{
+		sessPool:   make(chan pkcs11.SessionHandle, 2),
+		sessions:   map[pkcs11.SessionHandle]struct{}{},
+		generation: 1,
+	}
+
+	// 会话在generation变化之前取出，它已经被关闭，句柄可能被新的会话复用，不能放回缓存。
+	tok.returnSession(7, 0)
+	require.Len(t, tok.sessPool, 0)
+
+	// 其他协程已经丢弃过所有的会话时，不再重复丢弃。
+	tok.discardSessions(0)
+	require.Equal(t, uint64(1), tok.generation)
+}
+
+func TestIsSessionError(t *testing.T) {
+	require.True(t, isSessionError(pkcs11.Error(pkcs11.CKR_SESSION_HANDLE_INVALID)))
+	require.True(t, isSessionError(fmt.Errorf("P11: sign failed [%w]", pkcs11.Error(pkcs11.CKR_DEVICE_REMOVED))))
+	require.False(t, isSessionError(pkcs11.Error(pkcs11.CKR_SIGNATURE_INVALID)))
+	require.False(t, isSessionError(errors.New("session handle invalid")))
+	require.False(t, isSessionError(nil))
+
+	require.True(t, isSlotError(pkcs11.Error(pkcs11.CKR_SLOT_ID_INVALID)))
+	require.False(t, isSlotError(pkcs11.Error(pkcs11.CKR_SESSION_HANDLE_INVALID)))
+}
+
+func TestNamedCurveFromOID(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		oid, err := oidFromNamedCurve(curve)
+		require.NoError(t, err)
+		require.Equal(t, curve, namedCurveFromOID(oid))
+	}
+
+	require.Nil(t, namedCurveFromOID(asn1.ObjectIdentifier{1, 2, 3}))
+
+	_, err := curveForSecurityLevel(512)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "security level not supported [512]")
+}
+
+func TestNextRetryDelay(t *testing.T) {
+	require.Equal(t, 200*time.Millisecond, nextRetryDelay(100*time.Millisecond))
+	require.Equal(t, maxCreateSessionRetryDelay, nextRetryDelay(maxCreateSessionRetryDelay))
+	require.Equal(t, maxCreateSessionRetryDelay, nextRetryDelay(maxCreateSessionRetryDelay-time.Millisecond))
+}
+
+// countingProvider 记录计数器被增加的总量，用于检查统计指标。
+type countingProvider struct {
+	disabled.Provider
+	mutex  sync.Mutex
+	counts map[string]float64
+}
+
+type countingCounter struct {
+	p    *countingProvider
+	name string
+}
+
+// With 将标签的值附加到计数器的名称上，例如"session_retries.token=1234"。
+func (c *countingCounter) With(labelValues ...string) metrics.Counter {
+	name := c.name
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		name += fmt.Sprintf(".%s=%s", labelValues[i], labelValues[i+1])
+	}
+	return &countingCounter{p: c.p, name: name}
+}
+
+func (c *countingCounter) Add(delta float64) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	c.p.counts[c.name] += delta
+}
+
+func (p *countingProvider) NewCounter(opts metrics.CounterOpts) metrics.Counter {
+	return &countingCounter{p: p, name: opts.Name}
+}
+
+func (p *countingProvider) count(name string) float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.counts[name]
+}
+
+func TestSessionRecovery(t *testing.T) {
+	opts := testOpts(t)
+	provider := &countingProvider{counts: map[string]float64{}}
+	opts.MetricsProvider = provider
+	csp := newTestProvider(t, opts)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+
+	// 模拟令牌被重置：关闭令牌上所有的会话，缓存中的会话全部失效。
+	require.NoError(t, csp.ctx.CloseAllSessions(csp.tokens[0].slot))
+
+	digest := sha256.Sum256([]byte("hello world"))
+	signature, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	name := tokenName(csp.ctx, csp.tokens[0].slot, csp.tokens[0].opts)
+	require.Equal(t, float64(1), provider.count("session_retries.token="+name))
+
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+func TestConcurrentSessionRecovery(t *testing.T) {
+	opts := testOpts(t)
+	provider := &countingProvider{counts: map[string]float64{}}
+	opts.MetricsProvider = provider
+	csp := newTestProvider(t, opts)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("hello world"))
+
+	// 多个协程并发签名的同时令牌被重置，每个签名都应该成功，已经取出的失效会话不能再回到缓存中。
+	const workers, rounds = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				signature, err := csp.Sign(k, digest[:], nil)
+				if err == nil {
+					_, err = csp.Verify(k, signature, digest[:], nil)
+				}
+				errs <- err
+			}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, csp.ctx.CloseAllSessions(csp.tokens[0].slot))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// 令牌重置以后，缓存中不会留下失效的会话。
+	_, err = csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	name := tokenName(csp.ctx, csp.tokens[0].slot, csp.tokens[0].opts)
+	retries := provider.count("session_retries.token=" + name)
+	_, err = csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	require.Equal(t, retries, provider.count("session_retries.token="+name))
+}
+
+func TestKeyIDMappings(t *testing.T) {
+	keyIDs, err := keyIDMappings([]KeyIDMapping{
+		{SKI: "0102", ID: "key-1"},
+		{SKI: "0304", Label: "signing key"},
+	})
+	require.NoError(t, err)
+	require.Len(t, keyIDs, 2)
+	require.Equal(t, "key-1", keyIDs[string([]byte{1, 2})].ID)
+	require.Equal(t, "signing key", keyIDs[string([]byte{3, 4})].Label)
+
+	_, err = keyIDMappings([]KeyIDMapping{{SKI: "not hex", ID: "key-1"}})
+	require.Error(t, err)
+	_, err = keyIDMappings([]KeyIDMapping{{SKI: "", ID: "key-1"}})
+	require.Error(t, err)
+	_, err = keyIDMappings([]KeyIDMapping{{SKI: "0102"}})
+	require.Error(t, err)
+	_, err = keyIDMappings([]KeyIDMapping{{SKI: "0102", ID: "a"}, {SKI: "0102", ID: "b"}})
+	require.Error(t, err)
+
+	_, err = New(PKCS11Opts{Security: 256, Hash: bccsp.SHA2, KeyIDs: []KeyIDMapping{{SKI: "0102"}}}, sw.NewInMemoryKeyStore())
+	require.Error(t, err)
+}
+
+func TestAltIDAndKeyIDs(t *testing.T) {
+	opts := testOpts(t)
+	altID := fmt.Sprintf("alt-%d", time.Now().UnixNano())
+	opts.AltID = altID
+	csp := newTestProvider(t, opts)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("hello world"))
+	signature, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	// CKA_ID不等于SKI的密钥可以通过KeyIDs中配置的CKA_ID或CKA_LABEL找到。
+	for _, mapping := range []KeyIDMapping{
+		{SKI: hex.EncodeToString(k.SKI()), ID: altID},
+		{SKI: hex.EncodeToString(k.SKI()), Label: hex.EncodeToString(k.SKI())},
+	} {
+		opts := testOpts(t)
+		opts.KeyIDs = []KeyIDMapping{mapping}
+		csp2 := newTestProvider(t, opts)
+
+		k2, err := csp2.GetKey(k.SKI())
+		require.NoError(t, err)
+		require.True(t, k2.Private())
+		valid, err := csp2.Verify(k2, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+
+	// 没有配置映射关系时，按CKA_LABEL也能找到以AltID生成的密钥。
+	csp3 := newTestProvider(t, testOpts(t))
+	k3, err := csp3.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.True(t, k3.Private())
+}
+
+func TestAltIDSharedByKeys(t *testing.T) {
+	opts := testOpts(t)
+	opts.AltID = fmt.Sprintf("alt-%d", time.Now().UnixNano())
+	csp := newTestProvider(t, opts)
+
+	k1, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	k2, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	// 句柄缓存被清空以后，两个CKA_ID相同的密钥仍然能够通过CKA_LABEL区分开。
+	for _, tok := range csp.tokens {
+		tok.clearHandles()
+	}
+	digest := sha256.Sum256([]byte("hello world"))
+	for _, k := range []bccsp.Key{k1, k2} {
+		signature, err := csp.Sign(k, digest[:], nil)
+		require.NoError(t, err)
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+		valid, err := csp.Verify(pk, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+}
+
+func TestTokenOpts(t *testing.T) {
+	slot := uint(3)
+	info := pkcs11.TokenInfo{Label: "ForLark", SerialNumber: "5a1b2c3d"}
+
+	require.True(t, TokenOpts{Label: "ForLark"}.matches(1, info))
+	require.True(t, TokenOpts{SerialNumber: "5a1b2c3d"}.matches(1, info))
+	require.True(t, TokenOpts{SlotID: &slot}.matches(3, info))
+	require.True(t, TokenOpts{Label: "ForLark", SerialNumber: "5a1b2c3d", SlotID: &slot}.matches(3, info))
+	require.False(t, TokenOpts{Label: "ForLark", SerialNumber: "ffffffff"}.matches(3, info))
+	require.False(t, TokenOpts{Label: "ForLark", SlotID: &slot}.matches(1, info))
+
+	require.Error(t, TokenOpts{Pin: "1234"}.validate())
+	require.NoError(t, TokenOpts{SlotID: &slot}.validate())
+	require.Equal(t, "label=ForLark,serial=5a1b2c3d,slot=3", TokenOpts{Label: "ForLark", SerialNumber: "5a1b2c3d", SlotID: &slot}.String())
+
+	opts := PKCS11Opts{Label: "signing", Pin: "1234", Tokens: []TokenOpts{{Label: "encryption"}, {SlotID: &slot, Pin: "5678"}}}
+	tokens := opts.tokenOpts()
+	require.Len(t, tokens, 3)
+	require.Equal(t, "signing", tokens[0].Label)
+	require.Equal(t, "1234", tokens[1].Pin)
+	require.Equal(t, "5678", tokens[2].Pin)
+
+	_, err := New(PKCS11Opts{Security: 256, Hash: bccsp.SHA2, Library: "/no/such/library.so"}, sw.NewInMemoryKeyStore())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "a token must be selected by label, serial number or slot ID")
+}
+
+func TestTokenSelection(t *testing.T) {
+	opts := testOpts(t)
+	csp := newTestProvider(t, opts)
+	tok := csp.tokens[0]
+	info, err := csp.ctx.GetTokenInfo(tok.slot)
+	require.NoError(t, err)
+
+	// 按序列号和插槽ID选择同一个令牌。
+	bySerial := opts
+	bySerial.Label = ""
+	bySerial.SerialNumber = info.SerialNumber
+	require.Equal(t, tok.slot, newTestProvider(t, bySerial).tokens[0].slot)
+
+	bySlot := opts
+	bySlot.Label = ""
+	bySlot.SlotID = &tok.slot
+	require.Equal(t, tok.slot, newTestProvider(t, bySlot).tokens[0].slot)
+
+	mismatch := opts
+	mismatch.SerialNumber = "no such serial"
+	_, err = New(mismatch, sw.NewInMemoryKeyStore())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "could not find token")
+
+	// 同一个令牌不能被配置两次。
+	twice := opts
+	twice.Tokens = []TokenOpts{{SerialNumber: info.SerialNumber}}
+	_, err = New(twice, sw.NewInMemoryKeyStore())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "configured more than once")
+}
+
+// TestMultipleTokens 需要通过PKCS11_LABEL2环境变量指定第二个令牌。
+func TestMultipleTokens(t *testing.T) {
+	opts := testOpts(t)
+	label2 := os.Getenv("PKCS11_LABEL2")
+	if label2 == "" {
+		t.Skip("set PKCS11_LABEL2 to run the test")
+	}
+
+	// 在第二个令牌上生成密钥。
+	opts2 := opts
+	opts2.Label = label2
+	k, err := newTestProvider(t, opts2).KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+
+	opts.Tokens = []TokenOpts{{Label: label2}}
+	csp := newTestProvider(t, opts)
+
+	// 根据SKI找到第二个令牌上的密钥，并在该令牌上签名。
+	k2, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.Equal(t, csp.tokens[1], csp.keyToken(k.SKI()))
+
+	digest := sha256.Sum256([]byte("hello world"))
+	signature, err := csp.Sign(k2, digest[:], nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k2, signature, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// 新的密钥在第一个令牌上生成。
+	k3, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, csp.tokens[0], csp.keyToken(k3.SKI()))
+}
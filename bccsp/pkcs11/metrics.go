@@ -0,0 +1,59 @@
+package pkcs11
+
+import "github.com/232425wxy/lark/common/metrics"
+
+var (
+	sessionPoolSizeOpts = metrics.GaugeOpts{
+		Namespace:   "bccsp",
+		Subsystem:   "pkcs11",
+		Name:        "session_pool_size",
+		Help:        "The number of idle PKCS#11 sessions in the session pool.",
+		LabelNames:  []string{"token"},
+		StatsFormat: "%{#fqname}.%{token}",
+	}
+	sessionOpenFailuresOpts = metrics.CounterOpts{
+		Namespace:   "bccsp",
+		Subsystem:   "pkcs11",
+		Name:        "session_open_failures",
+		Help:        "The number of failed attempts to open and log in a PKCS#11 session.",
+		LabelNames:  []string{"token"},
+		StatsFormat: "%{#fqname}.%{token}",
+	}
+	sessionRetriesOpts = metrics.CounterOpts{
+		Namespace:   "bccsp",
+		Subsystem:   "pkcs11",
+		Name:        "session_retries",
+		Help:        "The number of operations retried after the session became invalid.",
+		LabelNames:  []string{"token"},
+		StatsFormat: "%{#fqname}.%{token}",
+	}
+)
+
+// Metrics 包含基于PKCS#11的BCCSP统计的指标，所有的指标都以令牌的序列号（没有序列号时为标签）作为token
+// 标签的值，以区分不同的令牌。
+type Metrics struct {
+	// SessionPoolSize 是会话缓存中空闲会话的数量。
+	SessionPoolSize metrics.Gauge
+	// SessionOpenFailures 是打开会话或登录失败的次数。
+	SessionOpenFailures metrics.Counter
+	// SessionRetries 是会话失效后重试操作的次数。
+	SessionRetries metrics.Counter
+}
+
+// NewMetrics 利用provider创建统计指标。
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		SessionPoolSize:     p.NewGauge(sessionPoolSizeOpts),
+		SessionOpenFailures: p.NewCounter(sessionOpenFailuresOpts),
+		SessionRetries:      p.NewCounter(sessionRetriesOpts),
+	}
+}
+
+// forToken 返回将token标签设置为name的指标，供名为name的令牌使用。
+func (m *Metrics) forToken(name string) *Metrics {
+	return &Metrics{
+		SessionPoolSize:     m.SessionPoolSize.With("token", name),
+		SessionOpenFailures: m.SessionOpenFailures.With("token", name),
+		SessionRetries:      m.SessionRetries.With("token", name),
+	}
+}
@@ -1,11 +1,18 @@
 package pkcs11
 
-import "time"
+import (
+	"time"
+
+	"github.com/232425wxy/lark/common/metrics"
+)
 
 const (
 	defaultCreateSessionRetries    = 10
 	defaultCreateSessionRetryDelay = 100 * time.Millisecond
 	defaultSessionCacheSize        = 10
+
+	// maxCreateSessionRetryDelay 是重新打开会话时两次尝试之间等待时间的上限。
+	maxCreateSessionRetryDelay = 2 * time.Second
 )
 
 // PKCS11Opts 包含公钥加密标准的选项
@@ -16,11 +23,56 @@ type PKCS11Opts struct {
 
 	Library        string `json:"library"`
 	Label          string `json:"label"`
+	SerialNumber   string `json:"serialnumber,omitempty"`
+	SlotID         *uint  `json:"slotid,omitempty"`
 	Pin            string `json:"pin"`
 	SoftwareVerify bool   `json:"softwareverify,omitempty"`
 	Immutable      bool   `json:"immutable,omitempty"`
 
+	// KeyIDs 将密钥的SKI映射为令牌上密钥的CKA_ID或CKA_LABEL，用于查找预先在令牌上生成的、CKA_ID不等于SKI的密钥。
+	KeyIDs []KeyIDMapping `json:"keyids,omitempty"`
+	// AltID 不为空时，新生成的密钥的CKA_ID被设置为AltID，而不是SKI。
+	AltID string `json:"altid,omitempty"`
+
+	// Tokens 是除了Label、SerialNumber和SlotID选中的令牌以外，还需要使用的其他令牌。
+	Tokens []TokenOpts `json:"tokens,omitempty"`
+
+	// MetricsProvider 用于统计会话缓存的大小、打开会话失败的次数以及会话失效后重试的次数，为空时不做统计。
+	MetricsProvider metrics.Provider `json:"-" yaml:"-"`
+
 	sessionCacheSize        int
 	createSessionRetries    int
 	createSessionRetryDelay time.Duration
 }
+
+// KeyIDMapping 将SKI映射为令牌上密钥的CKA_ID或CKA_LABEL，ID和Label至少需要设置一个，同时设置时，令牌上的
+// 密钥需要同时满足这两个条件。
+type KeyIDMapping struct {
+	// SKI 是十六进制编码的密钥标识符。
+	SKI string `json:"ski"`
+	// ID 是令牌上密钥的CKA_ID。
+	ID string `json:"id,omitempty"`
+	// Label 是令牌上密钥的CKA_LABEL。
+	Label string `json:"label,omitempty"`
+}
+
+// TokenOpts 包含选择令牌的条件，所有设置了的条件都必须满足，并且满足条件的令牌必须有且只有一个。
+type TokenOpts struct {
+	Label        string `json:"label,omitempty"`
+	SerialNumber string `json:"serialnumber,omitempty"`
+	SlotID       *uint  `json:"slotid,omitempty"`
+	// Pin 为空时，使用PKCS11Opts中的Pin登录。
+	Pin string `json:"pin,omitempty"`
+}
+
+// tokenOpts 返回需要使用的所有令牌的选项，第一个是新的密钥所在的令牌。
+func (o PKCS11Opts) tokenOpts() []TokenOpts {
+	tokens := []TokenOpts{{Label: o.Label, SerialNumber: o.SerialNumber, SlotID: o.SlotID, Pin: o.Pin}}
+	for _, t := range o.Tokens {
+		if t.Pin == "" {
+			t.Pin = o.Pin
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
@@ -1,2 +1,515 @@
 package pkcs11
 
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/232425wxy/lark/bccsp/utils"
+	"github.com/miekg/pkcs11"
+)
+
+// namedCurveFromOID 根据椭圆曲线的OID从曲线注册表中查找对应的椭圆曲线。
+func namedCurveFromOID(oid asn1.ObjectIdentifier) elliptic.Curve {
+	info, ok := utils.LookupCurveByOID(oid)
+	if !ok {
+		return nil
+	}
+	return info.Curve
+}
+
+// oidFromNamedCurve 从曲线注册表中查找椭圆曲线的OID。
+func oidFromNamedCurve(curve elliptic.Curve) (asn1.ObjectIdentifier, error) {
+	info, ok := utils.LookupCurve(curve)
+	if !ok {
+		return nil, fmt.Errorf("curve not recognized [%s]", curve.Params().Name)
+	}
+	return info.OID, nil
+}
+
+// curveForSecurityLevel 根据安全级别返回默认使用的椭圆曲线的OID。
+func curveForSecurityLevel(securityLevel int) (asn1.ObjectIdentifier, error) {
+	switch securityLevel {
+	case 256:
+		return oidFromNamedCurve(elliptic.P256())
+	case 384:
+		return oidFromNamedCurve(elliptic.P384())
+	default:
+		return nil, fmt.Errorf("security level not supported [%d]", securityLevel)
+	}
+}
+
+// isCKR 判断err是否是给定的PKCS#11返回值。
+func isCKR(err error, ckr uint) bool {
+	var p11Err pkcs11.Error
+	return errors.As(err, &p11Err) && uint(p11Err) == ckr
+}
+
+// isSessionError 判断err是否表示会话已经失效，例如令牌被重置或者被拔出，此时会话需要被丢弃并重新打开。
+func isSessionError(err error) bool {
+	for _, ckr := range []uint{
+		pkcs11.CKR_SESSION_HANDLE_INVALID,
+		pkcs11.CKR_SESSION_CLOSED,
+		pkcs11.CKR_USER_NOT_LOGGED_IN,
+		pkcs11.CKR_DEVICE_REMOVED,
+		pkcs11.CKR_DEVICE_ERROR,
+		pkcs11.CKR_TOKEN_NOT_PRESENT,
+		pkcs11.CKR_TOKEN_NOT_RECOGNIZED,
+	} {
+		if isCKR(err, ckr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSlotError 判断err是否表示令牌所在的插槽已经变化，此时需要重新查找令牌。
+func isSlotError(err error) bool {
+	return isCKR(err, pkcs11.CKR_SLOT_ID_INVALID) ||
+		isCKR(err, pkcs11.CKR_DEVICE_REMOVED) ||
+		isCKR(err, pkcs11.CKR_TOKEN_NOT_PRESENT) ||
+		isCKR(err, pkcs11.CKR_TOKEN_NOT_RECOGNIZED)
+}
+
+// getECKey 在令牌上查找与ski相关联的EC公钥，isPriv表示令牌上是否还存在对应的私钥。
+func (csp *Provider) getECKey(ski []byte) (pubKey *ecdsa.PublicKey, isPriv bool, err error) {
+	var ecpt, marshaledOid []byte
+	err = csp.onKeyToken(ski, func(tok *token, session pkcs11.SessionHandle) error {
+		isPriv = true
+		if _, err := csp.findKeyPairFromSKI(tok, session, ski, privateKeyType); err != nil {
+			isPriv = false
+		}
+
+		publicKey, err := csp.findKeyPairFromSKI(tok, session, ski, publicKeyType)
+		if err != nil {
+			return fmt.Errorf("public key not found [%w] for SKI [%s]", err, hex.EncodeToString(ski))
+		}
+
+		ecpt, marshaledOid, err = csp.ecPoint(session, publicKey)
+		if err != nil {
+			return fmt.Errorf("public key not found [%w] for SKI [%s]", err, hex.EncodeToString(ski))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	curveOid := new(asn1.ObjectIdentifier)
+	if _, err = asn1.Unmarshal(marshaledOid, curveOid); err != nil {
+		return nil, false, fmt.Errorf("failed unmarshalling curve OID [%s]", err)
+	}
+
+	curve := namedCurveFromOID(*curveOid)
+	if curve == nil {
+		return nil, false, fmt.Errorf("could not recognize curve from OID [%s]", curveOid)
+	}
+
+	x, y := elliptic.Unmarshal(curve, ecpt)
+	if x == nil {
+		return nil, false, errors.New("failed unmarshalling public key")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, isPriv, nil
+}
+
+// generateECKey 在令牌上生成EC密钥对，密钥的CKA_ID和CKA_LABEL被设置为公钥点的SHA256哈希值。ephemeral
+// 为true时生成的是会话对象，会话关闭后即被销毁。derive为true时私钥可以用于ECDH密钥协商（CKA_DERIVE），
+// 否则只能用于签名。
+// 密钥总是在第一个令牌上生成。
+//
+// 密钥生成不是幂等操作，所以会话失效时不会重试，只会丢弃失效的会话。
+func (csp *Provider) generateECKey(curve asn1.ObjectIdentifier, ephemeral, derive bool) (ski []byte, pubKey *ecdsa.PublicKey, err error) {
+	tok := csp.tokens[0]
+	session, gen, err := tok.getSession()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { tok.releaseSession(session, gen, err) }()
+
+	id := nextIDCtr()
+	publabel := fmt.Sprintf("BCPUB%s", id.Text(16))
+	prvlabel := fmt.Sprintf("BCPRV%s", id.Text(16))
+
+	marshaledOID, err := asn1.Marshal(curve)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not marshal OID [%s]", err)
+	}
+
+	pubkeyTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, !ephemeral),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, marshaledOID),
+
+		pkcs11.NewAttribute(pkcs11.CKA_ID, publabel),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, publabel),
+	}
+
+	prvkeyTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, !ephemeral),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_DERIVE, derive),
+
+		pkcs11.NewAttribute(pkcs11.CKA_ID, prvlabel),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, prvlabel),
+
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+	}
+
+	pub, prv, err := csp.ctx.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)},
+		pubkeyTemplate, prvkeyTemplate,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("P11: keypair generate failed [%w]", err)
+	}
+
+	ecpt, _, err := csp.ecPoint(session, pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying EC-point: [%w]", err)
+	}
+	hash := sha256.Sum256(ecpt)
+	ski = hash[:]
+
+	// 用公钥点的哈希值作为密钥的CKA_ID和CKA_LABEL，这样就可以通过SKI找到密钥。如果配置了AltID，则用它作为
+	// CKA_ID，并记住SKI与它的映射关系。以同一个AltID生成的密钥共享CKA_ID，所以映射中同时记录CKA_LABEL，以便
+	// 区分它们。
+	keyID := ski
+	if csp.altID != "" {
+		keyID = []byte(csp.altID)
+	}
+	setskiT := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ID, keyID),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, hex.EncodeToString(ski)),
+	}
+
+	if err = csp.ctx.SetAttributeValue(session, pub, setskiT); err != nil {
+		return nil, nil, fmt.Errorf("P11: set-ID-to-SKI[public] failed [%w]", err)
+	}
+	if err = csp.ctx.SetAttributeValue(session, prv, setskiT); err != nil {
+		return nil, nil, fmt.Errorf("P11: set-ID-to-SKI[private] failed [%w]", err)
+	}
+
+	// 密钥生成以后，将其设置为不可修改的。
+	if csp.immutable {
+		immutableT := []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODIFIABLE, false),
+		}
+		if err = csp.ctx.SetAttributeValue(session, pub, immutableT); err != nil {
+			return nil, nil, fmt.Errorf("P11: set-immutable[public] failed [%w]", err)
+		}
+		if err = csp.ctx.SetAttributeValue(session, prv, immutableT); err != nil {
+			return nil, nil, fmt.Errorf("P11: set-immutable[private] failed [%w]", err)
+		}
+	}
+
+	nistCurve := namedCurveFromOID(curve)
+	if nistCurve == nil {
+		return nil, nil, fmt.Errorf("cannot determine curve for OID [%s]", curve)
+	}
+
+	x, y := elliptic.Unmarshal(nistCurve, ecpt)
+	if x == nil {
+		return nil, nil, errors.New("failed unmarshalling public key")
+	}
+
+	pubGoKey := &ecdsa.PublicKey{Curve: nistCurve, X: x, Y: y}
+
+	if csp.altID != "" {
+		csp.cacheLock.Lock()
+		csp.keyIDs[string(ski)] = KeyIDMapping{SKI: hex.EncodeToString(ski), ID: csp.altID, Label: hex.EncodeToString(ski)}
+		csp.cacheLock.Unlock()
+	}
+
+	tok.cacheHandle(ski, privateKeyType, prv)
+	tok.cacheHandle(ski, publicKeyType, pub)
+	csp.setKeyToken(ski, tok)
+
+	return ski, pubGoKey, nil
+}
+
+// signP11ECDSA 利用令牌上与ski相关联的私钥对摘要进行签名，返回签名的r和s。
+func (csp *Provider) signP11ECDSA(ski []byte, msg []byte) (R, S *big.Int, err error) {
+	var sig []byte
+	err = csp.onKeyToken(ski, func(tok *token, session pkcs11.SessionHandle) error {
+		privateKey, err := csp.findKeyPairFromSKI(tok, session, ski, privateKeyType)
+		if err != nil {
+			return fmt.Errorf("private key not found [%w]", err)
+		}
+
+		err = csp.ctx.SignInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, privateKey)
+		if err != nil {
+			return fmt.Errorf("sign-initialize failed: [%w]", err)
+		}
+
+		sig, err = csp.ctx.Sign(session, msg)
+		if err != nil {
+			return fmt.Errorf("P11: sign failed [%w]", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	R = new(big.Int)
+	S = new(big.Int)
+	R.SetBytes(sig[0 : len(sig)/2])
+	S.SetBytes(sig[len(sig)/2:])
+
+	return R, S, nil
+}
+
+// verifyP11ECDSA 利用令牌上与ski相关联的公钥验证签名，byteSize是r和s填充后的字节长度。
+func (csp *Provider) verifyP11ECDSA(ski []byte, msg []byte, R, S *big.Int, byteSize int) (valid bool, err error) {
+	r := R.Bytes()
+	s := S.Bytes()
+
+	// r和s需要填充到曲线的字节长度。
+	sig := make([]byte, 2*byteSize)
+	copy(sig[byteSize-len(r):byteSize], r)
+	copy(sig[2*byteSize-len(s):], s)
+
+	err = csp.onKeyToken(ski, func(tok *token, session pkcs11.SessionHandle) error {
+		publicKey, err := csp.findKeyPairFromSKI(tok, session, ski, publicKeyType)
+		if err != nil {
+			return fmt.Errorf("public key not found [%w]", err)
+		}
+
+		err = csp.ctx.VerifyInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, publicKey)
+		if err != nil {
+			return fmt.Errorf("PKCS11: verify-initialize [%w]", err)
+		}
+		err = csp.ctx.Verify(session, msg, sig)
+		if isCKR(err, pkcs11.CKR_SIGNATURE_INVALID) {
+			valid = false
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("PKCS11: verify failed [%w]", err)
+		}
+		valid = true
+		return nil
+	})
+
+	return valid, err
+}
+
+// deriveP11ECDH 利用令牌上与ski相关联的私钥和对方的公钥点（未压缩格式）进行ECDH密钥协商，返回长度为
+// secretLen的共享秘密。共享秘密以会话对象的形式派生出来，读取以后立即销毁。
+func (csp *Provider) deriveP11ECDH(ski []byte, peerPoint []byte, secretLen int) (secret []byte, err error) {
+	err = csp.onKeyToken(ski, func(tok *token, session pkcs11.SessionHandle) error {
+		privateKey, err := csp.findKeyPairFromSKI(tok, session, ski, privateKeyType)
+		if err != nil {
+			return fmt.Errorf("private key not found [%w]", err)
+		}
+
+		mech := pkcs11.NewMechanism(pkcs11.CKM_ECDH1_DERIVE, pkcs11.NewECDH1DeriveParams(pkcs11.CKD_NULL, nil, peerPoint))
+		template := []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, secretLen),
+		}
+		shared, err := csp.ctx.DeriveKey(session, []*pkcs11.Mechanism{mech}, privateKey, template)
+		if err != nil {
+			return fmt.Errorf("P11: derive failed [%w]", err)
+		}
+		defer csp.ctx.DestroyObject(session, shared)
+
+		attr, err := csp.ctx.GetAttributeValue(session, shared, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil)})
+		if err != nil {
+			return fmt.Errorf("P11: get(shared secret) failed [%w]", err)
+		}
+		if len(attr) != 1 || len(attr[0].Value) != secretLen {
+			return errors.New("P11: unexpected shared secret length")
+		}
+		secret = attr[0].Value
+		return nil
+	})
+
+	return secret, err
+}
+
+type keyType int8
+
+const (
+	publicKeyType keyType = iota
+	privateKeyType
+)
+
+// onKeyToken 在持有与ski相关联的密钥的令牌上执行幂等的操作f。如果还不知道密钥在哪个令牌上，则依次在每个令牌
+// 上尝试，并记住第一个执行成功的令牌。
+func (csp *Provider) onKeyToken(ski []byte, f func(tok *token, session pkcs11.SessionHandle) error) error {
+	if tok := csp.keyToken(ski); tok != nil {
+		return tok.withSession(func(session pkcs11.SessionHandle) error {
+			return f(tok, session)
+		})
+	}
+
+	var err error
+	for _, tok := range csp.tokens {
+		tok := tok
+		err = tok.withSession(func(session pkcs11.SessionHandle) error {
+			return f(tok, session)
+		})
+		if err == nil {
+			csp.setKeyToken(ski, tok)
+			return nil
+		}
+	}
+	return err
+}
+
+func (csp *Provider) keyToken(ski []byte) *token {
+	csp.cacheLock.RLock()
+	defer csp.cacheLock.RUnlock()
+
+	return csp.keyTokens[string(ski)]
+}
+
+func (csp *Provider) setKeyToken(ski []byte, tok *token) {
+	csp.cacheLock.Lock()
+	defer csp.cacheLock.Unlock()
+
+	csp.keyTokens[string(ski)] = tok
+}
+
+// findKeyPairFromSKI 在令牌上查找CKA_ID等于ski的公钥或私钥对象。
+func (csp *Provider) findKeyPairFromSKI(tok *token, session pkcs11.SessionHandle, ski []byte, keyType keyType) (pkcs11.ObjectHandle, error) {
+	if handle, ok := tok.cachedHandle(keyType, ski); ok {
+		return handle, nil
+	}
+
+	ktype := pkcs11.CKO_PUBLIC_KEY
+	if keyType == privateKeyType {
+		ktype = pkcs11.CKO_PRIVATE_KEY
+	}
+
+	var obj pkcs11.ObjectHandle
+	var err error
+	for _, identity := range csp.keyIdentities(ski) {
+		template := append([]*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_CLASS, ktype)}, identity...)
+		obj, err = csp.findObject(session, template)
+		if err == nil || isSessionError(err) {
+			break
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed finding key [%s]: [%w]", hex.EncodeToString(ski), err)
+	}
+
+	tok.cacheHandle(ski, keyType, obj)
+
+	return obj, nil
+}
+
+// keyIdentities 返回在令牌上查找与ski相关联的密钥时依次尝试的属性。如果KeyIDs中配置了ski的映射关系，则按照
+// 映射的CKA_ID和CKA_LABEL查找；否则先按CKA_ID等于ski查找，再按CKA_LABEL等于ski的十六进制编码查找，后者
+// 用于找到以AltID作为CKA_ID生成的密钥。
+func (csp *Provider) keyIdentities(ski []byte) [][]*pkcs11.Attribute {
+	csp.cacheLock.RLock()
+	mapping, ok := csp.keyIDs[string(ski)]
+	csp.cacheLock.RUnlock()
+
+	if !ok {
+		return [][]*pkcs11.Attribute{
+			{pkcs11.NewAttribute(pkcs11.CKA_ID, ski)},
+			{pkcs11.NewAttribute(pkcs11.CKA_LABEL, hex.EncodeToString(ski))},
+		}
+	}
+
+	var identity []*pkcs11.Attribute
+	if mapping.ID != "" {
+		identity = append(identity, pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(mapping.ID)))
+	}
+	if mapping.Label != "" {
+		identity = append(identity, pkcs11.NewAttribute(pkcs11.CKA_LABEL, mapping.Label))
+	}
+	return [][]*pkcs11.Attribute{identity}
+}
+
+// findObject 在令牌上查找满足template的第一个对象。
+func (csp *Provider) findObject(session pkcs11.SessionHandle, template []*pkcs11.Attribute) (pkcs11.ObjectHandle, error) {
+	if err := csp.ctx.FindObjectsInit(session, template); err != nil {
+		return 0, err
+	}
+	defer csp.ctx.FindObjectsFinal(session)
+
+	// 只需要一个对象。
+	objs, _, err := csp.ctx.FindObjects(session, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(objs) == 0 {
+		return 0, errors.New("key not found")
+	}
+
+	return objs[0], nil
+}
+
+// ecPoint 返回EC公钥对象的公钥点（未压缩格式）以及ASN.1编码的曲线OID。
+//
+// 根据PKCS#11规范，CKA_EC_POINT是DER编码的OCTET STRING，但有些HSM直接返回未压缩的公钥点，所以这里
+// 同时处理这两种情况。
+func (csp *Provider) ecPoint(session pkcs11.SessionHandle, key pkcs11.ObjectHandle) (ecpt, oid []byte, err error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+	}
+
+	attr, err := csp.ctx.GetAttributeValue(session, key, template)
+	if err != nil {
+		return nil, nil, fmt.Errorf("PKCS11: get(EC point) [%w]", err)
+	}
+
+	for _, a := range attr {
+		switch a.Type {
+		case pkcs11.CKA_EC_POINT:
+			// 尝试按照DER编码的OCTET STRING解析。
+			var point []byte
+			if rest, err := asn1.Unmarshal(a.Value, &point); err == nil && len(rest) == 0 && len(point) > 0 && point[0] == 4 {
+				ecpt = point
+			} else if len(a.Value) > 0 && a.Value[0] == 4 {
+				ecpt = a.Value
+			} else {
+				return nil, nil, errors.New("CKA_EC_POINT not in a recognized format")
+			}
+		case pkcs11.CKA_EC_PARAMS:
+			oid = a.Value
+		}
+	}
+	if oid == nil || ecpt == nil {
+		return nil, nil, fmt.Errorf("CKA_EC_POINT not found, perhaps not an EC key?")
+	}
+
+	return ecpt, oid, nil
+}
+
+var (
+	idCtr   = new(big.Int)
+	idMutex sync.Mutex
+)
+
+// nextIDCtr 返回一个递增的计数器，用于在密钥生成时为其设置临时的标签。
+func nextIDCtr() *big.Int {
+	idMutex.Lock()
+	idCtr = new(big.Int).Add(idCtr, big.NewInt(1))
+	idMutex.Unlock()
+	return idCtr
+}
@@ -0,0 +1,325 @@
+package pkcs11
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/pkcs11"
+)
+
+// token 是Provider使用的一个令牌，每个令牌都有自己的会话缓存和对象句柄缓存。
+type token struct {
+	ctx  *pkcs11.Ctx
+	opts TokenOpts
+
+	sessLock sync.Mutex
+	slot     uint
+	sessPool chan pkcs11.SessionHandle
+	sessions map[pkcs11.SessionHandle]struct{}
+	// generation 在每次丢弃所有会话时加一。取出会话时记录当时的generation，归还时如果generation已经变化，
+	// 说明会话已经被关闭，它的句柄可能已经分配给了新的会话，所以不能再放回缓存。
+	generation uint64
+
+	handleLock  sync.RWMutex
+	handleCache map[string]pkcs11.ObjectHandle
+
+	createSessionRetries    int
+	createSessionRetryDelay time.Duration
+
+	metrics *Metrics
+}
+
+// matches 判断插槽slot上的令牌是否满足选项中设置的所有条件。
+func (o TokenOpts) matches(slot uint, info pkcs11.TokenInfo) bool {
+	if o.Label != "" && o.Label != info.Label {
+		return false
+	}
+	if o.SerialNumber != "" && o.SerialNumber != info.SerialNumber {
+		return false
+	}
+	if o.SlotID != nil && *o.SlotID != slot {
+		return false
+	}
+	return true
+}
+
+func (o TokenOpts) validate() error {
+	if o.Label == "" && o.SerialNumber == "" && o.SlotID == nil {
+		return errors.New("a token must be selected by label, serial number or slot ID")
+	}
+	return nil
+}
+
+func (o TokenOpts) String() string {
+	var criteria []string
+	if o.Label != "" {
+		criteria = append(criteria, fmt.Sprintf("label=%s", o.Label))
+	}
+	if o.SerialNumber != "" {
+		criteria = append(criteria, fmt.Sprintf("serial=%s", o.SerialNumber))
+	}
+	if o.SlotID != nil {
+		criteria = append(criteria, fmt.Sprintf("slot=%d", *o.SlotID))
+	}
+	return strings.Join(criteria, ",")
+}
+
+// findSlot 查找满足opts中所有条件的令牌所在的插槽，满足条件的令牌必须有且只有一个。
+func findSlot(ctx *pkcs11.Ctx, opts TokenOpts) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("pkcs11: get slot list: [%w]", err)
+	}
+
+	var matched []uint
+	for _, s := range slots {
+		info, err := ctx.GetTokenInfo(s)
+		if err != nil || !opts.matches(s, info) {
+			continue
+		}
+		matched = append(matched, s)
+	}
+
+	switch len(matched) {
+	case 0:
+		return 0, fmt.Errorf("pkcs11: could not find token with %s", opts)
+	case 1:
+		return matched[0], nil
+	default:
+		return 0, fmt.Errorf("pkcs11: found %d tokens with %s, the token selection is ambiguous", len(matched), opts)
+	}
+}
+
+// tokenName 返回统计指标中用于区分插槽slot上的令牌的名称，优先使用令牌的序列号，其次是标签。
+func tokenName(ctx *pkcs11.Ctx, slot uint, opts TokenOpts) string {
+	if info, err := ctx.GetTokenInfo(slot); err == nil {
+		if serial := strings.TrimSpace(info.SerialNumber); serial != "" {
+			return serial
+		}
+		if label := strings.TrimSpace(info.Label); label != "" {
+			return label
+		}
+	}
+	if opts.SerialNumber != "" {
+		return opts.SerialNumber
+	}
+	if opts.Label != "" {
+		return opts.Label
+	}
+	return fmt.Sprintf("slot-%d", slot)
+}
+
+// withSession 在一个会话上执行幂等的操作f。如果f因为会话失效而失败，则丢弃所有的会话，重新打开一个会话
+// 并登录，然后再重试一次f。
+func (t *token) withSession(f func(session pkcs11.SessionHandle) error) error {
+	session, gen, err := t.getSession()
+	if err != nil {
+		return err
+	}
+
+	err = f(session)
+	if !isSessionError(err) {
+		t.returnSession(session, gen)
+		return err
+	}
+
+	t.discardSessions(gen)
+	t.metrics.SessionRetries.Add(1)
+
+	session, gen, err = t.getSession()
+	if err != nil {
+		return err
+	}
+	err = f(session)
+	t.releaseSession(session, gen, err)
+
+	return err
+}
+
+// getSession 从会话缓存中取出一个会话，如果缓存为空，则创建一个新的会话。返回的gen是取出会话时的generation，
+// 归还会话时需要一并传入。
+func (t *token) getSession() (session pkcs11.SessionHandle, gen uint64, err error) {
+	t.sessLock.Lock()
+	select {
+	case session = <-t.sessPool:
+		gen = t.generation
+		t.metrics.SessionPoolSize.Set(float64(len(t.sessPool)))
+		t.sessLock.Unlock()
+		return session, gen, nil
+	default:
+		// 缓存为空，或者缓存被禁用。
+		t.sessLock.Unlock()
+		return t.createSession()
+	}
+}
+
+// createSession 打开一个新的读写会话并登录。失败时最多尝试createSessionRetries次，每次失败后等待的时间从
+// createSessionRetryDelay开始翻倍，但不超过maxCreateSessionRetryDelay。如果令牌所在的插槽发生了变化（例如
+// 令牌被拔出后重新插入），则根据选项重新查找令牌。
+func (t *token) createSession() (pkcs11.SessionHandle, uint64, error) {
+	var session pkcs11.SessionHandle
+	var err error
+	delay := t.createSessionRetryDelay
+	for i := 0; i < t.createSessionRetries; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay = nextRetryDelay(delay)
+		}
+
+		session, err = t.openSession()
+		if err == nil {
+			break
+		}
+
+		t.metrics.SessionOpenFailures.Add(1)
+		if isSlotError(err) {
+			if slot, ferr := findSlot(t.ctx, t.opts); ferr == nil {
+				t.sessLock.Lock()
+				t.slot = slot
+				t.sessLock.Unlock()
+			}
+		}
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("pkcs11: failed to open session after %d attempts: [%w]", t.createSessionRetries, err)
+	}
+
+	t.sessLock.Lock()
+	defer t.sessLock.Unlock()
+	t.sessions[session] = struct{}{}
+
+	return session, t.generation, nil
+}
+
+func (t *token) openSession() (pkcs11.SessionHandle, error) {
+	t.sessLock.Lock()
+	slot := t.slot
+	t.sessLock.Unlock()
+
+	session, err := t.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = t.ctx.Login(session, pkcs11.CKU_USER, t.opts.Pin); err != nil && !isCKR(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		t.ctx.CloseSession(session)
+		return 0, fmt.Errorf("pkcs11: login failed: [%w]", err)
+	}
+
+	return session, nil
+}
+
+// nextRetryDelay 返回下一次重试前等待的时间。
+func nextRetryDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > maxCreateSessionRetryDelay {
+		delay = maxCreateSessionRetryDelay
+	}
+	return delay
+}
+
+// closeSession 关闭会话，并清空对象句柄的缓存，因为句柄可能与会话相关。调用者必须持有sessLock。
+func (t *token) closeSession(session pkcs11.SessionHandle) {
+	t.ctx.CloseSession(session)
+	delete(t.sessions, session)
+
+	// 会话全部关闭以后，令牌会登出，之前缓存的句柄都可能失效。
+	if len(t.sessions) == 0 {
+		t.clearHandles()
+	}
+}
+
+// returnSession 将取出时generation为gen的会话放回缓存中，如果缓存已满，则关闭该会话。如果会话取出以后所有的
+// 会话被丢弃过，该会话已经被关闭，它的句柄可能属于其他协程新打开的会话，所以直接丢弃，既不放回缓存也不关闭。
+func (t *token) returnSession(session pkcs11.SessionHandle, gen uint64) {
+	t.sessLock.Lock()
+	defer t.sessLock.Unlock()
+
+	if gen != t.generation {
+		return
+	}
+
+	select {
+	case t.sessPool <- session:
+		t.metrics.SessionPoolSize.Set(float64(len(t.sessPool)))
+	default:
+		t.closeSession(session)
+	}
+}
+
+// releaseSession 在操作结束后释放会话：如果err表示会话已经失效，则丢弃所有的会话，否则将会话放回缓存中。
+func (t *token) releaseSession(session pkcs11.SessionHandle, gen uint64, err error) {
+	if isSessionError(err) {
+		t.discardSessions(gen)
+		return
+	}
+	t.returnSession(session, gen)
+}
+
+// discardSessions 在取出时generation为gen的会话失效后丢弃所有的会话。如果所有的会话在此之后已经被丢弃过，
+// 说明其他协程已经处理了这次失效，新打开的会话不受影响，所以不再重复丢弃。
+func (t *token) discardSessions(gen uint64) {
+	t.sessLock.Lock()
+	defer t.sessLock.Unlock()
+
+	if gen != t.generation {
+		return
+	}
+	t.resetSessionsLocked()
+}
+
+// resetSessions 关闭所有打开的会话并清空缓存。令牌被重置以后，所有的会话和对象句柄都会失效，其他正在使用的
+// 会话也会在下一次操作时失败，所以这里一并关闭，它们归还时会因为generation变化而被丢弃。
+func (t *token) resetSessions() {
+	t.sessLock.Lock()
+	defer t.sessLock.Unlock()
+
+	t.resetSessionsLocked()
+}
+
+func (t *token) resetSessionsLocked() {
+drain:
+	for {
+		select {
+		case <-t.sessPool:
+		default:
+			break drain
+		}
+	}
+	t.metrics.SessionPoolSize.Set(0)
+
+	for session := range t.sessions {
+		t.ctx.CloseSession(session)
+	}
+	t.sessions = map[pkcs11.SessionHandle]struct{}{}
+	t.generation++
+	t.clearHandles()
+}
+
+func (t *token) clearHandles() {
+	t.handleLock.Lock()
+	defer t.handleLock.Unlock()
+
+	t.handleCache = map[string]pkcs11.ObjectHandle{}
+}
+
+func (t *token) cachedHandle(keyType keyType, ski []byte) (pkcs11.ObjectHandle, bool) {
+	cacheKey := hex.EncodeToString(append([]byte{byte(keyType)}, ski...))
+	t.handleLock.RLock()
+	defer t.handleLock.RUnlock()
+
+	handle, ok := t.handleCache[cacheKey]
+	return handle, ok
+}
+
+func (t *token) cacheHandle(ski []byte, keyType keyType, handle pkcs11.ObjectHandle) {
+	cacheKey := hex.EncodeToString(append([]byte{byte(keyType)}, ski...))
+	t.handleLock.Lock()
+	defer t.handleLock.Unlock()
+
+	t.handleCache[cacheKey] = handle
+}
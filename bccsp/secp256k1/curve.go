@@ -0,0 +1,216 @@
+package secp256k1
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+// curve 是SEC 2定义的secp256k1曲线y² = x³ + 7。elliptic.CurveParams提供的通用实现假定曲线的参数a等于-3，
+// 不适用于a等于0的secp256k1，所以这里基于雅可比坐标实现了点的加法和倍点运算。Add和Double不是常数时间的，
+// 只能用于公开的点；标量可能是私钥或随机数，所以ScalarMult和ScalarBaseMult是常数时间的，参见scalarmult.go。
+type curve struct {
+	params *elliptic.CurveParams
+}
+
+var (
+	s256     *curve
+	s256Once sync.Once
+)
+
+func initS256() {
+	params := &elliptic.CurveParams{Name: "secp256k1", BitSize: 256}
+	params.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+	params.N, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+	params.B = big.NewInt(7)
+	params.Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+	params.Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+	s256 = &curve{params: params}
+}
+
+// S256 返回secp256k1曲线。
+func S256() elliptic.Curve {
+	s256Once.Do(initS256)
+	return s256
+}
+
+// IsS256 判断c是否是secp256k1曲线。
+func IsS256(c elliptic.Curve) bool {
+	return c != nil && c.Params() == S256().Params()
+}
+
+func (c *curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *curve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	// y² = x³ + 7
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+
+	return y2.Cmp(c.polynomial(x)) == 0
+}
+
+// polynomial 返回x³ + 7 mod p。
+func (c *curve) polynomial(x *big.Int) *big.Int {
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	x3.Add(x3, c.params.B)
+	return x3.Mod(x3, c.params.P)
+}
+
+func (c *curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	z1 := zForAffine(x1, y1)
+	z2 := zForAffine(x2, y2)
+	return c.affineFromJacobian(c.addJacobian(x1, y1, z1, x2, y2, z2))
+}
+
+func (c *curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	z1 := zForAffine(x1, y1)
+	return c.affineFromJacobian(c.doubleJacobian(x1, y1, z1))
+}
+
+// zForAffine 返回仿射坐标(x, y)对应的雅可比坐标的z值，无穷远点(0, 0)的z值为0。
+func zForAffine(x, y *big.Int) *big.Int {
+	z := new(big.Int)
+	if x.Sign() != 0 || y.Sign() != 0 {
+		z.SetInt64(1)
+	}
+	return z
+}
+
+// affineFromJacobian 将雅可比坐标(x, y, z)转换为仿射坐标(x/z², y/z³)。
+func (c *curve) affineFromJacobian(x, y, z *big.Int) (xOut, yOut *big.Int) {
+	if z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+
+	p := c.params.P
+	zinv := new(big.Int).ModInverse(z, p)
+	zinvsq := new(big.Int).Mul(zinv, zinv)
+
+	xOut = new(big.Int).Mul(x, zinvsq)
+	xOut.Mod(xOut, p)
+	zinvsq.Mul(zinvsq, zinv)
+	yOut = new(big.Int).Mul(y, zinvsq)
+	yOut.Mod(yOut, p)
+	return
+}
+
+// addJacobian 计算两个雅可比坐标表示的点的和，参见
+// http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-add-2007-bl
+func (c *curve) addJacobian(x1, y1, z1, x2, y2, z2 *big.Int) (*big.Int, *big.Int, *big.Int) {
+	if z1.Sign() == 0 {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2), new(big.Int).Set(z2)
+	}
+	if z2.Sign() == 0 {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1), new(big.Int).Set(z1)
+	}
+
+	p := c.params.P
+	z1z1 := new(big.Int).Mul(z1, z1)
+	z1z1.Mod(z1z1, p)
+	z2z2 := new(big.Int).Mul(z2, z2)
+	z2z2.Mod(z2z2, p)
+
+	u1 := new(big.Int).Mul(x1, z2z2)
+	u1.Mod(u1, p)
+	u2 := new(big.Int).Mul(x2, z1z1)
+	u2.Mod(u2, p)
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, p)
+
+	s1 := new(big.Int).Mul(y1, z2)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, p)
+	s2 := new(big.Int).Mul(y2, z1)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, p)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, p)
+
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return c.doubleJacobian(x1, y1, z1)
+		}
+		// 两个点互为相反数，和为无穷远点。
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+	r.Lsh(r, 1)
+
+	i := new(big.Int).Lsh(h, 1)
+	i.Mul(i, i)
+	j := new(big.Int).Mul(h, i)
+
+	v := new(big.Int).Mul(u1, i)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3.Sub(x3, v)
+	x3.Sub(x3, v)
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	s1.Mul(s1, j)
+	s1.Lsh(s1, 1)
+	y3.Sub(y3, s1)
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Add(z1, z2)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3.Mul(z3, h)
+	z3.Mod(z3, p)
+
+	return x3, y3, z3
+}
+
+// doubleJacobian 计算雅可比坐标表示的点的二倍，参见
+// http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#doubling-dbl-2009-l
+func (c *curve) doubleJacobian(x, y, z *big.Int) (*big.Int, *big.Int, *big.Int) {
+	if z.Sign() == 0 || y.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+
+	p := c.params.P
+	a := new(big.Int).Mul(x, x)
+	a.Mod(a, p)
+	b := new(big.Int).Mul(y, y)
+	b.Mod(b, p)
+	cc := new(big.Int).Mul(b, b)
+	cc.Mod(cc, p)
+
+	d := new(big.Int).Add(x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, cc)
+	d.Lsh(d, 1)
+	d.Mod(d, p)
+
+	e := new(big.Int).Lsh(a, 1)
+	e.Add(e, a)
+	f := new(big.Int).Mul(e, e)
+
+	x3 := new(big.Int).Lsh(d, 1)
+	x3.Sub(f, x3)
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	cc.Lsh(cc, 3)
+	y3.Sub(y3, cc)
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Mul(y, z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, p)
+
+	return x3, y3, z3
+}
@@ -0,0 +1,160 @@
+package secp256k1
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+
+	secp "github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurve(t *testing.T) {
+	c := S256()
+	params := c.Params()
+	require.True(t, c.IsOnCurve(params.Gx, params.Gy))
+	require.False(t, c.IsOnCurve(params.Gx, new(big.Int).Add(params.Gy, big.NewInt(1))))
+
+	// n * G 是无穷远点。
+	x, y := c.ScalarBaseMult(params.N.Bytes())
+	require.Zero(t, x.Sign())
+	require.Zero(t, y.Sign())
+
+	x1, y1 := c.Double(params.Gx, params.Gy)
+	x2, y2 := c.Add(params.Gx, params.Gy, params.Gx, params.Gy)
+	x3, y3 := c.ScalarBaseMult([]byte{2})
+	require.Equal(t, "C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5", fmt.Sprintf("%X", x1))
+	require.Equal(t, x1, x2)
+	require.Equal(t, y1, y2)
+	require.Equal(t, x1, x3)
+	require.Equal(t, y1, y3)
+
+	// 已知的私钥与公钥。
+	k, _ := hex.DecodeString("AA5E28D6A97A2479A65527F7290311A3624D4CC0FA1578598EE3C2613BF99522")
+	x, y = c.ScalarBaseMult(k)
+	require.Equal(t, "34F9460F0E4F08393D192B3C5133A6BA099AA0AD9FD54EBCCFACDFA239FF49C6", fmt.Sprintf("%X", x))
+	require.Equal(t, "B71EA9BD730FD8923F6D25A7A91E7DD7728A960686CB5A901BB419E0F2CA232", fmt.Sprintf("%X", y))
+
+	// P + (-P) 是无穷远点。
+	x, y = c.Add(params.Gx, params.Gy, params.Gx, new(big.Int).Sub(params.P, params.Gy))
+	require.Zero(t, x.Sign())
+	require.Zero(t, y.Sign())
+}
+
+func TestScalarMult(t *testing.T) {
+	c := S256()
+	n := c.Params().N
+
+	// 与dcrd的实现对比，包括边界上的标量和长于32字节的标量。
+	scalars := [][]byte{
+		nil,
+		{0},
+		{1},
+		{0x0f},
+		{0x10},
+		new(big.Int).Sub(n, big.NewInt(1)).Bytes(),
+		n.Bytes(),
+		new(big.Int).Add(n, big.NewInt(1)).Bytes(),
+		bytes.Repeat([]byte{0xff}, 32),
+		bytes.Repeat([]byte{0xff}, 40),
+	}
+	for i := 0; i < 16; i++ {
+		k := make([]byte, 32)
+		_, err := rand.Read(k)
+		require.NoError(t, err)
+		scalars = append(scalars, k)
+	}
+
+	px, py := c.ScalarBaseMult([]byte{7})
+	for _, k := range scalars {
+		var s secp.ModNScalar
+		s.SetByteSlice(new(big.Int).Mod(new(big.Int).SetBytes(k), n).Bytes())
+
+		var expected secp.JacobianPoint
+		secp.ScalarBaseMultNonConst(&s, &expected)
+		x, y := c.ScalarBaseMult(k)
+		requirePoint(t, &expected, x, y)
+
+		var p secp.JacobianPoint
+		p.X.SetByteSlice(px.Bytes())
+		p.Y.SetByteSlice(py.Bytes())
+		p.Z.SetInt(1)
+		secp.ScalarMultNonConst(&s, &p, &expected)
+		x, y = c.ScalarMult(px, py, k)
+		requirePoint(t, &expected, x, y)
+	}
+
+	// 无穷远点的任意倍都是无穷远点。
+	x, y := c.ScalarMult(new(big.Int), new(big.Int), []byte{3})
+	require.Zero(t, x.Sign())
+	require.Zero(t, y.Sign())
+}
+
+func requirePoint(t *testing.T, expected *secp.JacobianPoint, x, y *big.Int) {
+	if (expected.X.IsZero() && expected.Y.IsZero()) || expected.Z.IsZero() {
+		require.Zero(t, x.Sign())
+		require.Zero(t, y.Sign())
+		return
+	}
+	expected.ToAffine()
+	require.Equal(t, new(big.Int).SetBytes(expected.X.Bytes()[:]), x)
+	require.Equal(t, new(big.Int).SetBytes(expected.Y.Bytes()[:]), y)
+}
+
+func TestECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(S256(), rand.Reader)
+	require.NoError(t, err)
+	require.True(t, IsS256(priv.Curve))
+	require.False(t, IsS256(elliptic.P256()))
+
+	digest := sha256.Sum256([]byte("hello world"))
+	r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+	require.NoError(t, err)
+	require.True(t, ecdsa.Verify(&priv.PublicKey, digest[:], r, s))
+	require.False(t, ecdsa.Verify(&priv.PublicKey, digest[1:], r, s))
+}
+
+func TestEncoding(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(S256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+	pub, err := ParsePKIXPublicKey(der)
+	require.NoError(t, err)
+	require.Equal(t, priv.X, pub.X)
+	require.Equal(t, priv.Y, pub.Y)
+
+	der, err = MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	parsed, err := ParsePKCS8PrivateKey(der)
+	require.NoError(t, err)
+	require.Equal(t, priv.D, parsed.D)
+	require.Equal(t, priv.Y, parsed.Y)
+
+	// 压缩格式的公钥。
+	compressed := make([]byte, 33)
+	compressed[0] = byte(2 + priv.Y.Bit(0))
+	priv.X.FillBytes(compressed[1:])
+	pub, err = ParsePublicKey(compressed)
+	require.NoError(t, err)
+	require.Equal(t, priv.Y, pub.Y)
+
+	pub, err = ParsePublicKey(elliptic.Marshal(S256(), priv.X, priv.Y))
+	require.NoError(t, err)
+	require.Equal(t, priv.X, pub.X)
+
+	_, err = ParsePublicKey(compressed[:32])
+	require.Error(t, err)
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = MarshalPKIXPublicKey(&p256.PublicKey)
+	require.Error(t, err)
+}
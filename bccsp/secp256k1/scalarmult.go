@@ -0,0 +1,192 @@
+package secp256k1
+
+import (
+	"crypto/subtle"
+	"math/big"
+	"sync"
+
+	secp "github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// 标量乘法的标量可能是私钥或签名用的随机数，所以这里的实现是常数时间的：域运算使用dcrd的常数时间实现，点的
+// 加法使用Renes、Costello和Batina给出的a = 0时的完备公式（https://eprint.iacr.org/2015/1060 算法7），
+// 无需区分无穷远点、相同的点和互为相反数的点；标量按4比特的固定窗口处理，每个窗口都做4次倍点和1次加法，
+// 预计算表中的点以常数时间的方式选出。
+
+// b3 是曲线参数b的3倍。
+const b3 = 21
+
+// windowSize 是固定窗口的比特数，预计算表中有1 << windowSize个点。
+const windowSize = 4
+
+// projectivePoint 是齐次射影坐标(X : Y : Z)表示的点，对应的仿射坐标为(X/Z, Y/Z)，无穷远点为(0 : 1 : 0)。
+type projectivePoint struct {
+	x, y, z secp.FieldVal
+}
+
+// encodedPoint 是规约以后的X、Y、Z依次拼接成的字节串，用于以常数时间的方式从预计算表中选出点。
+type encodedPoint [96]byte
+
+type precomputedTable [1 << windowSize]encodedPoint
+
+var (
+	baseTable     *precomputedTable
+	baseTableOnce sync.Once
+)
+
+func (c *curve) ScalarMult(bx, by *big.Int, k []byte) (*big.Int, *big.Int) {
+	var p projectivePoint
+	c.setAffine(&p, bx, by)
+	return c.scalarMult(precompute(&p), k)
+}
+
+func (c *curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	baseTableOnce.Do(func() {
+		var g projectivePoint
+		c.setAffine(&g, c.params.Gx, c.params.Gy)
+		baseTable = precompute(&g)
+	})
+	return c.scalarMult(baseTable, k)
+}
+
+// scalarMult 计算k * P，table是P的预计算表。
+func (c *curve) scalarMult(table *precomputedTable, k []byte) (*big.Int, *big.Int) {
+	// 曲线的余因子为1，曲线上的点的阶都是n，所以k可以先模n。长于32字节的标量极少出现，这里直接用big.Int规约。
+	if len(k) > 32 {
+		k = new(big.Int).Mod(new(big.Int).SetBytes(k), c.params.N).Bytes()
+	}
+	var s secp.ModNScalar
+	s.SetByteSlice(k)
+	scalar := s.Bytes()
+	s.Zero()
+	defer func() {
+		for i := range scalar {
+			scalar[i] = 0
+		}
+	}()
+
+	q := projectivePoint{}
+	q.y.SetInt(1)
+	var entry encodedPoint
+	var selected projectivePoint
+	for _, b := range scalar {
+		for _, w := range [2]byte{b >> 4, b & 0x0f} {
+			for i := 0; i < windowSize; i++ {
+				q.add(&q, &q)
+			}
+			table.lookup(w, &entry)
+			selected.decode(&entry)
+			q.add(&q, &selected)
+		}
+	}
+
+	return q.affine()
+}
+
+// setAffine 将仿射坐标(x, y)转换为射影坐标，(0, 0)表示无穷远点。
+func (c *curve) setAffine(p *projectivePoint, x, y *big.Int) {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		p.x.Zero()
+		p.y.SetInt(1)
+		p.z.Zero()
+		return
+	}
+	p.x.SetByteSlice(new(big.Int).Mod(x, c.params.P).Bytes())
+	p.y.SetByteSlice(new(big.Int).Mod(y, c.params.P).Bytes())
+	p.z.SetInt(1)
+}
+
+// affine 将射影坐标转换为仿射坐标，无穷远点转换为(0, 0)。
+func (p *projectivePoint) affine() (*big.Int, *big.Int) {
+	if p.z.Normalize().IsZero() {
+		return new(big.Int), new(big.Int)
+	}
+
+	var zinv, x, y secp.FieldVal
+	zinv.Set(&p.z).Inverse()
+	x.Mul2(&p.x, &zinv).Normalize()
+	y.Mul2(&p.y, &zinv).Normalize()
+	return new(big.Int).SetBytes(x.Bytes()[:]), new(big.Int).SetBytes(y.Bytes()[:])
+}
+
+// add 计算p1 + p2，结果写入p。p可以与p1或p2相同。
+func (p *projectivePoint) add(p1, p2 *projectivePoint) {
+	var t0, t1, t2, t3, t4, x3, y3, z3 secp.FieldVal
+
+	t0.Mul2(&p1.x, &p2.x)
+	t1.Mul2(&p1.y, &p2.y)
+	t2.Mul2(&p1.z, &p2.z)
+	t3.Add2(&p1.x, &p1.y).Normalize()
+	t4.Add2(&p2.x, &p2.y).Normalize()
+	t3.Mul(&t4)
+	t4.Add2(&t0, &t1).Normalize()
+	fieldSub(&t3, &t3, &t4)
+	t4.Add2(&p1.y, &p1.z).Normalize()
+	x3.Add2(&p2.y, &p2.z).Normalize()
+	t4.Mul(&x3)
+	x3.Add2(&t1, &t2).Normalize()
+	fieldSub(&t4, &t4, &x3)
+	x3.Add2(&p1.x, &p1.z).Normalize()
+	y3.Add2(&p2.x, &p2.z).Normalize()
+	x3.Mul(&y3)
+	y3.Add2(&t0, &t2).Normalize()
+	fieldSub(&y3, &x3, &y3)
+	x3.Add2(&t0, &t0).Normalize()
+	t0.Add(&x3).Normalize()
+	t2.MulInt(b3).Normalize()
+	z3.Add2(&t1, &t2).Normalize()
+	fieldSub(&t1, &t1, &t2)
+	y3.MulInt(b3).Normalize()
+	x3.Mul2(&t4, &y3)
+	t2.Mul2(&t3, &t1)
+	fieldSub(&x3, &t2, &x3)
+	y3.Mul(&t0)
+	t1.Mul(&z3)
+	y3.Add(&t1).Normalize()
+	t0.Mul(&t3)
+	z3.Mul(&t4)
+	z3.Add(&t0).Normalize()
+
+	p.x.Set(&x3)
+	p.y.Set(&y3)
+	p.z.Set(&z3)
+}
+
+// fieldSub 计算a - b，结果写入r，b的量级不能超过1。
+func fieldSub(r, a, b *secp.FieldVal) {
+	var nb secp.FieldVal
+	nb.NegateVal(b, 1)
+	r.Add2(a, &nb).Normalize()
+}
+
+func (p *projectivePoint) encode(out *encodedPoint) {
+	var v secp.FieldVal
+	v.Set(&p.x).Normalize().PutBytesUnchecked(out[0:32])
+	v.Set(&p.y).Normalize().PutBytesUnchecked(out[32:64])
+	v.Set(&p.z).Normalize().PutBytesUnchecked(out[64:96])
+}
+
+func (p *projectivePoint) decode(in *encodedPoint) {
+	p.x.SetByteSlice(in[0:32])
+	p.y.SetByteSlice(in[32:64])
+	p.z.SetByteSlice(in[64:96])
+}
+
+// precompute 返回P的预计算表，第i项为i * P。
+func precompute(p *projectivePoint) *precomputedTable {
+	table := &precomputedTable{}
+	q := projectivePoint{}
+	q.y.SetInt(1)
+	for i := range table {
+		q.encode(&table[i])
+		q.add(&q, p)
+	}
+	return table
+}
+
+// lookup 以常数时间的方式将表中的第w项复制到out中，每一项都会被访问。
+func (t *precomputedTable) lookup(w byte, out *encodedPoint) {
+	for i := range t {
+		subtle.ConstantTimeCopy(subtle.ConstantTimeByteEq(uint8(i), w), out[:], t[i][:])
+	}
+}
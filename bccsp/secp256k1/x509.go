@@ -0,0 +1,188 @@
+package secp256k1
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// OIDNamedCurveSecp256k1 是secp256k1曲线的对象标识符。
+	OIDNamedCurveSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+// Go标准库的crypto/x509不认识secp256k1曲线，所以secp256k1密钥的PKIX和PKCS#8编码需要在这里实现。
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+func algorithmIdentifier() (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(OIDNamedCurveSecp256k1)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+func checkAlgorithmIdentifier(algo pkix.AlgorithmIdentifier) error {
+	if !algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		return fmt.Errorf("unexpected public key algorithm [%s]", algo.Algorithm)
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &curve); err != nil {
+		return fmt.Errorf("failed parsing named curve [%w]", err)
+	}
+	if !curve.Equal(OIDNamedCurveSecp256k1) {
+		return fmt.Errorf("unexpected named curve [%s], expected secp256k1", curve)
+	}
+	return nil
+}
+
+// MarshalPKIXPublicKey 将secp256k1公钥编码成PKIX格式。
+func MarshalPKIXPublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	if pub == nil || !IsS256(pub.Curve) {
+		return nil, errors.New("invalid public key, it must be on the secp256k1 curve")
+	}
+
+	algo, err := algorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	raw := elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: algo,
+		PublicKey: asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)},
+	})
+}
+
+// ParsePKIXPublicKey 解析PKIX格式的secp256k1公钥。
+func ParsePKIXPublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, fmt.Errorf("failed parsing PKIX public key [%w]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after PKIX public key")
+	}
+	if err := checkAlgorithmIdentifier(spki.Algorithm); err != nil {
+		return nil, err
+	}
+
+	return ParsePublicKey(spki.PublicKey.RightAlign())
+}
+
+// ParsePublicKey 解析SEC 1格式编码的secp256k1公钥，支持65字节的非压缩格式和33字节的压缩格式，后者常见
+// 于比特币和以太坊的钱包。
+func ParsePublicKey(raw []byte) (*ecdsa.PublicKey, error) {
+	c := S256()
+	switch {
+	case len(raw) == 65 && raw[0] == 4:
+		x, y := elliptic.Unmarshal(c, raw)
+		if x == nil {
+			return nil, errors.New("invalid secp256k1 public key, the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+	case len(raw) == 33 && (raw[0] == 2 || raw[0] == 3):
+		x, y := decompress(raw)
+		if x == nil {
+			return nil, errors.New("invalid secp256k1 public key, the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("invalid secp256k1 public key encoding of length [%d]", len(raw))
+	}
+}
+
+// decompress 根据压缩格式的公钥恢复出y坐标。secp256k1的p满足p ≡ 3 (mod 4)，所以y = (x³ + 7)^((p+1)/4)。
+func decompress(raw []byte) (x, y *big.Int) {
+	c := S256().(*curve)
+	p := c.params.P
+
+	x = new(big.Int).SetBytes(raw[1:])
+	if x.Cmp(p) >= 0 {
+		return nil, nil
+	}
+
+	exp := new(big.Int).Add(p, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y = new(big.Int).Exp(c.polynomial(x), exp, p)
+	if y.Bit(0) != uint(raw[0]&1) {
+		y.Sub(p, y)
+	}
+	if !c.IsOnCurve(x, y) {
+		return nil, nil
+	}
+	return x, y
+}
+
+// MarshalPKCS8PrivateKey 将secp256k1私钥编码成PKCS#8格式。
+func MarshalPKCS8PrivateKey(priv *ecdsa.PrivateKey) ([]byte, error) {
+	if priv == nil || !IsS256(priv.Curve) {
+		return nil, errors.New("invalid private key, it must be on the secp256k1 curve")
+	}
+
+	algo, err := algorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	pub := elliptic.Marshal(priv.Curve, priv.X, priv.Y)
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: priv.D.FillBytes(make([]byte, 32)),
+		PublicKey:  asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8{Algo: algo, PrivateKey: inner})
+}
+
+// ParsePKCS8PrivateKey 解析PKCS#8格式的secp256k1私钥。
+func ParsePKCS8PrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	var p pkcs8
+	if rest, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("failed parsing PKCS#8 private key [%w]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after PKCS#8 private key")
+	}
+	if err := checkAlgorithmIdentifier(p.Algo); err != nil {
+		return nil, err
+	}
+
+	var key ecPrivateKey
+	if _, err := asn1.Unmarshal(p.PrivateKey, &key); err != nil {
+		return nil, fmt.Errorf("failed parsing EC private key [%w]", err)
+	}
+
+	c := S256()
+	d := new(big.Int).SetBytes(key.PrivateKey)
+	if d.Sign() <= 0 || d.Cmp(c.Params().N) >= 0 {
+		return nil, errors.New("invalid secp256k1 private key value")
+	}
+
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.PublicKey.Curve = c
+	priv.PublicKey.X, priv.PublicKey.Y = c.ScalarBaseMult(key.PrivateKey)
+	return priv, nil
+}
@@ -0,0 +1,56 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// SplitKey 导出私钥或对称密钥k，并将其拆分成n份，任意t份可以通过RecoverKey恢复出密钥。csp必须实现
+// bccsp.KeyExporter接口，保存在硬件中的密钥和不可导出的对称密钥无法拆分。
+func SplitKey(csp bccsp.BCCSP, k bccsp.Key, n, t int) ([]*Share, error) {
+	if k == nil {
+		return nil, errors.New("invalid key, it must not be nil")
+	}
+	exporter, ok := csp.(bccsp.KeyExporter)
+	if !ok {
+		return nil, fmt.Errorf("BCCSP [%T] does not support exporting keys", csp)
+	}
+
+	raw, err := exporter.KeyExport(k)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+
+	shares, err := Split(raw, n, t)
+	if err != nil {
+		return nil, err
+	}
+	ski := k.SKI()
+	for _, share := range shares {
+		share.SKI = ski
+	}
+	return shares, nil
+}
+
+// RecoverKey 利用SplitKey得到的份额恢复出密钥，并通过bccsp.PEMKeyImportOpts将其导入到csp中，如果temporary
+// 为false，密钥会被保存到csp的KeyStore中。恢复出的密钥的SKI必须与份额中记录的SKI相同。
+func RecoverKey(csp bccsp.BCCSP, shares []*Share, temporary bool) (bccsp.Key, error) {
+	raw, err := Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+
+	k, err := csp.KeyImport(raw, &bccsp.PEMKeyImportOpts{Temporary: temporary})
+	if err != nil {
+		return nil, fmt.Errorf("failed importing recovered key [%w]", err)
+	}
+	if ski := shares[0].SKI; len(ski) != 0 && !bytes.Equal(ski, k.SKI()) {
+		return nil, fmt.Errorf("recovered key SKI [%x] does not match [%x]", k.SKI(), ski)
+	}
+	return k, nil
+}
@@ -0,0 +1,136 @@
+package shamir
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SharePEMType 是份额的PEM块类型。
+const SharePEMType = "BCCSP KEY SHARE"
+
+// shareVersion 是份额编码格式的版本号。
+const shareVersion = 1
+
+// shareInfo 是份额的ASN.1编码。
+type shareInfo struct {
+	Version   int
+	ID        []byte
+	Threshold int
+	Total     int
+	Index     int
+	SKI       []byte
+	Digest    []byte
+	Value     []byte
+}
+
+// shareContainer 在份额的ASN.1编码后面附上校验和SHA256(info)，用于发现损坏的份额。
+type shareContainer struct {
+	Info     asn1.RawValue
+	Checksum []byte
+}
+
+// ShareToPEM 将份额编码成PEM块。PEM头部记录了份额的标识符、序号和门限值，方便保管人辨认，解码时只以PEM块的
+// 内容为准。
+func ShareToPEM(share *Share) ([]byte, error) {
+	if err := share.validate(); err != nil {
+		return nil, fmt.Errorf("invalid share: [%w]", err)
+	}
+
+	info, err := asn1.Marshal(shareInfo{
+		Version:   shareVersion,
+		ID:        share.ID,
+		Threshold: share.Threshold,
+		Total:     share.Total,
+		Index:     share.Index,
+		SKI:       share.SKI,
+		Digest:    share.Digest,
+		Value:     share.Value,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling share [%w]", err)
+	}
+	checksum := sha256.Sum256(info)
+	der, err := asn1.Marshal(shareContainer{Info: asn1.RawValue{FullBytes: info}, Checksum: checksum[:]})
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling share [%w]", err)
+	}
+
+	headers := map[string]string{
+		"Split-ID":  hex.EncodeToString(share.ID),
+		"Share":     strconv.Itoa(share.Index) + "/" + strconv.Itoa(share.Total),
+		"Threshold": strconv.Itoa(share.Threshold),
+	}
+	if len(share.SKI) != 0 {
+		headers["SKI"] = hex.EncodeToString(share.SKI)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: SharePEMType, Headers: headers, Bytes: der}), nil
+}
+
+// PEMToShares 解码raw中所有"BCCSP KEY SHARE"类型的PEM块，其他类型的PEM块会被忽略。校验和不正确的份额会
+// 导致解码失败。
+func PEMToShares(raw []byte) ([]*Share, error) {
+	var shares []*Share
+	for {
+		var block *pem.Block
+		block, raw = pem.Decode(raw)
+		if block == nil {
+			break
+		}
+		if block.Type != SharePEMType {
+			continue
+		}
+		share, err := parseShare(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding share [%d]: [%w]", len(shares), err)
+		}
+		shares = append(shares, share)
+	}
+
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no PEM block of type [%s] found", SharePEMType)
+	}
+	return shares, nil
+}
+
+func parseShare(der []byte) (*Share, error) {
+	var container shareContainer
+	if rest, err := asn1.Unmarshal(der, &container); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling share [%s]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after share")
+	}
+	checksum := sha256.Sum256(container.Info.FullBytes)
+	if subtle.ConstantTimeCompare(checksum[:], container.Checksum) != 1 {
+		return nil, errors.New("checksum mismatch, the share is corrupted")
+	}
+
+	var info shareInfo
+	if rest, err := asn1.Unmarshal(container.Info.FullBytes, &info); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling share [%s]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after share")
+	}
+	if info.Version != shareVersion {
+		return nil, fmt.Errorf("unsupported share version [%d]", info.Version)
+	}
+
+	share := &Share{
+		ID:        info.ID,
+		Threshold: info.Threshold,
+		Total:     info.Total,
+		Index:     info.Index,
+		SKI:       info.SKI,
+		Digest:    info.Digest,
+		Value:     info.Value,
+	}
+	if err := share.validate(); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
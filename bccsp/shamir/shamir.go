@@ -0,0 +1,223 @@
+/*
+Package shamir 实现了基于GF(2^8)的Shamir秘密共享，用于将私钥或对称密钥拆分成n份，其中任意t份可以恢复出
+原来的密钥。这样CA和排序节点的签名密钥的灾难恢复就不必依赖单个保管人。
+
+每一份都记录了所属拆分的标识符、门限值、秘密的摘要和自身的校验和，序列化成PEM块以后损坏的份额在解码时即可
+被发现，恢复出的秘密也会与摘要进行比对。份额的值本身与秘密无关，但每一份都带有摘要SHA256(ID || secret)，
+所以少于t份的份额除了这个摘要以外得不到关于秘密的任何信息；摘要只有在秘密的熵足够大时才能隐藏秘密，熵很小的
+秘密可以通过穷举从摘要中恢复出来，因此Split拒绝拆分短于16字节的秘密，调用者也不应该拆分口令等低熵的秘密。
+*/
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxShares 是一个秘密最多可以拆分成的份数，份额的横坐标是GF(2^8)中的非零元素。
+	MaxShares = 255
+	// MinSecretSize 是可以拆分的秘密的最小字节长度，更短的秘密可以通过穷举从份额的摘要中恢复出来。
+	MinSecretSize = 16
+	// idSize 是拆分标识符的字节长度。
+	idSize = 16
+)
+
+// Share 是秘密的一份，Value中的第i个字节是第i个秘密字节的多项式在Index处的取值。
+type Share struct {
+	// ID 是拆分的随机标识符，同一次拆分得到的所有份额的ID相同。
+	ID []byte
+	// Threshold 是恢复秘密至少需要的份数。
+	Threshold int
+	// Total 是拆分得到的总份数。
+	Total int
+	// Index 是份额的横坐标，取值范围为[1, Total]。
+	Index int
+	// SKI 是被拆分的密钥的标识符，拆分的不是密钥时为空。
+	SKI []byte
+	// Digest 是秘密的摘要SHA256(ID || secret)，用于检查恢复出的秘密是否正确。它不是隐藏的承诺，任何持有
+	// 份额的人都可以用它验证对秘密的猜测。
+	Digest []byte
+	// Value 是份额的值，长度与秘密相同。
+	Value []byte
+}
+
+// Split 将秘密secret拆分成n份，任意t份可以恢复出秘密，要求2 <= t <= n <= 255，且秘密不短于MinSecretSize字节。
+func Split(secret []byte, n, t int) ([]*Share, error) {
+	if len(secret) < MinSecretSize {
+		return nil, fmt.Errorf("invalid secret, it must be at least %d bytes, but got [%d]", MinSecretSize, len(secret))
+	}
+	if t < 2 || t > n || n > MaxShares {
+		return nil, fmt.Errorf("invalid parameters n = [%d] and t = [%d], must satisfy 2 <= t <= n <= %d", n, t, MaxShares)
+	}
+
+	id := make([]byte, idSize)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed generating split identifier [%s]", err)
+	}
+
+	d := digest(id, secret)
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{
+			ID:        id,
+			Threshold: t,
+			Total:     n,
+			Index:     i + 1,
+			Digest:    d,
+			Value:     make([]byte, len(secret)),
+		}
+	}
+
+	// 每个秘密字节对应一个t-1次的随机多项式，常数项为该秘密字节。
+	coefs := make([]byte, t)
+	defer wipe(coefs)
+	for j, b := range secret {
+		if _, err := rand.Read(coefs[1:]); err != nil {
+			return nil, fmt.Errorf("failed generating polynomial coefficients [%s]", err)
+		}
+		coefs[0] = b
+		for _, share := range shares {
+			share.Value[j] = evaluate(coefs, byte(share.Index))
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine 利用至少Threshold份来自同一次拆分的份额恢复出秘密，并检查秘密与份额中记录的摘要是否一致。
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("invalid shares, at least one share is required")
+	}
+
+	for i, share := range shares {
+		if err := share.validate(); err != nil {
+			return nil, fmt.Errorf("invalid share at index [%d]: [%w]", i, err)
+		}
+	}
+
+	first := shares[0]
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("not enough shares, [%d] shares are required, but got [%d]", first.Threshold, len(shares))
+	}
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		if subtle.ConstantTimeCompare(share.ID, first.ID) != 1 || share.Threshold != first.Threshold ||
+			share.Total != first.Total || len(share.Value) != len(first.Value) ||
+			!bytes.Equal(share.SKI, first.SKI) || subtle.ConstantTimeCompare(share.Digest, first.Digest) != 1 {
+			return nil, fmt.Errorf("invalid share at index [%d], it belongs to another split", i)
+		}
+		for j := 0; j < i; j++ {
+			if shares[j].Index == share.Index {
+				return nil, fmt.Errorf("invalid share at index [%d], duplicate share [%d]", i, share.Index)
+			}
+		}
+		xs[i] = byte(share.Index)
+	}
+
+	secret := make([]byte, len(first.Value))
+	ys := make([]byte, len(shares))
+	for j := range secret {
+		for i, share := range shares {
+			ys[i] = share.Value[j]
+		}
+		secret[j] = interpolate(xs, ys)
+	}
+
+	if subtle.ConstantTimeCompare(digest(first.ID, secret), first.Digest) != 1 {
+		wipe(secret)
+		return nil, errors.New("failed recovering secret, digest mismatch: some shares are corrupted")
+	}
+	return secret, nil
+}
+
+// validate 检查份额的各个字段是否合法。
+func (s *Share) validate() error {
+	if s == nil {
+		return errors.New("share must not be nil")
+	}
+	if len(s.ID) != idSize {
+		return fmt.Errorf("invalid identifier length [%d], must be %d bytes", len(s.ID), idSize)
+	}
+	if s.Threshold < 2 || s.Threshold > s.Total || s.Total > MaxShares {
+		return fmt.Errorf("invalid parameters n = [%d] and t = [%d]", s.Total, s.Threshold)
+	}
+	if s.Index < 1 || s.Index > s.Total {
+		return fmt.Errorf("invalid share index [%d], must be in range [1, %d]", s.Index, s.Total)
+	}
+	if len(s.Digest) != sha256.Size {
+		return fmt.Errorf("invalid digest length [%d], must be %d bytes", len(s.Digest), sha256.Size)
+	}
+	if len(s.Value) == 0 {
+		return errors.New("invalid value, it must not be empty")
+	}
+	return nil
+}
+
+func digest(id, secret []byte) []byte {
+	h := sha256.New()
+	h.Write(id)
+	h.Write(secret)
+	return h.Sum(nil)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// evaluate 用霍纳法则计算多项式coefs在x处的取值，coefs[0]是常数项。
+func evaluate(coefs []byte, x byte) byte {
+	var y byte
+	for i := len(coefs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefs[i]
+	}
+	return y
+}
+
+// interpolate 用拉格朗日插值计算经过点(xs[i], ys[i])的多项式在0处的取值。GF(2^8)中加法和减法都是异或。
+func interpolate(xs, ys []byte) byte {
+	var y byte
+	for i := range xs {
+		num, den := byte(1), byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			num = mul(num, xs[j])
+			den = mul(den, xs[i]^xs[j])
+		}
+		y ^= mul(ys[i], mul(num, inverse(den)))
+	}
+	return y
+}
+
+// mul 计算GF(2^8)中的乘法，既约多项式为x^8 + x^4 + x^3 + x + 1（与AES相同）。运算不依赖于分支，
+// 执行时间与操作数无关。
+func mul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		a = (a << 1) ^ (-(a >> 7) & 0x1b)
+		b >>= 1
+	}
+	return p
+}
+
+// inverse 计算GF(2^8)中非零元素的乘法逆元a^254。
+func inverse(a byte) byte {
+	// a^254 = a^(2+4+8+16+32+64+128)
+	r := byte(1)
+	sq := a
+	for i := 0; i < 7; i++ {
+		sq = mul(sq, sq)
+		r = mul(r, sq)
+	}
+	return r
+}
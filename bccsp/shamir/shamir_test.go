@@ -0,0 +1,210 @@
+package shamir
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGF256(t *testing.T) {
+	// AES规范中的例子：{57} • {83} = {c1}。
+	require.Equal(t, byte(0xc1), mul(0x57, 0x83))
+	for a := 1; a < 256; a++ {
+		require.Equal(t, byte(1), mul(byte(a), inverse(byte(a))))
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("orderer signing key material")
+
+	shares, err := Split(secret, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	for i, share := range shares {
+		require.Equal(t, i+1, share.Index)
+		require.Len(t, share.Value, len(secret))
+	}
+
+	// 任意3份都可以恢复出秘密。
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			for k := j + 1; k < 5; k++ {
+				recovered, err := Combine([]*Share{shares[k], shares[i], shares[j]})
+				require.NoError(t, err)
+				require.Equal(t, secret, recovered)
+			}
+		}
+	}
+	recovered, err := Combine(shares)
+	require.NoError(t, err)
+	require.Equal(t, secret, recovered)
+
+	_, err = Combine(shares[:2])
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not enough shares, [3] shares are required, but got [2]")
+	_, err = Combine([]*Share{shares[0], shares[1], shares[0]})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "duplicate share [1]")
+
+	// 不同拆分的份额不能混用。
+	others, err := Split(secret, 5, 3)
+	require.NoError(t, err)
+	_, err = Combine([]*Share{shares[0], shares[1], others[2]})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid share at index [2], it belongs to another split")
+
+	// 被篡改的份额导致恢复出的秘密与摘要不一致。
+	tampered := *shares[2]
+	tampered.Value = append([]byte(nil), tampered.Value...)
+	tampered.Value[0] ^= 1
+	_, err = Combine([]*Share{shares[0], shares[1], &tampered})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "digest mismatch")
+
+	// 空的份额在读取门限值之前就会被发现。
+	_, err = Combine([]*Share{nil, shares[1], shares[2]})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid share at index [0]")
+	_, err = Combine([]*Share{shares[0], shares[1], nil})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid share at index [2]")
+
+	for _, tc := range []struct{ n, t int }{{5, 1}, {2, 3}, {256, 2}} {
+		_, err = Split(secret, tc.n, tc.t)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid parameters")
+	}
+	_, err = Split(nil, 3, 2)
+	require.Error(t, err)
+	// 过短的秘密可以通过穷举从摘要中恢复出来，不允许拆分。
+	_, err = Split(secret[:MinSecretSize-1], 3, 2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "at least 16 bytes")
+	_, err = Split(secret[:MinSecretSize], 3, 2)
+	require.NoError(t, err)
+}
+
+func TestSharePEM(t *testing.T) {
+	secret := sha256.Sum256([]byte("secret"))
+	shares, err := Split(secret[:], 3, 2)
+	require.NoError(t, err)
+
+	var bundle []byte
+	for _, share := range shares {
+		raw, err := ShareToPEM(share)
+		require.NoError(t, err)
+		block, _ := pem.Decode(raw)
+		require.NotNil(t, block)
+		require.Equal(t, SharePEMType, block.Type)
+		require.Equal(t, "2", block.Headers["Threshold"])
+		bundle = append(bundle, raw...)
+	}
+
+	decoded, err := PEMToShares(bundle)
+	require.NoError(t, err)
+	require.Len(t, decoded, 3)
+	for i := range shares {
+		require.Equal(t, shares[i].ID, decoded[i].ID)
+		require.Equal(t, shares[i].Index, decoded[i].Index)
+		require.Equal(t, shares[i].Value, decoded[i].Value)
+	}
+	recovered, err := Combine(decoded[1:])
+	require.NoError(t, err)
+	require.Equal(t, secret[:], recovered)
+
+	// 损坏的份额在解码时就会被发现。
+	raw, err := ShareToPEM(shares[0])
+	require.NoError(t, err)
+	block, _ := pem.Decode(raw)
+	block.Bytes[len(block.Bytes)-40] ^= 1
+	_, err = PEMToShares(pem.EncodeToMemory(block))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+
+	_, err = PEMToShares(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no PEM block of type [BCCSP KEY SHARE] found")
+	_, err = ShareToPEM(&Share{})
+	require.Error(t, err)
+}
+
+func TestSplitRecoverKey(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	// 只有可导出的对称密钥才能被拆分，例如通过HMACDeriveKeyOpts派生的密钥。
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	hmacKey, err := csp.KeyDeriv(aesKey, &bccsp.HMACDeriveKeyOpts{Temporary: true, Arg: []byte("backup")})
+	require.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSASecp256k1KeyGenOpts{Temporary: true},
+		&bccsp.Ed25519KeyGenOpts{Temporary: true},
+		&bccsp.SM2KeyGenOpts{Temporary: true},
+		&bccsp.BLS12381KeyGenOpts{Temporary: true},
+		nil,
+	} {
+		k := hmacKey
+		if opts != nil {
+			k, err = csp.KeyGen(opts)
+			require.NoError(t, err)
+		}
+
+		shares, err := SplitKey(csp, k, 3, 2)
+		require.NoError(t, err)
+		require.Equal(t, k.SKI(), shares[0].SKI)
+
+		recovered, err := RecoverKey(csp, shares[1:], true)
+		require.NoError(t, err, "%T", opts)
+		require.Equal(t, k.SKI(), recovered.SKI())
+		require.Equal(t, k.Private(), recovered.Private())
+		require.Equal(t, k.Symmetric(), recovered.Symmetric())
+	}
+
+	// 恢复出的签名密钥可以继续使用。
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	shares, err := SplitKey(csp, k, 5, 3)
+	require.NoError(t, err)
+	recovered, err := RecoverKey(csp, []*Share{shares[4], shares[0], shares[2]}, true)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("block"))
+	signature, err := csp.Sign(recovered, digest[:], nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// 公钥不能拆分。
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	_, err = SplitKey(csp, pk, 3, 2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot be exported")
+	_, err = SplitKey(csp, nil, 3, 2)
+	require.Error(t, err)
+	_, err = SplitKey(csp, aesKey, 3, 2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "symmetric key is not exportable")
+
+	// 份额中记录的SKI与恢复出的密钥不一致。
+	other, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	shares, err = SplitKey(csp, other, 3, 2)
+	require.NoError(t, err)
+	for _, share := range shares {
+		share.SKI = k.SKI()
+	}
+	_, err = RecoverKey(csp, shares, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match")
+	_, err = RecoverKey(csp, []*Share{nil, shares[1]}, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "share must not be nil")
+}
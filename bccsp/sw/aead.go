@@ -0,0 +1,45 @@
+package sw
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// aeadSeal 利用认证加密算法aead加密明文，返回的结果为nonce || ciphertext || tag。nonce为nil时用prng
+// 生成随机的nonce，prng为nil时使用crypto/rand。
+func aeadSeal(aead cipher.AEAD, prng io.Reader, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	if len(nonce) == 0 {
+		if prng == nil {
+			prng = rand.Reader
+		}
+		nonce = make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(prng, nonce); err != nil {
+			return nil, fmt.Errorf("failed generating nonce: [%w]", err)
+		}
+	} else if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce, it must have length %d, was %d", aead.NonceSize(), len(nonce))
+	}
+
+	ciphertext := make([]byte, len(nonce), len(nonce)+len(plaintext)+aead.Overhead())
+	copy(ciphertext, nonce)
+	return aead.Seal(ciphertext, nonce, plaintext, additionalData), nil
+}
+
+// aeadOpen 解密aeadSeal加密的密文，认证失败时返回bccsp.ErrAuthenticationFailed。
+func aeadOpen(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("invalid ciphertext, it is too short")
+	}
+
+	nonce := ciphertext[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, ciphertext[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, bccsp.ErrAuthenticationFailed
+	}
+	return plaintext, nil
+}
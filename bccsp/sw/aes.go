@@ -0,0 +1,249 @@
+package sw
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// GetRandomBytes 返回长度为len的随机字节切片。
+func GetRandomBytes(len int) ([]byte, error) {
+	if len < 0 {
+		return nil, errors.New("len must be larger than 0")
+	}
+
+	buffer := make([]byte, len)
+
+	n, err := rand.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	if n != len {
+		return nil, fmt.Errorf("buffer not filled, requested [%d], got [%d]", len, n)
+	}
+
+	return buffer, nil
+}
+
+// pkcs7Padding 按照PKCS7规则对src进行填充，使其长度为AES块大小的整数倍。
+func pkcs7Padding(src []byte) []byte {
+	padding := aes.BlockSize - len(src)%aes.BlockSize
+	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
+	return append(src, padtext...)
+}
+
+// pkcs7UnPadding 去掉src末尾的PKCS7填充。
+func pkcs7UnPadding(src []byte) ([]byte, error) {
+	length := len(src)
+	if length == 0 {
+		return nil, errors.New("invalid pkcs7 padding (len(src) == 0)")
+	}
+	unpadding := int(src[length-1])
+
+	if unpadding > aes.BlockSize || unpadding == 0 {
+		return nil, errors.New("invalid pkcs7 padding (unpadding > aes.BlockSize || unpadding == 0)")
+	}
+
+	pad := src[len(src)-unpadding:]
+	for i := 0; i < unpadding; i++ {
+		if pad[i] != byte(unpadding) {
+			return nil, errors.New("invalid pkcs7 padding (pad[i] != unpadding)")
+		}
+	}
+
+	return src[:(length - unpadding)], nil
+}
+
+func aesCBCEncrypt(key, s []byte) ([]byte, error) {
+	return aesCBCEncryptWithRand(rand.Reader, key, s)
+}
+
+func aesCBCEncryptWithRand(prng io.Reader, key, s []byte) ([]byte, error) {
+	if len(s)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid plaintext, it must be a multiple of the block size")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, aes.BlockSize+len(s))
+	iv := ciphertext[:aes.BlockSize]
+	if _, err := io.ReadFull(prng, iv); err != nil {
+		return nil, err
+	}
+
+	mode := cipher.NewCBCEncrypter(block, iv)
+	mode.CryptBlocks(ciphertext[aes.BlockSize:], s)
+
+	return ciphertext, nil
+}
+
+func aesCBCEncryptWithIV(IV []byte, key, s []byte) ([]byte, error) {
+	if len(s)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid plaintext, it must be a multiple of the block size")
+	}
+
+	if len(IV) != aes.BlockSize {
+		return nil, errors.New("invalid IV, it must have length the block size")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, aes.BlockSize+len(s))
+	copy(ciphertext[:aes.BlockSize], IV)
+
+	mode := cipher.NewCBCEncrypter(block, IV)
+	mode.CryptBlocks(ciphertext[aes.BlockSize:], s)
+
+	return ciphertext, nil
+}
+
+func aesCBCDecrypt(key, src []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(src) < aes.BlockSize {
+		return nil, errors.New("invalid ciphertext, it must be a multiple of the block size")
+	}
+	iv := src[:aes.BlockSize]
+	src = src[aes.BlockSize:]
+
+	if len(src)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid ciphertext, it must be a multiple of the block size")
+	}
+
+	plaintext := make([]byte, len(src))
+	mode := cipher.NewCBCDecrypter(block, iv)
+	mode.CryptBlocks(plaintext, src)
+
+	return plaintext, nil
+}
+
+// AESCBCPKCS7Encrypt 先对明文进行PKCS7填充，然后以CBC模式进行AES加密，得到的密文的前16个字节是IV。
+func AESCBCPKCS7Encrypt(key, src []byte) ([]byte, error) {
+	tmp := pkcs7Padding(src)
+	return aesCBCEncrypt(key, tmp)
+}
+
+// AESCBCPKCS7EncryptWithRand 与AESCBCPKCS7Encrypt一样，只是利用prng来采样IV。
+func AESCBCPKCS7EncryptWithRand(prng io.Reader, key, src []byte) ([]byte, error) {
+	tmp := pkcs7Padding(src)
+	return aesCBCEncryptWithRand(prng, key, tmp)
+}
+
+// AESCBCPKCS7EncryptWithIV 与AESCBCPKCS7Encrypt一样，只是使用给定的IV。
+func AESCBCPKCS7EncryptWithIV(IV []byte, key, src []byte) ([]byte, error) {
+	tmp := pkcs7Padding(src)
+	return aesCBCEncryptWithIV(IV, key, tmp)
+}
+
+// AESCBCPKCS7Decrypt 以CBC模式进行AES解密，然后去掉PKCS7填充。
+func AESCBCPKCS7Decrypt(key, src []byte) ([]byte, error) {
+	pt, err := aesCBCDecrypt(key, src)
+	if err == nil {
+		return pkcs7UnPadding(pt)
+	}
+	return nil, err
+}
+
+// aesGCMEncrypt 以GCM模式加密明文，nonce为nil时用prng生成随机的nonce，prng为nil时使用crypto/rand。
+// 返回的结果为nonce || ciphertext || tag。
+func aesGCMEncrypt(prng io.Reader, nonce, key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadSeal(aead, prng, nonce, plaintext, additionalData)
+}
+
+// aesGCMDecrypt 解密aesGCMEncrypt加密的密文，认证失败时返回bccsp.ErrAuthenticationFailed。
+func aesGCMDecrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadOpen(aead, ciphertext, additionalData)
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// AESGCMEncrypt 以GCM模式加密明文，nonce是随机生成的，返回的结果为nonce || ciphertext || tag。
+func AESGCMEncrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	return aesGCMEncrypt(nil, nil, key, plaintext, additionalData)
+}
+
+// AESGCMEncryptWithNonce 以GCM模式和给定的nonce加密明文，同一个密钥下的nonce绝对不能重复使用。
+func AESGCMEncryptWithNonce(nonce, key, plaintext, additionalData []byte) ([]byte, error) {
+	if len(nonce) == 0 {
+		return nil, errors.New("invalid nonce, it must not be empty")
+	}
+	return aesGCMEncrypt(nil, nonce, key, plaintext, additionalData)
+}
+
+// AESGCMDecrypt 解密GCM模式下加密的密文，认证失败时返回bccsp.ErrAuthenticationFailed。
+func AESGCMDecrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	return aesGCMDecrypt(key, ciphertext, additionalData)
+}
+
+type aesEncryptor struct{}
+
+func (e *aesEncryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	switch o := opts.(type) {
+	case *bccsp.AESCBCPKCS7ModeOpts:
+		if len(o.IV) != 0 && o.PRNG != nil {
+			return nil, errors.New("invalid options, either IV or PRNG should be different from nil, or both nil")
+		}
+
+		if len(o.IV) != 0 {
+			return AESCBCPKCS7EncryptWithIV(o.IV, k.(*aesPrivateKey).privKey, plaintext)
+		} else if o.PRNG != nil {
+			return AESCBCPKCS7EncryptWithRand(o.PRNG, k.(*aesPrivateKey).privKey, plaintext)
+		}
+		return AESCBCPKCS7Encrypt(k.(*aesPrivateKey).privKey, plaintext)
+	case bccsp.AESCBCPKCS7ModeOpts:
+		return e.Encrypt(k, plaintext, &o)
+	case *bccsp.AESGCMModeOpts:
+		if len(o.Nonce) != 0 && o.PRNG != nil {
+			return nil, errors.New("invalid options, either Nonce or PRNG should be different from nil, or both nil")
+		}
+		return aesGCMEncrypt(o.PRNG, o.Nonce, k.(*aesPrivateKey).privKey, plaintext, o.AdditionalData)
+	case bccsp.AESGCMModeOpts:
+		return e.Encrypt(k, plaintext, &o)
+	default:
+		return nil, fmt.Errorf("mode not recognized [%s]", opts)
+	}
+}
+
+type aesDecryptor struct{}
+
+func (d *aesDecryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	switch o := opts.(type) {
+	case *bccsp.AESCBCPKCS7ModeOpts, bccsp.AESCBCPKCS7ModeOpts:
+		return AESCBCPKCS7Decrypt(k.(*aesPrivateKey).privKey, ciphertext)
+	case *bccsp.AESGCMModeOpts:
+		return aesGCMDecrypt(k.(*aesPrivateKey).privKey, ciphertext, o.AdditionalData)
+	case bccsp.AESGCMModeOpts:
+		return aesGCMDecrypt(k.(*aesPrivateKey).privKey, ciphertext, o.AdditionalData)
+	default:
+		return nil, fmt.Errorf("mode not recognized [%s]", opts)
+	}
+}
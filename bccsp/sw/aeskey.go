@@ -0,0 +1,45 @@
+package sw
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+type aesPrivateKey struct {
+	privKey    []byte
+	exportable bool
+}
+
+// Bytes 只有当密钥是可导出的时候才返回密钥的原始字节，否则返回错误。
+func (k *aesPrivateKey) Bytes() (raw []byte, err error) {
+	if k.exportable {
+		return k.privKey, nil
+	}
+
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回AES密钥的标识符，它等于SHA256(0x01 || key)。
+func (k *aesPrivateKey) SKI() []byte {
+	hash := sha256.New()
+	hash.Write([]byte{0x01})
+	hash.Write(k.privKey)
+	return hash.Sum(nil)
+}
+
+// Symmetric AES是一个对称密码方案，所以此方法返回true。
+func (k *aesPrivateKey) Symmetric() bool {
+	return true
+}
+
+// Private AES密钥是需要保密的，所以此方法返回true。
+func (k *aesPrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey 对称密钥方案没有公钥，调用此方法会返回错误。
+func (k *aesPrivateKey) PublicKey() (bccsp.Key, error) {
+	return nil, errors.New("cannot call this method on a symmetric key")
+}
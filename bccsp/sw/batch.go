@@ -0,0 +1,61 @@
+package sw
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// VerifyBatch 将items中的签名分发给有界的工作池并行验证，工作协程的数量默认等于runtime.GOMAXPROCS(0)。
+// 每一项都通过Verify验证，因此支持所有密钥类型和签名选项。
+func (csp *CSP) VerifyBatch(items []bccsp.VerifyItem, opts *bccsp.VerifyBatchOpts) (valid []bool, err error) {
+	if opts == nil {
+		opts = &bccsp.VerifyBatchOpts{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	valid = make([]bool, len(items))
+	errs := make([]error, len(items))
+	var (
+		next    int64 = -1
+		aborted int32
+		wg      sync.WaitGroup
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(items) || atomic.LoadInt32(&aborted) == 1 {
+					return
+				}
+				item := items[i]
+				valid[i], errs[i] = csp.Verify(item.Key, item.Signature, item.Digest, item.Opts)
+				if errs[i] != nil {
+					valid[i] = false
+				}
+				if !valid[i] && opts.FailFast {
+					atomic.StoreInt32(&aborted, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, e := range errs {
+		if e != nil {
+			return valid, fmt.Errorf("failed verifying item [%d]: [%w]", i, e)
+		}
+	}
+	return valid, nil
+}
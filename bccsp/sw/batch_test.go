@@ -0,0 +1,70 @@
+package sw
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifyItems(t *testing.T, csp bccsp.BCCSP, n int) []bccsp.VerifyItem {
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+
+	items := make([]bccsp.VerifyItem, n)
+	for i := range items {
+		digest := sha256.Sum256([]byte("tx" + strconv.Itoa(i)))
+		signature, err := csp.Sign(k, digest[:], nil)
+		require.NoError(t, err)
+		items[i] = bccsp.VerifyItem{Key: pk, Signature: signature, Digest: digest[:]}
+	}
+	return items
+}
+
+func TestVerifyBatch(t *testing.T) {
+	csp := newTestCSP(t)
+	items := newVerifyItems(t, csp, 64)
+
+	valid, err := bccsp.VerifyBatch(csp, items, nil)
+	require.NoError(t, err)
+	require.Len(t, valid, 64)
+	for i := range valid {
+		require.True(t, valid[i], "item %d", i)
+	}
+
+	// 摘要不匹配的项验证失败，其他项不受影响。
+	items[7].Digest = items[8].Digest
+	items[40].Digest = items[41].Digest
+	for _, workers := range []int{1, 4, 100} {
+		valid, err = csp.(bccsp.BatchVerifier).VerifyBatch(items, &bccsp.VerifyBatchOpts{Workers: workers})
+		require.NoError(t, err)
+		for i := range valid {
+			require.Equal(t, i != 7 && i != 40, valid[i], "item %d", i)
+		}
+	}
+
+	// 无法验证的项报告错误。
+	items[12].Signature = []byte{1, 2, 3}
+	items[30].Key = nil
+	valid, err = bccsp.VerifyBatch(csp, items, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed verifying item [12]")
+	require.False(t, valid[12])
+	require.False(t, valid[30])
+	require.True(t, valid[13])
+
+	// FailFast时第一个失败的项之后的项不再验证。
+	valid, err = bccsp.VerifyBatch(csp, items, &bccsp.VerifyBatchOpts{Workers: 1, FailFast: true})
+	require.NoError(t, err)
+	for i := range valid {
+		require.Equal(t, i < 7, valid[i], "item %d", i)
+	}
+
+	valid, err = bccsp.VerifyBatch(csp, nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, valid)
+}
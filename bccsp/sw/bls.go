@@ -0,0 +1,161 @@
+package sw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// 签名和持有证明采用IETF BLS签名草案中公钥在G1上的持有证明方案，二者使用不同的域分隔标签。
+const (
+	blsSignatureDST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+	blsPopDST       = "BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+)
+
+// blsDST 根据签名选项返回域分隔标签，生成或验证持有证明时，digest必须是公钥的编码。
+func blsDST(pubKey, digest []byte, opts bccsp.SignerOpts) (string, error) {
+	if _, ok := opts.(*bccsp.BLS12381ProofOfPossessionOpts); !ok {
+		return blsSignatureDST, nil
+	}
+	if !bytes.Equal(pubKey, digest) {
+		return "", errors.New("invalid digest, proof of possession must be computed over the public key")
+	}
+	return blsPopDST, nil
+}
+
+// signBLS 将消息哈希到G2上的点H(m)，签名为sk·H(m)的压缩编码。
+func signBLS(k *blsPrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	dst, err := blsDST(k.publicKey().pubKey, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	g2 := bls12381.NewG2()
+	h, err := g2.HashToCurve(digest, []byte(dst))
+	if err != nil {
+		return nil, fmt.Errorf("failed hashing to curve [%s]", err)
+	}
+	sig := g2.MulScalar(g2.New(), h, bls12381.NewFr().FromBytes(k.privKey))
+	return g2.ToCompressed(sig), nil
+}
+
+// verifyBLS 检查配对等式e(g1, σ) = e(pk, H(m))是否成立。
+func verifyBLS(k *blsPublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	dst, err := blsDST(k.pubKey, digest, opts)
+	if err != nil {
+		return false, err
+	}
+
+	g1 := bls12381.NewG1()
+	pub, err := g1.FromCompressed(k.pubKey)
+	if err != nil {
+		return false, fmt.Errorf("failed decoding public key [%s]", err)
+	}
+	sig, err := decodeBLSSignature(signature)
+	if err != nil {
+		return false, err
+	}
+
+	g2 := bls12381.NewG2()
+	h, err := g2.HashToCurve(digest, []byte(dst))
+	if err != nil {
+		return false, fmt.Errorf("failed hashing to curve [%s]", err)
+	}
+
+	engine := bls12381.NewEngine()
+	engine.AddPairInv(g1.One(), sig)
+	engine.AddPair(pub, h)
+	return engine.Check(), nil
+}
+
+// decodeBLSSignature 解码G2上的签名，并检查它在正确的子群中且不是无穷远点。
+func decodeBLSSignature(signature []byte) (*bls12381.PointG2, error) {
+	if len(signature) != blsSignatureSize {
+		return nil, fmt.Errorf("invalid signature length [%d], must be %d bytes", len(signature), blsSignatureSize)
+	}
+	g2 := bls12381.NewG2()
+	sig, err := g2.FromCompressed(signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding signature [%s]", err)
+	}
+	if g2.IsZero(sig) {
+		return nil, errors.New("invalid signature, point at infinity")
+	}
+	return sig, nil
+}
+
+type blsSigner struct{}
+
+func (s *blsSigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return signBLS(k.(*blsPrivateKey), digest, opts)
+}
+
+type blsPrivateKeyVerifier struct{}
+
+func (v *blsPrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifyBLS(k.(*blsPrivateKey).publicKey(), signature, digest, opts)
+}
+
+type blsPublicKeyKeyVerifier struct{}
+
+func (v *blsPublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifyBLS(k.(*blsPublicKey), signature, digest, opts)
+}
+
+type blsAggregator struct{}
+
+// AggregateSignatures 将G2上的签名相加得到聚合签名。
+func (a *blsAggregator) AggregateSignatures(signatures [][]byte, opts bccsp.AggregateOpts) ([]byte, error) {
+	if len(signatures) == 0 {
+		return nil, errors.New("invalid signatures, at least one signature is required")
+	}
+
+	g2 := bls12381.NewG2()
+	agg := g2.Zero()
+	for i, signature := range signatures {
+		sig, err := decodeBLSSignature(signature)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature at index [%d]: [%w]", i, err)
+		}
+		g2.Add(agg, agg, sig)
+	}
+	return g2.ToCompressed(agg), nil
+}
+
+// AggregatePublicKeys 将G1上的公钥相加得到聚合公钥，私钥会被替换成对应的公钥。为了防止恶意公钥(rogue key)
+// 攻击，公钥的持有证明必须已经验证过，即公钥是由私钥得到的，或者是带着持有证明导入的。聚合公钥本身没有持有
+// 证明，不能再次被聚合。
+func (a *blsAggregator) AggregatePublicKeys(keys []bccsp.Key, opts bccsp.AggregateOpts) (bccsp.Key, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("invalid keys, at least one key is required")
+	}
+
+	g1 := bls12381.NewG1()
+	agg := g1.Zero()
+	for i, k := range keys {
+		var pk *blsPublicKey
+		switch kk := k.(type) {
+		case *blsPublicKey:
+			if len(kk.pop) == 0 {
+				return nil, fmt.Errorf("invalid key at index [%d], its proof of possession has not been verified", i)
+			}
+			pk = kk
+		case *blsPrivateKey:
+			pk = kk.publicKey()
+		default:
+			return nil, fmt.Errorf("invalid key at index [%d], expected a BLS12-381 key, but got [%T]", i, k)
+		}
+		pub, err := g1.FromCompressed(pk.pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding public key at index [%d]: [%s]", i, err)
+		}
+		g1.Add(agg, agg, pub)
+	}
+	if g1.IsZero(agg) {
+		return nil, errors.New("invalid aggregated public key, point at infinity")
+	}
+	return &blsPublicKey{pubKey: g1.ToCompressed(agg)}, nil
+}
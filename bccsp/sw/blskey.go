@@ -0,0 +1,140 @@
+package sw
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/utils"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+const (
+	// blsPrivateKeySize 是BLS12-381私钥的字节长度。
+	blsPrivateKeySize = 32
+	// blsPublicKeySize 是BLS12-381公钥（G1上的点）压缩编码的字节长度。
+	blsPublicKeySize = 48
+	// blsSignatureSize 是BLS12-381签名（G2上的点）压缩编码的字节长度。
+	blsSignatureSize = 96
+)
+
+type blsPrivateKey struct {
+	privKey []byte
+}
+
+// Bytes BLS12-381私钥的字节序列表现形式不予支持。
+func (k *blsPrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回BLS12-381私钥的标识符，它等于对应公钥的标识符。
+func (k *blsPrivateKey) SKI() []byte {
+	return k.publicKey().SKI()
+}
+
+// Symmetric BLS是一个非对称密码方案，所以此方法返回false。
+func (k *blsPrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是私钥，所以返回true。
+func (k *blsPrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey 返回BLS12-381私钥对应的公钥，公钥附带用私钥生成的持有证明，因此可以被聚合。
+func (k *blsPrivateKey) PublicKey() (bccsp.Key, error) {
+	pk := k.publicKey()
+	pop, err := signBLS(k, pk.pubKey, &bccsp.BLS12381ProofOfPossessionOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("failed generating proof of possession [%w]", err)
+	}
+	pk.pop = pop
+	return pk, nil
+}
+
+func (k *blsPrivateKey) publicKey() *blsPublicKey {
+	g1 := bls12381.NewG1()
+	pub := g1.MulScalar(g1.New(), g1.One(), bls12381.NewFr().FromBytes(k.privKey))
+	return &blsPublicKey{pubKey: g1.ToCompressed(pub)}
+}
+
+type blsPublicKey struct {
+	pubKey []byte
+	// pop 是已经验证过的持有证明，为空时公钥不能被聚合。
+	pop []byte
+}
+
+// Bytes 返回公钥的48字节压缩编码。
+func (k *blsPublicKey) Bytes() ([]byte, error) {
+	return utils.Clone(k.pubKey), nil
+}
+
+// SKI 返回BLS12-381公钥的标识符，它等于公钥压缩编码的SHA256哈希值。
+func (k *blsPublicKey) SKI() []byte {
+	if len(k.pubKey) == 0 {
+		return nil
+	}
+
+	hash := sha256.New()
+	hash.Write(k.pubKey)
+	return hash.Sum(nil)
+}
+
+// Symmetric BLS是一个非对称密码方案，所以此方法返回false。
+func (k *blsPublicKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是公钥，所以返回false。
+func (k *blsPublicKey) Private() bool {
+	return false
+}
+
+// PublicKey 返回公钥本身。
+func (k *blsPublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
+
+// newBLSPrivateKey 检查私钥的编码是否是[1, r-1]范围内的标量，r是G1和G2的阶。
+func newBLSPrivateKey(raw []byte) (*blsPrivateKey, error) {
+	if len(raw) != blsPrivateKeySize {
+		return nil, fmt.Errorf("invalid BLS12-381 private key length [%d], must be %d bytes", len(raw), blsPrivateKeySize)
+	}
+	d := new(big.Int).SetBytes(raw)
+	if d.Sign() == 0 || d.Cmp(bls12381.NewG1().Q()) >= 0 {
+		return nil, errors.New("invalid BLS12-381 private key, it must be in range [1, r-1]")
+	}
+	return &blsPrivateKey{utils.Clone(raw)}, nil
+}
+
+// newBLSPublicKey 检查公钥的编码是否是G1子群中的点，无穷远点不是合法的公钥。
+func newBLSPublicKey(raw []byte) (*blsPublicKey, error) {
+	if len(raw) != blsPublicKeySize {
+		return nil, fmt.Errorf("invalid BLS12-381 public key length [%d], must be %d bytes", len(raw), blsPublicKeySize)
+	}
+	g1 := bls12381.NewG1()
+	pub, err := g1.FromCompressed(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding BLS12-381 public key [%s]", err)
+	}
+	if g1.IsZero(pub) {
+		return nil, errors.New("invalid BLS12-381 public key, point at infinity")
+	}
+	return &blsPublicKey{pubKey: utils.Clone(raw)}, nil
+}
+
+// setProofOfPossession 验证公钥的持有证明pop，验证通过以后公钥才能被聚合。
+func (k *blsPublicKey) setProofOfPossession(pop []byte) error {
+	valid, err := verifyBLS(k, pop, k.pubKey, &bccsp.BLS12381ProofOfPossessionOpts{})
+	if err != nil {
+		return fmt.Errorf("failed verifying proof of possession [%w]", err)
+	}
+	if !valid {
+		return errors.New("invalid proof of possession")
+	}
+	k.pop = utils.Clone(pop)
+	return nil
+}
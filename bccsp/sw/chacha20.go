@@ -0,0 +1,69 @@
+package sw
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+type chacha20Poly1305Encryptor struct{}
+
+func (e *chacha20Poly1305Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	key := k.(*chacha20Poly1305Key).privKey
+
+	switch o := opts.(type) {
+	case *bccsp.ChaCha20Poly1305ModeOpts:
+		if len(o.Nonce) != 0 && o.PRNG != nil {
+			return nil, errors.New("invalid options, either Nonce or PRNG should be different from nil, or both nil")
+		}
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, err
+		}
+		return aeadSeal(aead, o.PRNG, o.Nonce, plaintext, o.AdditionalData)
+	case bccsp.ChaCha20Poly1305ModeOpts:
+		return e.Encrypt(k, plaintext, &o)
+	case *bccsp.XChaCha20Poly1305ModeOpts:
+		if len(o.Nonce) != 0 && o.PRNG != nil {
+			return nil, errors.New("invalid options, either Nonce or PRNG should be different from nil, or both nil")
+		}
+		aead, err := chacha20poly1305.NewX(key)
+		if err != nil {
+			return nil, err
+		}
+		return aeadSeal(aead, o.PRNG, o.Nonce, plaintext, o.AdditionalData)
+	case bccsp.XChaCha20Poly1305ModeOpts:
+		return e.Encrypt(k, plaintext, &o)
+	default:
+		return nil, fmt.Errorf("mode not recognized [%s]", opts)
+	}
+}
+
+type chacha20Poly1305Decryptor struct{}
+
+func (d *chacha20Poly1305Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	key := k.(*chacha20Poly1305Key).privKey
+
+	switch o := opts.(type) {
+	case *bccsp.ChaCha20Poly1305ModeOpts:
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, err
+		}
+		return aeadOpen(aead, ciphertext, o.AdditionalData)
+	case bccsp.ChaCha20Poly1305ModeOpts:
+		return d.Decrypt(k, ciphertext, &o)
+	case *bccsp.XChaCha20Poly1305ModeOpts:
+		aead, err := chacha20poly1305.NewX(key)
+		if err != nil {
+			return nil, err
+		}
+		return aeadOpen(aead, ciphertext, o.AdditionalData)
+	case bccsp.XChaCha20Poly1305ModeOpts:
+		return d.Decrypt(k, ciphertext, &o)
+	default:
+		return nil, fmt.Errorf("mode not recognized [%s]", opts)
+	}
+}
@@ -0,0 +1,45 @@
+package sw
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+type chacha20Poly1305Key struct {
+	privKey    []byte
+	exportable bool
+}
+
+// Bytes 只有当密钥是可导出的时候才返回密钥的原始字节，否则返回错误。
+func (k *chacha20Poly1305Key) Bytes() (raw []byte, err error) {
+	if k.exportable {
+		return k.privKey, nil
+	}
+
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回ChaCha20-Poly1305密钥的标识符，它等于SHA256(0x02 || key)，与相同字节的AES密钥的标识符不同。
+func (k *chacha20Poly1305Key) SKI() []byte {
+	hash := sha256.New()
+	hash.Write([]byte{0x02})
+	hash.Write(k.privKey)
+	return hash.Sum(nil)
+}
+
+// Symmetric ChaCha20-Poly1305是一个对称密码方案，所以此方法返回true。
+func (k *chacha20Poly1305Key) Symmetric() bool {
+	return true
+}
+
+// Private ChaCha20-Poly1305密钥是需要保密的，所以此方法返回true。
+func (k *chacha20Poly1305Key) Private() bool {
+	return true
+}
+
+// PublicKey 对称密钥没有公钥，所以此方法返回错误。
+func (k *chacha20Poly1305Key) PublicKey() (bccsp.Key, error) {
+	return nil, errors.New("cannot call this method on a symmetric key")
+}
@@ -0,0 +1,68 @@
+package sw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// NewCompositeKeyStore 将多个KeyStore串联成一个KeyStore：GetKey按顺序在各个KeyStore中查找密钥，
+// 返回第一个找到的密钥；StoreKey将密钥存储到第一个可写的KeyStore中。例如可以将一个只读的、存放预先
+// 分发的密钥的KeyStore放在一个可写的基于文件系统的KeyStore前面。
+func NewCompositeKeyStore(stores ...bccsp.KeyStore) (bccsp.KeyStore, error) {
+	if len(stores) == 0 {
+		return nil, errors.New("at least one KeyStore must be provided")
+	}
+	for i, ks := range stores {
+		if ks == nil {
+			return nil, fmt.Errorf("invalid KeyStore at position [%d], it must be different from nil", i)
+		}
+	}
+
+	return &compositeKeyStore{stores: append([]bccsp.KeyStore(nil), stores...)}, nil
+}
+
+type compositeKeyStore struct {
+	stores []bccsp.KeyStore
+}
+
+// ReadOnly 只有当所有的KeyStore都是只读的时候才返回true。
+func (ks *compositeKeyStore) ReadOnly() bool {
+	for _, s := range ks.stores {
+		if !s.ReadOnly() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetKey 按顺序在各个KeyStore中查找与ski相关联的密钥，返回第一个找到的密钥。
+func (ks *compositeKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	if len(ski) == 0 {
+		return nil, errors.New("invalid SKI, cannot be of zero length")
+	}
+
+	var errs []string
+	for i, s := range ks.stores {
+		k, err := s.GetKey(ski)
+		if err == nil {
+			return k, nil
+		}
+		errs = append(errs, fmt.Sprintf("keystore [%d]: %s", i, err))
+	}
+
+	return nil, fmt.Errorf("key with SKI %x not found in any keystore: [%s]", ski, strings.Join(errs, "; "))
+}
+
+// StoreKey 将密钥存储到第一个可写的KeyStore中，如果所有的KeyStore都是只读的，则返回错误。
+func (ks *compositeKeyStore) StoreKey(k bccsp.Key) error {
+	for _, s := range ks.stores {
+		if !s.ReadOnly() {
+			return s.StoreKey(k)
+		}
+	}
+
+	return errors.New("read only KeyStore, no writable keystore available")
+}
@@ -0,0 +1,65 @@
+package sw
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"github.com/232425wxy/lark/bccsp"
+	"golang.org/x/crypto/sha3"
+)
+
+// config 记录了某一安全级别下默认使用的椭圆曲线、哈希函数和AES密钥长度。
+type config struct {
+	ellipticCurve elliptic.Curve
+	hashFunction  func() hash.Hash
+	aesByteLength int
+}
+
+// setSecurityLevel 根据安全级别和哈希族设置默认的密码学参数，目前支持的哈希族有SHA2和SHA3，
+// 支持的安全级别有256和384。
+func (conf *config) setSecurityLevel(securityLevel int, hashFamily string) (err error) {
+	switch hashFamily {
+	case bccsp.SHA2:
+		err = conf.setSecurityLevelSHA2(securityLevel)
+	case bccsp.SHA3:
+		err = conf.setSecurityLevelSHA3(securityLevel)
+	default:
+		err = fmt.Errorf("hash family not supported [%s]", hashFamily)
+	}
+	return
+}
+
+func (conf *config) setSecurityLevelSHA2(level int) (err error) {
+	switch level {
+	case 256:
+		conf.ellipticCurve = elliptic.P256()
+		conf.hashFunction = sha256.New
+		conf.aesByteLength = 32
+	case 384:
+		conf.ellipticCurve = elliptic.P384()
+		conf.hashFunction = sha512.New384
+		conf.aesByteLength = 32
+	default:
+		err = fmt.Errorf("security level not supported [%d]", level)
+	}
+	return
+}
+
+func (conf *config) setSecurityLevelSHA3(level int) (err error) {
+	switch level {
+	case 256:
+		conf.ellipticCurve = elliptic.P256()
+		conf.hashFunction = sha3.New256
+		conf.aesByteLength = 32
+	case 384:
+		conf.ellipticCurve = elliptic.P384()
+		conf.hashFunction = sha3.New384
+		conf.aesByteLength = 32
+	default:
+		err = fmt.Errorf("security level not supported [%d]", level)
+	}
+	return
+}
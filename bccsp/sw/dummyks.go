@@ -0,0 +1,29 @@
+package sw
+
+import (
+	"errors"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// NewDummyKeyStore 返回一个既不加载也不存储密钥的KeyStore，适用于所有密钥都是暂时的场景。
+func NewDummyKeyStore() bccsp.KeyStore {
+	return &dummyKeyStore{}
+}
+
+type dummyKeyStore struct{}
+
+// ReadOnly dummyKeyStore不会存储任何密钥，所以它总是只读的。
+func (ks *dummyKeyStore) ReadOnly() bool {
+	return true
+}
+
+// GetKey dummyKeyStore里不存在任何密钥，所以该方法总是返回错误。
+func (ks *dummyKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	return nil, errors.New("key not found, this is a dummy KeyStore")
+}
+
+// StoreKey dummyKeyStore会直接丢弃给定的密钥，不会返回错误。
+func (ks *dummyKeyStore) StoreKey(k bccsp.Key) error {
+	return nil
+}
@@ -0,0 +1,132 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/utils"
+	"golang.org/x/crypto/curve25519"
+)
+
+// ecdhKeyDeriv 计算本方ECDSA私钥与对方公钥的ECDH共享秘密，即共享点d·Q的x坐标（按曲线的字节长度填充），再用
+// opts中的KDF或HKDF派生出对称密钥。
+func ecdhKeyDeriv(priv *ecdsa.PrivateKey, opts *bccsp.ECDHKeyDerivOpts, kdf *hkdfKeyDeriver) (bccsp.Key, error) {
+	peer, err := ecdhPeerPublicKey(opts.PeerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	curve := priv.Curve
+	if peer.Curve.Params().Name != curve.Params().Name {
+		return nil, fmt.Errorf("invalid peer public key, curve [%s] does not match [%s]", peer.Curve.Params().Name, curve.Params().Name)
+	}
+	if !curve.IsOnCurve(peer.X, peer.Y) {
+		return nil, errors.New("invalid peer public key, point is not on curve")
+	}
+
+	x, y := curve.ScalarMult(peer.X, peer.Y, priv.D.Bytes())
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("invalid shared secret, point at infinity")
+	}
+	secret := x.FillBytes(make([]byte, (curve.Params().BitSize+7)/8))
+
+	return ecdhSharedKey(secret, opts, kdf)
+}
+
+// ecdhPeerPublicKey 从对方的密钥中取出ECDSA公钥，对方的密钥可以来自其他的BCCSP实现（例如PKCS#11）。
+func ecdhPeerPublicKey(k bccsp.Key) (*ecdsa.PublicKey, error) {
+	if k == nil {
+		return nil, errors.New("invalid peer public key, it must not be nil")
+	}
+	if k.Private() {
+		var err error
+		if k, err = k.PublicKey(); err != nil {
+			return nil, fmt.Errorf("failed getting peer public key [%w]", err)
+		}
+	}
+	if pk, ok := k.(*ecdsaPublicKey); ok {
+		return pk.pubKey, nil
+	}
+
+	raw, err := k.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling peer public key [%w]", err)
+	}
+	pub, err := utils.ParseECPKIXPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer public key, expected an ECDSA public key [%w]", err)
+	}
+	return pub, nil
+}
+
+// ecdhSharedKey 用opts中的KDF从共享秘密派生出AES-256密钥，如果opts中指定了HKDF，则用HKDF派生出对称密钥。
+func ecdhSharedKey(secret []byte, opts *bccsp.ECDHKeyDerivOpts, kdf *hkdfKeyDeriver) (bccsp.Key, error) {
+	if opts.HKDF != nil {
+		return kdf.deriveKey(secret, opts.HKDF)
+	}
+
+	key, err := opts.DeriveKey(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed deriving shared key [%w]", err)
+	}
+	return &aesPrivateKey{key, false}, nil
+}
+
+type x25519PrivateKeyKeyDeriver struct {
+	hkdf *hkdfKeyDeriver
+}
+
+// KeyDeriv 计算本方X25519私钥与对方X25519公钥的共享秘密，再用opts中的KDF或HKDF派生出对称密钥。
+func (kd *x25519PrivateKeyKeyDeriver) KeyDeriv(key bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts parameter, it must not be nil")
+	}
+
+	x25519K := key.(*x25519PrivateKey)
+
+	ecdhOpts, ok := opts.(*bccsp.ECDHKeyDerivOpts)
+	if !ok {
+		return nil, fmt.Errorf("unsupported 'KeyDerivOpts' provided [%v]", opts)
+	}
+
+	peer, err := x25519PeerPublicKey(ecdhOpts.PeerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 对方的公钥是小阶点时共享秘密全为0，X25519会返回错误。
+	secret, err := curve25519.X25519(x25519K.privKey, peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed computing X25519 shared secret [%s]", err)
+	}
+
+	return ecdhSharedKey(secret, ecdhOpts, kd.hkdf)
+}
+
+// x25519PeerPublicKey 从对方的密钥中取出X25519公钥的32字节编码。
+func x25519PeerPublicKey(k bccsp.Key) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("invalid peer public key, it must not be nil")
+	}
+	if k.Private() {
+		var err error
+		if k, err = k.PublicKey(); err != nil {
+			return nil, fmt.Errorf("failed getting peer public key [%w]", err)
+		}
+	}
+	if pk, ok := k.(*x25519PublicKey); ok {
+		return pk.pubKey, nil
+	}
+
+	raw, err := k.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling peer public key [%w]", err)
+	}
+	pub, err := parseX25519PKIXPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer public key, expected an X25519 public key [%w]", err)
+	}
+	return pub, nil
+}
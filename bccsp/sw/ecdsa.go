@@ -0,0 +1,83 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/utils"
+)
+
+// signECDSA 利用ECDSA私钥对摘要进行签名，得到的签名的s值会被转换为低S值。
+func signECDSA(k *ecdsa.PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err = utils.ToLowS(&k.PublicKey, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.MarshalECDSASignature(r, s)
+}
+
+// verifyECDSA 利用ECDSA公钥验证签名，签名的s值必须是低S值，否则验证失败。
+func verifyECDSA(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	r, s, err := utils.UnmarshalECDSASignature(signature)
+	if err != nil {
+		return false, fmt.Errorf("failed unmashalling signature [%s]", err)
+	}
+
+	lowS, err := utils.IsLowS(k, s)
+	if err != nil {
+		return false, err
+	}
+
+	if !lowS {
+		return false, fmt.Errorf("invalid S, must be smaller than half the order [%s][%s]", s, utils.GetCurveHalfOrderAt(k.Curve))
+	}
+
+	return ecdsa.Verify(k, digest, r, s), nil
+}
+
+// verifyECDSAKey 根据签名选项选择验证ECDSA签名、Schnorr签名还是MuSig2部分签名。
+func verifyECDSAKey(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	switch o := opts.(type) {
+	case *bccsp.SchnorrSignerOpts:
+		return verifySchnorr(k.Curve, scalarBytes(k.X), signature, digest)
+	case *bccsp.MuSig2SignerOpts:
+		return verifyMuSig2(k, signature, digest, o)
+	default:
+		return verifyECDSA(k, signature, digest, opts)
+	}
+}
+
+type ecdsaSigner struct{}
+
+// Sign 默认生成ECDSA签名，签名选项是SchnorrSignerOpts或MuSig2SignerOpts时分别生成Schnorr签名和MuSig2部分签名。
+func (s *ecdsaSigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	priv := k.(*ecdsaPrivateKey).privKey
+	switch o := opts.(type) {
+	case *bccsp.SchnorrSignerOpts:
+		return signSchnorr(priv, digest, o)
+	case *bccsp.MuSig2SignerOpts:
+		return signMuSig2(priv, digest, o)
+	default:
+		return signECDSA(priv, digest, opts)
+	}
+}
+
+type ecdsaPrivateKeyVerifier struct{}
+
+func (v *ecdsaPrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifyECDSAKey(&(k.(*ecdsaPrivateKey).privKey.PublicKey), signature, digest, opts)
+}
+
+type ecdsaPublicKeyKeyVerifier struct{}
+
+func (v *ecdsaPublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifyECDSAKey(k.(*ecdsaPublicKey).pubKey, signature, digest, opts)
+}
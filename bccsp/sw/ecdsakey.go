@@ -0,0 +1,90 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/utils"
+)
+
+type ecdsaPrivateKey struct {
+	privKey *ecdsa.PrivateKey
+}
+
+// Bytes ECDSA私钥的字节序列表现形式不予支持。
+func (k *ecdsaPrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回ECDSA私钥的标识符，它等于对应公钥的标识符。
+func (k *ecdsaPrivateKey) SKI() []byte {
+	if k.privKey == nil {
+		return nil
+	}
+
+	raw := elliptic.Marshal(k.privKey.Curve, k.privKey.PublicKey.X, k.privKey.PublicKey.Y)
+
+	hash := sha256.New()
+	hash.Write(raw)
+	return hash.Sum(nil)
+}
+
+// Symmetric ECDSA是一个非对称密码方案，所以此方法返回false。
+func (k *ecdsaPrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private ECDSA是非对称密码方案，且该密钥是私钥，所以返回true。
+func (k *ecdsaPrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey 返回ECDSA私钥对应的公钥。
+func (k *ecdsaPrivateKey) PublicKey() (bccsp.Key, error) {
+	return &ecdsaPublicKey{&k.privKey.PublicKey}, nil
+}
+
+type ecdsaPublicKey struct {
+	pubKey *ecdsa.PublicKey
+}
+
+// Bytes 将公钥按照PKIX格式序列化成一串字节序列。
+func (k *ecdsaPublicKey) Bytes() (raw []byte, err error) {
+	raw, err = utils.MarshalECPKIXPublicKey(k.pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling key [%s]", err)
+	}
+	return raw, nil
+}
+
+// SKI 返回ECDSA公钥的标识符。
+func (k *ecdsaPublicKey) SKI() []byte {
+	if k.pubKey == nil {
+		return nil
+	}
+
+	raw := elliptic.Marshal(k.pubKey.Curve, k.pubKey.X, k.pubKey.Y)
+
+	hash := sha256.New()
+	hash.Write(raw)
+	return hash.Sum(nil)
+}
+
+// Symmetric ECDSA是一个非对称密码方案，所以此方法返回false。
+func (k *ecdsaPublicKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是公钥，所以返回false。
+func (k *ecdsaPublicKey) Private() bool {
+	return false
+}
+
+// PublicKey 返回公钥自己。
+func (k *ecdsaPublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
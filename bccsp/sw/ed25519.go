@@ -0,0 +1,37 @@
+package sw
+
+import (
+	"crypto/ed25519"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// signEd25519 利用Ed25519私钥对消息进行签名。Ed25519不需要预先计算消息的哈希值，所以这里的digest就是
+// 完整的消息。
+func signEd25519(k *ed25519.PrivateKey, msg []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return ed25519.Sign(*k, msg), nil
+}
+
+// verifyEd25519 利用Ed25519公钥验证消息的签名。
+func verifyEd25519(k *ed25519.PublicKey, signature, msg []byte, opts bccsp.SignerOpts) (bool, error) {
+	return ed25519.Verify(*k, msg, signature), nil
+}
+
+type ed25519Signer struct{}
+
+func (s *ed25519Signer) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return signEd25519(k.(*ed25519PrivateKey).privKey, digest, opts)
+}
+
+type ed25519PrivateKeyVerifier struct{}
+
+func (v *ed25519PrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	pubKey := k.(*ed25519PrivateKey).privKey.Public().(ed25519.PublicKey)
+	return verifyEd25519(&pubKey, signature, digest, opts)
+}
+
+type ed25519PublicKeyKeyVerifier struct{}
+
+func (v *ed25519PublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifyEd25519(k.(*ed25519PublicKey).pubKey, signature, digest, opts)
+}
@@ -0,0 +1,88 @@
+package sw
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+type ed25519PrivateKey struct {
+	privKey *ed25519.PrivateKey
+}
+
+// Bytes Ed25519私钥的字节序列表现形式不予支持。
+func (k *ed25519PrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回Ed25519私钥的标识符，它等于对应公钥的标识符。
+func (k *ed25519PrivateKey) SKI() []byte {
+	if k.privKey == nil {
+		return nil
+	}
+
+	pubKey := k.privKey.Public().(ed25519.PublicKey)
+
+	hash := sha256.New()
+	hash.Write(pubKey)
+	return hash.Sum(nil)
+}
+
+// Symmetric Ed25519是一个非对称密码方案，所以此方法返回false。
+func (k *ed25519PrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private Ed25519是非对称密码方案，且该密钥是私钥，所以返回true。
+func (k *ed25519PrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey 返回Ed25519私钥对应的公钥。
+func (k *ed25519PrivateKey) PublicKey() (bccsp.Key, error) {
+	pubKey := k.privKey.Public().(ed25519.PublicKey)
+	return &ed25519PublicKey{&pubKey}, nil
+}
+
+type ed25519PublicKey struct {
+	pubKey *ed25519.PublicKey
+}
+
+// Bytes 将公钥按照PKIX格式序列化成一串字节序列。
+func (k *ed25519PublicKey) Bytes() (raw []byte, err error) {
+	raw, err = x509.MarshalPKIXPublicKey(*k.pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling key [%s]", err)
+	}
+	return raw, nil
+}
+
+// SKI 返回Ed25519公钥的标识符，它等于公钥32字节编码的SHA256哈希值。
+func (k *ed25519PublicKey) SKI() []byte {
+	if k.pubKey == nil {
+		return nil
+	}
+
+	hash := sha256.New()
+	hash.Write(*k.pubKey)
+	return hash.Sum(nil)
+}
+
+// Symmetric Ed25519是一个非对称密码方案，所以此方法返回false。
+func (k *ed25519PublicKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是公钥，所以返回false。
+func (k *ed25519PublicKey) Private() bool {
+	return false
+}
+
+// PublicKey 返回公钥自己。
+func (k *ed25519PublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
@@ -0,0 +1,184 @@
+package sw
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/232425wxy/lark/bccsp/utils"
+)
+
+// EncryptedFileBasedKeyStore 是基于文件系统实现的KeyStore，与NewFileBasedKeyStore创建的KeyStore
+// 不同的是，私钥和对称密钥在写入文件前都会以PBES2方案加密：先利用KDFOpts从口令派生出密钥，再以
+// AES-256-CBC模式加密PKCS#8格式的私钥（或ASN.1编码的对称密钥）。公钥不需要保密，所以仍以明文存储。
+type EncryptedFileBasedKeyStore struct {
+	fileBasedKeyStore
+}
+
+// NewEncryptedFileBasedKeyStore 创建一个加密的基于文件系统的KeyStore，pwd是用于保护密钥的口令，
+// 不能为空；opts为空时使用DefaultKDFOpts返回的选项。
+func NewEncryptedFileBasedKeyStore(pwd []byte, path string, readOnly bool, opts *KDFOpts) (*EncryptedFileBasedKeyStore, error) {
+	if len(pwd) == 0 {
+		return nil, errors.New("invalid passphrase, it must not be empty")
+	}
+	if opts == nil {
+		opts = DefaultKDFOpts()
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid KDF options: [%w]", err)
+	}
+
+	ks := &EncryptedFileBasedKeyStore{}
+	ks.pwd = utils.Clone(pwd)
+	kdf := *opts
+	ks.kdf = &kdf
+
+	if err := ks.Init(path, readOnly); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// ChangePassphrase 用新的口令newPwd重新加密KeyStore中的所有私钥和对称密钥，明文存储的密钥也会被加密。
+// 只有当所有密钥都能用当前口令解密时才会开始改写文件，所以口令错误不会导致KeyStore中的密钥被不同的口令
+// 加密。替换文件的过程中出错时，已经替换的文件会被恢复，KeyStore继续使用当前口令。
+func (ks *EncryptedFileBasedKeyStore) ChangePassphrase(newPwd []byte) error {
+	if ks.readOnly {
+		return errors.New("read only KeyStore")
+	}
+	if len(newPwd) == 0 {
+		return errors.New("invalid passphrase, it must not be empty")
+	}
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	files, err := os.ReadDir(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed reading KeyStore at [%s]: [%w]", ks.path, err)
+	}
+
+	// 先用当前口令解密所有的密钥，并用新的口令重新加密，结果写入临时文件。
+	reencrypted := make(map[string]string)
+	cleanup := func() {
+		for _, tmp := range reencrypted {
+			os.Remove(tmp)
+		}
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		var raw []byte
+		path := filepath.Join(ks.path, f.Name())
+		switch {
+		case strings.HasSuffix(f.Name(), "_"+privateKeySuffix):
+			raw, err = ks.reencryptPrivateKey(path, newPwd)
+		case strings.HasSuffix(f.Name(), "_"+symmetricKeySuffix):
+			raw, err = ks.reencryptKey(path, newPwd)
+		default:
+			continue
+		}
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed re-encrypting [%s]: [%w]", f.Name(), err)
+		}
+
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, raw, 0o600); err != nil {
+			cleanup()
+			return fmt.Errorf("failed writing [%s]: [%w]", tmp, err)
+		}
+		reencrypted[path] = tmp
+	}
+
+	// 所有的密钥都重新加密成功以后，再用临时文件替换原来的文件。
+	if err = replaceKeyFiles(reencrypted); err != nil {
+		cleanup()
+		return err
+	}
+
+	ks.pwd = utils.Clone(newPwd)
+	return nil
+}
+
+// renameFile 用于替换密钥文件，测试时可以替换成会失败的实现。
+var renameFile = os.Rename
+
+// replaceKeyFiles 用临时文件替换原来的密钥文件。替换之前原来的文件先被备份为".bak"文件，任何一个文件替换
+// 失败时，已经替换的文件都会从备份中恢复，所有的密钥仍然使用原来的口令加密；只有全部替换成功以后才删除备份。
+// 如果恢复也失败了，返回的错误会列出仍然使用新口令加密的文件，它们的备份会被保留，以便人工恢复。
+func replaceKeyFiles(reencrypted map[string]string) error {
+	backups := make(map[string]string, len(reencrypted))
+	removeBackups := func() {
+		for _, bak := range backups {
+			os.Remove(bak)
+		}
+	}
+
+	for path := range reencrypted {
+		bak := path + ".bak"
+		raw, err := os.ReadFile(path)
+		if err == nil {
+			err = os.WriteFile(bak, raw, 0o600)
+		}
+		if err != nil {
+			removeBackups()
+			return fmt.Errorf("failed backing up [%s]: [%w]", path, err)
+		}
+		backups[path] = bak
+	}
+
+	var replaced []string
+	for path, tmp := range reencrypted {
+		if err := renameFile(tmp, path); err != nil {
+			var migrated []string
+			for _, p := range replaced {
+				if rerr := renameFile(backups[p], p); rerr != nil {
+					migrated = append(migrated, p)
+				}
+			}
+			// 恢复失败的文件保留备份，其余的备份都可以删除。
+			for _, p := range migrated {
+				delete(backups, p)
+			}
+			removeBackups()
+			if len(migrated) != 0 {
+				return fmt.Errorf("failed replacing [%s]: [%w], and failed rolling back %v, they are encrypted with the new passphrase and their backups are kept", path, err, migrated)
+			}
+			return fmt.Errorf("failed replacing [%s], all keys are still encrypted with the old passphrase: [%w]", path, err)
+		}
+		replaced = append(replaced, path)
+	}
+
+	removeBackups()
+	return nil
+}
+
+func (ks *EncryptedFileBasedKeyStore) reencryptPrivateKey(path string, newPwd []byte) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := pemToPrivateKey(raw, ks.pwd)
+	if err != nil {
+		return nil, err
+	}
+	return privateKeyToPEM(key, newPwd, ks.kdf)
+}
+
+func (ks *EncryptedFileBasedKeyStore) reencryptKey(path string, newPwd []byte) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	blockType, key, err := pemToSymmetricKey(raw, ks.pwd)
+	if err != nil {
+		return nil, err
+	}
+	return symmetricKeyToPEM(blockType, key, newPwd, ks.kdf)
+}
@@ -0,0 +1,301 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/stretchr/testify/require"
+)
+
+// 测试中使用较小的开销参数，以加快测试速度。
+var (
+	testScryptOpts = &KDFOpts{Algorithm: Scrypt, SaltSize: 16, N: 1 << 10, R: 8, P: 1}
+	testPBKDF2Opts = &KDFOpts{Algorithm: PBKDF2, SaltSize: 16, Iterations: 1000}
+)
+
+func TestKDFOptsValidate(t *testing.T) {
+	require.NoError(t, DefaultKDFOpts().Validate())
+	require.NoError(t, testScryptOpts.Validate())
+	require.NoError(t, testPBKDF2Opts.Validate())
+
+	require.Error(t, (&KDFOpts{Algorithm: "MD5", SaltSize: 16}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: Scrypt, SaltSize: 4, N: 1024, R: 8, P: 1}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: Scrypt, SaltSize: 16, N: 1000, R: 8, P: 1}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: Scrypt, SaltSize: 16, N: 1024, R: 0, P: 1}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: PBKDF2, SaltSize: 16}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: PBKDF2, SaltSize: 16, Iterations: 1 << 31}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: Scrypt, SaltSize: 16, N: 1 << 30, R: 8, P: 1}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: Scrypt, SaltSize: 16, N: 1 << 20, R: 16, P: 1}).Validate())
+	require.Error(t, (&KDFOpts{Algorithm: Scrypt, SaltSize: 16, N: 1024, R: 8, P: 1 << 20}).Validate())
+}
+
+func TestDecryptPEMBlockExcessiveParams(t *testing.T) {
+	pwd := []byte("passphrase")
+
+	// tamper 修改PEM块中的密钥派生参数，模拟被篡改或损坏的密钥文件。
+	tamper := func(block *pem.Block, modify func(kdfParams []byte) interface{}) *pem.Block {
+		var info encryptedPrivateKeyInfo
+		_, err := asn1.Unmarshal(block.Bytes, &info)
+		require.NoError(t, err)
+		var params pbes2Params
+		_, err = asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params)
+		require.NoError(t, err)
+		kdfParams, err := asn1.Marshal(modify(params.KeyDerivationFunc.Parameters.FullBytes))
+		require.NoError(t, err)
+		params.KeyDerivationFunc.Parameters = asn1.RawValue{FullBytes: kdfParams}
+		raw, err := asn1.Marshal(params)
+		require.NoError(t, err)
+		info.EncryptionAlgorithm.Parameters = asn1.RawValue{FullBytes: raw}
+		der, err := asn1.Marshal(info)
+		require.NoError(t, err)
+		return &pem.Block{Type: block.Type, Bytes: der}
+	}
+
+	block, err := encryptPEMBlock("ENCRYPTED PRIVATE KEY", []byte("secret"), pwd, testPBKDF2Opts)
+	require.NoError(t, err)
+	block = tamper(block, func(raw []byte) interface{} {
+		var p pbkdf2Params
+		_, err := asn1.Unmarshal(raw, &p)
+		require.NoError(t, err)
+		p.IterationCount = 1 << 31
+		return p
+	})
+	_, err = decryptPEMBlock(block, pwd)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid PBKDF2 iteration count [2147483648]")
+
+	block, err = encryptPEMBlock("ENCRYPTED PRIVATE KEY", []byte("secret"), pwd, testScryptOpts)
+	require.NoError(t, err)
+	block = tamper(block, func(raw []byte) interface{} {
+		var p scryptParams
+		_, err := asn1.Unmarshal(raw, &p)
+		require.NoError(t, err)
+		p.CostParameter = 1 << 30
+		return p
+	})
+	_, err = decryptPEMBlock(block, pwd)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid scrypt cost parameter N [1073741824]")
+}
+
+func TestEncryptedKeyStore(t *testing.T) {
+	for _, opts := range []*KDFOpts{testScryptOpts, testPBKDF2Opts} {
+		t.Run(opts.Algorithm, func(t *testing.T) {
+			dir := t.TempDir()
+			ks, err := NewEncryptedFileBasedKeyStore([]byte("passphrase"), dir, false, opts)
+			require.NoError(t, err)
+
+			lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(t, err)
+			sk := &ecdsaPrivateKey{lowLevelKey}
+			aesKey := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+			chachaKey := &chacha20Poly1305Key{[]byte("0123456789abcdef0123456789abcdef"), false}
+			sm4Key := &sm4PrivateKey{[]byte("0123456789abcdef"), false}
+			require.NoError(t, ks.StoreKey(sk))
+			require.NoError(t, ks.StoreKey(sm4Key))
+			require.NoError(t, ks.StoreKey(aesKey))
+			require.NoError(t, ks.StoreKey(chachaKey))
+
+			// 磁盘上不能出现明文的密钥。
+			for name, blockType := range map[string]string{
+				hex.EncodeToString(sk.SKI()) + "_sk":         "ENCRYPTED PRIVATE KEY",
+				hex.EncodeToString(aesKey.SKI()) + "_key":    "ENCRYPTED AES PRIVATE KEY",
+				hex.EncodeToString(chachaKey.SKI()) + "_key": "ENCRYPTED CHACHA20-POLY1305 PRIVATE KEY",
+				hex.EncodeToString(sm4Key.SKI()) + "_key":    "ENCRYPTED SM4 PRIVATE KEY",
+			} {
+				raw, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				block, _ := pem.Decode(raw)
+				require.NotNil(t, block)
+				require.Equal(t, blockType, block.Type)
+			}
+
+			k, err := ks.GetKey(sk.SKI())
+			require.NoError(t, err)
+			require.Equal(t, lowLevelKey.D, k.(*ecdsaPrivateKey).privKey.D)
+
+			k, err = ks.GetKey(aesKey.SKI())
+			require.NoError(t, err)
+			require.Equal(t, aesKey.privKey, k.(*aesPrivateKey).privKey)
+
+			k, err = ks.GetKey(chachaKey.SKI())
+			require.NoError(t, err)
+			require.Equal(t, chachaKey.privKey, k.(*chacha20Poly1305Key).privKey)
+
+			k, err = ks.GetKey(sm4Key.SKI())
+			require.NoError(t, err)
+			require.Equal(t, sm4Key.privKey, k.(*sm4PrivateKey).privKey)
+
+			// 口令错误时返回明确的错误。
+			wrong, err := NewEncryptedFileBasedKeyStore([]byte("wrong"), dir, true, opts)
+			require.NoError(t, err)
+			_, err = wrong.GetKey(sk.SKI())
+			require.Error(t, err)
+			require.True(t, errors.Is(err, ErrIncorrectPassphrase))
+			_, err = wrong.GetKey(aesKey.SKI())
+			require.Error(t, err)
+			require.True(t, errors.Is(err, ErrIncorrectPassphrase))
+
+			// 没有口令的KeyStore无法读取加密的密钥。
+			plain, err := NewFileBasedKeyStore(dir, true)
+			require.NoError(t, err)
+			_, err = plain.GetKey(sk.SKI())
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "a passphrase is required")
+		})
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	dir := t.TempDir()
+
+	// 先存储一个明文的密钥，修改口令时它也会被加密。
+	plain, err := NewFileBasedKeyStore(dir, false)
+	require.NoError(t, err)
+	legacy := &aesPrivateKey{[]byte("fedcba9876543210fedcba9876543210"), false}
+	require.NoError(t, plain.StoreKey(legacy))
+	legacyChaCha := &chacha20Poly1305Key{[]byte("fedcba9876543210fedcba9876543210"), false}
+	require.NoError(t, plain.StoreKey(legacyChaCha))
+
+	ks, err := NewEncryptedFileBasedKeyStore([]byte("old"), dir, false, testScryptOpts)
+	require.NoError(t, err)
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	sk := &ecdsaPrivateKey{lowLevelKey}
+	require.NoError(t, ks.StoreKey(sk))
+	require.NoError(t, ks.StoreKey(&ecdsaPublicKey{&lowLevelKey.PublicKey}))
+
+	require.NoError(t, ks.ChangePassphrase([]byte("new")))
+
+	// 修改口令以后，KeyStore本身使用新的口令。
+	_, err = ks.GetKey(sk.SKI())
+	require.NoError(t, err)
+
+	old, err := NewEncryptedFileBasedKeyStore([]byte("old"), dir, true, testScryptOpts)
+	require.NoError(t, err)
+	_, err = old.GetKey(sk.SKI())
+	require.True(t, errors.Is(err, ErrIncorrectPassphrase))
+	_, err = old.GetKey(legacy.SKI())
+	require.True(t, errors.Is(err, ErrIncorrectPassphrase))
+
+	reopened, err := NewEncryptedFileBasedKeyStore([]byte("new"), dir, false, testScryptOpts)
+	require.NoError(t, err)
+	k, err := reopened.GetKey(legacy.SKI())
+	require.NoError(t, err)
+	require.Equal(t, legacy.privKey, k.(*aesPrivateKey).privKey)
+	k, err = reopened.GetKey(legacyChaCha.SKI())
+	require.NoError(t, err)
+	require.Equal(t, legacyChaCha.privKey, k.(*chacha20Poly1305Key).privKey)
+
+	// 当前口令错误时，不会修改任何文件。
+	oldRW, err := NewEncryptedFileBasedKeyStore([]byte("old"), dir, false, testScryptOpts)
+	require.NoError(t, err)
+	err = oldRW.ChangePassphrase([]byte("other"))
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrIncorrectPassphrase))
+	_, err = reopened.GetKey(sk.SKI())
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+}
+
+func TestChangePassphraseRollback(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := NewEncryptedFileBasedKeyStore([]byte("old"), dir, false, testScryptOpts)
+	require.NoError(t, err)
+	var keys []bccsp.Key
+	for i := 0; i < 3; i++ {
+		lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		keys = append(keys, &ecdsaPrivateKey{lowLevelKey})
+		require.NoError(t, ks.StoreKey(keys[i]))
+	}
+
+	// 第二个文件替换失败时，第一个已经替换的文件会被恢复。
+	defer func() { renameFile = os.Rename }()
+	renamed := 0
+	renameFile = func(oldpath, newpath string) error {
+		if strings.HasSuffix(oldpath, ".tmp") {
+			if renamed++; renamed == 2 {
+				return errors.New("disk failure")
+			}
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	err = ks.ChangePassphrase([]byte("new"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "all keys are still encrypted with the old passphrase")
+
+	reopened, err := NewEncryptedFileBasedKeyStore([]byte("old"), dir, true, testScryptOpts)
+	require.NoError(t, err)
+	for _, k := range keys {
+		_, err = reopened.GetKey(k.SKI())
+		require.NoError(t, err)
+		_, err = ks.GetKey(k.SKI())
+		require.NoError(t, err)
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	// 恢复也失败时，错误中列出使用新口令加密的文件，并保留它们的备份。
+	renamed = 0
+	renameFile = func(oldpath, newpath string) error {
+		if strings.HasSuffix(oldpath, ".bak") {
+			return errors.New("disk failure")
+		}
+		if renamed++; renamed == 2 {
+			return errors.New("disk failure")
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	err = ks.ChangePassphrase([]byte("new"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed rolling back")
+	baks, gerr := filepath.Glob(filepath.Join(dir, "*.bak"))
+	require.NoError(t, gerr)
+	require.Len(t, baks, 1)
+	require.Contains(t, err.Error(), strings.TrimSuffix(baks[0], ".bak"))
+}
+
+func TestEncryptedKeyStoreInvalidInputs(t *testing.T) {
+	_, err := NewEncryptedFileBasedKeyStore(nil, t.TempDir(), false, nil)
+	require.Error(t, err)
+
+	_, err = NewEncryptedFileBasedKeyStore([]byte("pwd"), t.TempDir(), false, &KDFOpts{Algorithm: PBKDF2})
+	require.Error(t, err)
+
+	ks, err := NewEncryptedFileBasedKeyStore([]byte("pwd"), t.TempDir(), true, testPBKDF2Opts)
+	require.NoError(t, err)
+	require.Error(t, ks.ChangePassphrase([]byte("new")))
+}
+
+func TestCSPWithEncryptedKeyStore(t *testing.T) {
+	ks, err := NewEncryptedFileBasedKeyStore([]byte("passphrase"), t.TempDir(), false, testScryptOpts)
+	require.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	k2, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+
+	ct, err := csp.Encrypt(k, []byte("hello world"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	pt, err := csp.Decrypt(k2, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello world"), pt)
+}
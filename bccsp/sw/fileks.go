@@ -0,0 +1,416 @@
+package sw
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+)
+
+const (
+	// privateKeySuffix 是存储私钥的文件名后缀。
+	privateKeySuffix = "sk"
+	// publicKeySuffix 是存储公钥的文件名后缀。
+	publicKeySuffix = "pk"
+	// symmetricKeySuffix 是存储对称密钥的文件名后缀。
+	symmetricKeySuffix = "key"
+)
+
+// NewFileBasedKeyStore 创建一个基于文件系统的KeyStore，密钥以PEM格式存储在path目录下，文件名为
+// "<十六进制SKI>_<后缀>"，其中私钥、公钥和对称密钥的后缀分别是"sk"、"pk"和"key"。如果path目录不存在，
+// 且KeyStore不是只读的，则会创建该目录。
+func NewFileBasedKeyStore(path string, readOnly bool) (bccsp.KeyStore, error) {
+	ks := &fileBasedKeyStore{}
+	return ks, ks.Init(path, readOnly)
+}
+
+// fileBasedKeyStore 是基于文件系统实现的KeyStore，每个密钥都存储在一个单独的文件中。
+// 已经存在的密钥文件不会被覆盖。
+type fileBasedKeyStore struct {
+	path     string
+	readOnly bool
+	isOpen   bool
+
+	// pwd 不为空时，私钥和对称密钥会被加密后再写入文件。
+	pwd []byte
+	// kdf 是从pwd派生出加密密钥时使用的选项。
+	kdf *KDFOpts
+
+	// 保护对密钥文件的并发读写。
+	m sync.RWMutex
+}
+
+// Init 初始化KeyStore，path是存放密钥文件的目录，readOnly表示KeyStore是否是只读的。
+func (ks *fileBasedKeyStore) Init(path string, readOnly bool) error {
+	if len(path) == 0 {
+		return errors.New("an invalid KeyStore path provided, path cannot be an empty string")
+	}
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	if ks.isOpen {
+		return errors.New("keystore is already initialized")
+	}
+
+	ks.path = path
+	ks.readOnly = readOnly
+
+	if err := ks.createKeyStoreIfNotExists(); err != nil {
+		return err
+	}
+
+	ks.isOpen = true
+	return nil
+}
+
+// ReadOnly 如果KeyStore是只读的，则返回true，否则返回false。
+func (ks *fileBasedKeyStore) ReadOnly() bool {
+	return ks.readOnly
+}
+
+// GetKey 根据ski获取对应的密钥，如果没有以ski命名的密钥文件，则会遍历整个目录寻找SKI与之匹配的私钥。
+func (ks *fileBasedKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	if len(ski) == 0 {
+		return nil, errors.New("invalid SKI, cannot be of zero length")
+	}
+
+	ks.m.RLock()
+	defer ks.m.RUnlock()
+
+	alias := hex.EncodeToString(ski)
+	suffix := ks.getSuffix(alias)
+
+	switch suffix {
+	case symmetricKeySuffix:
+		key, err := ks.loadKey(alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading key [%x]: [%w]", ski, err)
+		}
+		return key, nil
+	case privateKeySuffix:
+		key, err := ks.loadPrivateKey(alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading secret key [%x]: [%w]", ski, err)
+		}
+		return privateKeyToBCCSPKey(key)
+	case publicKeySuffix:
+		key, err := ks.loadPublicKey(alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading public key [%x]: [%w]", ski, err)
+		}
+		return publicKeyToBCCSPKey(key)
+	default:
+		return ks.searchKeystoreForSKI(ski)
+	}
+}
+
+// StoreKey 将密钥存储到KeyStore中，如果KeyStore是只读的，或者相同SKI的密钥已经存在，则返回错误。
+func (ks *fileBasedKeyStore) StoreKey(k bccsp.Key) error {
+	if ks.readOnly {
+		return errors.New("read only KeyStore")
+	}
+
+	if k == nil {
+		return errors.New("invalid key, it must be different from nil")
+	}
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		if err := ks.storePrivateKey(hex.EncodeToString(k.SKI()), kk.privKey); err != nil {
+			return fmt.Errorf("failed storing ECDSA private key: [%w]", err)
+		}
+	case *ecdsaPublicKey:
+		if err := ks.storePublicKey(hex.EncodeToString(k.SKI()), kk.pubKey); err != nil {
+			return fmt.Errorf("failed storing ECDSA public key: [%w]", err)
+		}
+	case *ed25519PrivateKey:
+		if err := ks.storePrivateKey(hex.EncodeToString(k.SKI()), kk.privKey); err != nil {
+			return fmt.Errorf("failed storing Ed25519 private key: [%w]", err)
+		}
+	case *ed25519PublicKey:
+		if err := ks.storePublicKey(hex.EncodeToString(k.SKI()), kk.pubKey); err != nil {
+			return fmt.Errorf("failed storing Ed25519 public key: [%w]", err)
+		}
+	case *x25519PrivateKey:
+		if err := ks.storePrivateKey(hex.EncodeToString(k.SKI()), kk); err != nil {
+			return fmt.Errorf("failed storing X25519 private key: [%w]", err)
+		}
+	case *x25519PublicKey:
+		if err := ks.storePublicKey(hex.EncodeToString(k.SKI()), kk); err != nil {
+			return fmt.Errorf("failed storing X25519 public key: [%w]", err)
+		}
+	case *blsPrivateKey:
+		if err := ks.storeKey(hex.EncodeToString(k.SKI()), blsPrivateKeyPEMType, kk.privKey); err != nil {
+			return fmt.Errorf("failed storing BLS12-381 private key: [%w]", err)
+		}
+	case *blsPublicKey:
+		if err := ks.storePublicKey(hex.EncodeToString(k.SKI()), kk); err != nil {
+			return fmt.Errorf("failed storing BLS12-381 public key: [%w]", err)
+		}
+	case *sm2PrivateKey:
+		if err := ks.storePrivateKey(hex.EncodeToString(k.SKI()), kk.privKey); err != nil {
+			return fmt.Errorf("failed storing SM2 private key: [%w]", err)
+		}
+	case *sm2PublicKey:
+		if err := ks.storePublicKey(hex.EncodeToString(k.SKI()), kk.pubKey); err != nil {
+			return fmt.Errorf("failed storing SM2 public key: [%w]", err)
+		}
+	case *rsaPublicKey:
+		if err := ks.storePublicKey(hex.EncodeToString(k.SKI()), kk.pubKey); err != nil {
+			return fmt.Errorf("failed storing RSA public key: [%w]", err)
+		}
+	case *aesPrivateKey:
+		if err := ks.storeKey(hex.EncodeToString(k.SKI()), aesKeyPEMType, kk.privKey); err != nil {
+			return fmt.Errorf("failed storing AES key: [%w]", err)
+		}
+	case *sm4PrivateKey:
+		if err := ks.storeKey(hex.EncodeToString(k.SKI()), sm4KeyPEMType, kk.privKey); err != nil {
+			return fmt.Errorf("failed storing SM4 key: [%w]", err)
+		}
+	case *chacha20Poly1305Key:
+		if err := ks.storeKey(hex.EncodeToString(k.SKI()), chacha20Poly1305KeyPEMType, kk.privKey); err != nil {
+			return fmt.Errorf("failed storing ChaCha20-Poly1305 key: [%w]", err)
+		}
+	default:
+		return fmt.Errorf("key type not recognized [%s]", k)
+	}
+
+	return nil
+}
+
+// privateKeyToBCCSPKey 将底层的私钥转换为bccsp.Key。
+func privateKeyToBCCSPKey(key interface{}) (bccsp.Key, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		if gm.IsSM2Curve(k.Curve) {
+			return &sm2PrivateKey{k}, nil
+		}
+		return &ecdsaPrivateKey{k}, nil
+	case *ed25519.PrivateKey:
+		return &ed25519PrivateKey{k}, nil
+	case *x25519PrivateKey:
+		return k, nil
+	default:
+		return nil, errors.New("secret key type not recognized")
+	}
+}
+
+// publicKeyToBCCSPKey 将底层的公钥转换为bccsp.Key。
+func publicKeyToBCCSPKey(key interface{}) (bccsp.Key, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if gm.IsSM2Curve(k.Curve) {
+			return &sm2PublicKey{k}, nil
+		}
+		return &ecdsaPublicKey{k}, nil
+	case *ed25519.PublicKey:
+		return &ed25519PublicKey{k}, nil
+	case *rsa.PublicKey:
+		return &rsaPublicKey{k}, nil
+	case *x25519PublicKey:
+		return k, nil
+	case *blsPublicKey:
+		return k, nil
+	default:
+		return nil, errors.New("public key type not recognized")
+	}
+}
+
+// searchKeystoreForSKI 遍历KeyStore目录下的所有文件，寻找SKI与给定ski相同的私钥，这使得不以SKI
+// 命名的私钥文件（例如由其他工具生成的私钥文件）也能被找到。
+func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err error) {
+	files, _ := os.ReadDir(ks.path)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil || info.Size() > (1<<16) { // 密钥文件不会超过64KB
+			continue
+		}
+
+		raw, err := os.ReadFile(filepath.Join(ks.path, f.Name()))
+		if err != nil {
+			continue
+		}
+
+		key, err := pemToPrivateKey(raw, ks.pwd)
+		if err != nil {
+			continue
+		}
+
+		k, err = privateKeyToBCCSPKey(key)
+		if err != nil {
+			continue
+		}
+
+		if !bytes.Equal(k.SKI(), ski) {
+			continue
+		}
+
+		return k, nil
+	}
+	return nil, fmt.Errorf("key with SKI %x not found in %s", ski, ks.path)
+}
+
+// getSuffix 返回以alias命名的密钥文件的后缀，私钥优先于对称密钥，对称密钥优先于公钥，如果不存在这样的
+// 文件，则返回空字符串。
+func (ks *fileBasedKeyStore) getSuffix(alias string) string {
+	for _, suffix := range []string{privateKeySuffix, symmetricKeySuffix, publicKeySuffix} {
+		if info, err := os.Stat(ks.getPathForAlias(alias, suffix)); err == nil && !info.IsDir() {
+			return suffix
+		}
+	}
+	return ""
+}
+
+func (ks *fileBasedKeyStore) storePrivateKey(alias string, privateKey interface{}) error {
+	rawKey, err := privateKeyToPEM(privateKey, ks.pwd, ks.kdf)
+	if err != nil {
+		return err
+	}
+
+	return ks.writeKeyFile(alias, privateKeySuffix, rawKey)
+}
+
+func (ks *fileBasedKeyStore) storePublicKey(alias string, publicKey interface{}) error {
+	rawKey, err := publicKeyToPEM(publicKey)
+	if err != nil {
+		return err
+	}
+
+	return ks.writeKeyFile(alias, publicKeySuffix, rawKey)
+}
+
+func (ks *fileBasedKeyStore) storeKey(alias, blockType string, key []byte) error {
+	rawKey, err := symmetricKeyToPEM(blockType, key, ks.pwd, ks.kdf)
+	if err != nil {
+		return err
+	}
+
+	return ks.writeKeyFile(alias, symmetricKeySuffix, rawKey)
+}
+
+func (ks *fileBasedKeyStore) loadPrivateKey(alias string) (interface{}, error) {
+	raw, err := os.ReadFile(ks.getPathForAlias(alias, privateKeySuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	return pemToPrivateKey(raw, ks.pwd)
+}
+
+func (ks *fileBasedKeyStore) loadPublicKey(alias string) (interface{}, error) {
+	raw, err := os.ReadFile(ks.getPathForAlias(alias, publicKeySuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	return pemToPublicKey(raw)
+}
+
+// loadKey 读取以alias命名的对称密钥或BLS12-381私钥，根据PEM块的类型确定密钥的种类。
+func (ks *fileBasedKeyStore) loadKey(alias string) (bccsp.Key, error) {
+	raw, err := os.ReadFile(ks.getPathForAlias(alias, symmetricKeySuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	blockType, key, err := pemToSymmetricKey(raw, ks.pwd)
+	if err != nil {
+		return nil, err
+	}
+
+	return symmetricKeyToBCCSPKey(blockType, key)
+}
+
+// symmetricKeyToBCCSPKey 根据PEM块的类型将对称密钥（以及BLS12-381私钥）转换为bccsp.Key。
+func symmetricKeyToBCCSPKey(blockType string, key []byte) (bccsp.Key, error) {
+	switch blockType {
+	case aesKeyPEMType:
+		return &aesPrivateKey{key, false}, nil
+	case sm4KeyPEMType:
+		return &sm4PrivateKey{key, false}, nil
+	case chacha20Poly1305KeyPEMType:
+		return &chacha20Poly1305Key{key, false}, nil
+	case blsPrivateKeyPEMType:
+		return newBLSPrivateKey(key)
+	default:
+		return nil, fmt.Errorf("symmetric key type not recognized [%s]", blockType)
+	}
+}
+
+// writeKeyFile 将密钥写入新的文件中，如果文件已经存在，则返回错误，不会覆盖已有的密钥。
+func (ks *fileBasedKeyStore) writeKeyFile(alias, suffix string, raw []byte) error {
+	path := ks.getPathForAlias(alias, suffix)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("key [%s] already exists in the keystore", filepath.Base(path))
+		}
+		return fmt.Errorf("failed creating key file [%s]: [%w]", path, err)
+	}
+
+	if _, err = f.Write(raw); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed writing key file [%s]: [%w]", path, err)
+	}
+
+	return f.Close()
+}
+
+func (ks *fileBasedKeyStore) createKeyStoreIfNotExists() error {
+	missing, err := dirMissingOrEmpty(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed checking KeyStore at [%s]: [%w]", ks.path, err)
+	}
+
+	if missing && !ks.readOnly {
+		if err := os.MkdirAll(ks.path, 0o755); err != nil {
+			return fmt.Errorf("failed creating KeyStore at [%s]: [%w]", ks.path, err)
+		}
+	}
+
+	return nil
+}
+
+func (ks *fileBasedKeyStore) getPathForAlias(alias, suffix string) string {
+	return filepath.Join(ks.path, alias+"_"+suffix)
+}
+
+// dirMissingOrEmpty 判断目录是否不存在或者为空。
+func dirMissingOrEmpty(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if _, err = f.Readdir(1); err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,316 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidStoreKey(t *testing.T) {
+	ks, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+
+	err = ks.StoreKey(nil)
+	require.Error(t, err)
+
+	err = ks.StoreKey(&ecdsaPrivateKey{nil})
+	require.Error(t, err)
+
+	err = ks.StoreKey(&ecdsaPublicKey{nil})
+	require.Error(t, err)
+}
+
+func TestStoreAndGetKey(t *testing.T) {
+	ks, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+	require.False(t, ks.ReadOnly())
+
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	sk := &ecdsaPrivateKey{lowLevelKey}
+	pk := &ecdsaPublicKey{&lowLevelKey.PublicKey}
+	aesKey := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	sm4Key := &sm4PrivateKey{[]byte("0123456789abcdef"), false}
+
+	for _, k := range []bccsp.Key{sk, pk, aesKey, sm4Key} {
+		require.NoError(t, ks.StoreKey(k))
+	}
+
+	k, err := ks.GetKey(sk.SKI())
+	require.NoError(t, err)
+	require.True(t, k.Private())
+	require.Equal(t, lowLevelKey.D, k.(*ecdsaPrivateKey).privKey.D)
+
+	k, err = ks.GetKey(aesKey.SKI())
+	require.NoError(t, err)
+	require.True(t, k.Symmetric())
+	require.Equal(t, aesKey.privKey, k.(*aesPrivateKey).privKey)
+
+	k, err = ks.GetKey(sm4Key.SKI())
+	require.NoError(t, err)
+	require.True(t, k.Symmetric())
+	require.Equal(t, sm4Key.privKey, k.(*sm4PrivateKey).privKey)
+
+	_, err = ks.GetKey([]byte("not existing"))
+	require.Error(t, err)
+
+	_, err = ks.GetKey(nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid SKI, cannot be of zero length")
+}
+
+func TestStoreAndGetKeyRoundTrip(t *testing.T) {
+	csp := newTestCSP(t)
+
+	for _, tc := range []struct {
+		name string
+		opts bccsp.KeyGenOpts
+	}{
+		{"Ed25519", &bccsp.Ed25519KeyGenOpts{Temporary: true}},
+		{"SM2", &bccsp.SM2KeyGenOpts{Temporary: true}},
+		{"Secp256k1", &bccsp.ECDSASecp256k1KeyGenOpts{Temporary: true}},
+		{"X25519", &bccsp.X25519KeyGenOpts{Temporary: true}},
+		{"BLS12381", &bccsp.BLS12381KeyGenOpts{Temporary: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sk, err := csp.KeyGen(tc.opts)
+			require.NoError(t, err)
+			expected, err := keyToPEM(sk)
+			require.NoError(t, err)
+
+			plainStore, err := NewFileBasedKeyStore(t.TempDir(), false)
+			require.NoError(t, err)
+			pwdStore, err := NewEncryptedFileBasedKeyStore([]byte("passphrase"), t.TempDir(), false, testPBKDF2Opts)
+			require.NoError(t, err)
+			for _, ks := range []bccsp.KeyStore{plainStore, pwdStore} {
+				require.NoError(t, ks.StoreKey(sk))
+				k, err := ks.GetKey(sk.SKI())
+				require.NoError(t, err)
+				require.IsType(t, sk, k)
+				require.True(t, k.Private())
+				actual, err := keyToPEM(k)
+				require.NoError(t, err)
+				require.Equal(t, expected, actual)
+			}
+
+			// 只有公钥的时候也能够被读取出来。
+			pk, err := sk.PublicKey()
+			require.NoError(t, err)
+			pkStore, err := NewFileBasedKeyStore(t.TempDir(), false)
+			require.NoError(t, err)
+			require.NoError(t, pkStore.StoreKey(pk))
+			k, err := pkStore.GetKey(sk.SKI())
+			require.NoError(t, err)
+			require.IsType(t, pk, k)
+			require.False(t, k.Private())
+			raw, err := pk.Bytes()
+			require.NoError(t, err)
+			actual, err := k.Bytes()
+			require.NoError(t, err)
+			require.Equal(t, raw, actual)
+		})
+	}
+}
+
+func TestStoreAndGetRSAPublicKey(t *testing.T) {
+	ks, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+
+	lowLevelKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// 非临时的导入会将公钥保存到KeyStore中。
+	k, err := csp.KeyImport(&lowLevelKey.PublicKey, &bccsp.RSAGoPublicKeyImportOpts{})
+	require.NoError(t, err)
+
+	k2, err := ks.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.False(t, k2.Private())
+	require.Equal(t, lowLevelKey.PublicKey.N, k2.(*rsaPublicKey).pubKey.N)
+	require.Equal(t, lowLevelKey.PublicKey.E, k2.(*rsaPublicKey).pubKey.E)
+}
+
+func TestStoreAndGetBLSPublicKey(t *testing.T) {
+	csp := newTestCSP(t)
+	k, err := csp.KeyGen(&bccsp.BLS12381KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	pkStore, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+	require.NoError(t, pkStore.StoreKey(pk))
+
+	// 持有证明保存在PEM头部，读取时重新验证，所以读取出来的公钥仍然可以被聚合。
+	pk2, err := pkStore.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.Equal(t, pk.(*blsPublicKey).pop, pk2.(*blsPublicKey).pop)
+	_, err = csp.AggregatePublicKeys([]bccsp.Key{pk2}, &bccsp.BLS12381AggregateOpts{})
+	require.NoError(t, err)
+
+	path := filepath.Join(pkStore.(*fileBasedKeyStore).path, hex.EncodeToString(k.SKI())+"_"+publicKeySuffix)
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// 用对消息的签名替换持有证明，读取时验证失败。
+	sig, err := csp.Sign(k, raw, nil)
+	require.NoError(t, err)
+	block, _ := pem.Decode(raw)
+	block.Headers[blsProofOfPossessionHeader] = hex.EncodeToString(sig)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	_, err = pkStore.GetKey(k.SKI())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid proof of possession")
+}
+
+func TestGetPublicKeyOnly(t *testing.T) {
+	ks, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	pk := &ecdsaPublicKey{&lowLevelKey.PublicKey}
+	require.NoError(t, ks.StoreKey(pk))
+
+	k, err := ks.GetKey(pk.SKI())
+	require.NoError(t, err)
+	require.False(t, k.Private())
+	require.Equal(t, pk.SKI(), k.SKI())
+}
+
+func TestStoreKeyRefusesOverwrite(t *testing.T) {
+	ks, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+
+	aesKey := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	require.NoError(t, ks.StoreKey(aesKey))
+
+	err = ks.StoreKey(aesKey)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already exists in the keystore")
+}
+
+func TestReadOnlyKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := NewFileBasedKeyStore(dir, false)
+	require.NoError(t, err)
+
+	aesKey := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	require.NoError(t, ks.StoreKey(aesKey))
+
+	roks, err := NewFileBasedKeyStore(dir, true)
+	require.NoError(t, err)
+	require.True(t, roks.ReadOnly())
+
+	err = roks.StoreKey(&aesPrivateKey{[]byte("fedcba9876543210fedcba9876543210"), false})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "read only KeyStore")
+
+	k, err := roks.GetKey(aesKey.SKI())
+	require.NoError(t, err)
+	require.Equal(t, aesKey.SKI(), k.SKI())
+}
+
+func TestKeyStoreCreatesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "keystore")
+
+	_, err := NewFileBasedKeyStore(dir, true)
+	require.NoError(t, err)
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+
+	_, err = NewFileBasedKeyStore(dir, false)
+	require.NoError(t, err)
+	_, err = os.Stat(dir)
+	require.NoError(t, err)
+
+	_, err = NewFileBasedKeyStore("", false)
+	require.Error(t, err)
+}
+
+func TestSearchKeystoreForSKI(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := NewFileBasedKeyStore(dir, false)
+	require.NoError(t, err)
+
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw, err := privateKeyToPEM(lowLevelKey, nil, nil)
+	require.NoError(t, err)
+
+	// 私钥文件不以SKI命名。
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "priv_sk"), raw, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "garbage"), []byte("garbage"), 0o600))
+
+	sk := &ecdsaPrivateKey{lowLevelKey}
+	k, err := ks.GetKey(sk.SKI())
+	require.NoError(t, err)
+	require.Equal(t, sk.SKI(), k.SKI())
+}
+
+func TestConcurrentStoreAndGetKey(t *testing.T) {
+	ks, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := &aesPrivateKey{[]byte(fmt.Sprintf("%032d", i)), false}
+			if err := ks.StoreKey(key); err != nil {
+				errs <- err
+				return
+			}
+			k, err := ks.GetKey(key.SKI())
+			if err != nil {
+				errs <- err
+				return
+			}
+			if hex.EncodeToString(k.SKI()) != hex.EncodeToString(key.SKI()) {
+				errs <- fmt.Errorf("SKI mismatch for key %d", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestCSPWithFileBasedKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	csp, err := NewDefaultSecurityLevel(dir)
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+
+	k2, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.True(t, k2.Private())
+
+	digest := []byte("0123456789abcdef0123456789abcdef")
+	signature, err := csp.Sign(k2, digest, nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, signature, digest, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	_, err = os.Stat(filepath.Join(dir, hex.EncodeToString(k.SKI())+"_sk"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,103 @@
+package sw
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/232425wxy/lark/bccsp"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+type hkdfKeyDeriver struct {
+	bccsp bccsp.BCCSP
+}
+
+// KeyDeriv 以对称密钥k为输入密钥材料，用HKDF派生出新的对称密钥，哈希函数从CSP注册的哈希函数中查找。
+func (kd *hkdfKeyDeriver) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts parameter, it must not be nil")
+	}
+
+	hkdfOpts, ok := opts.(*bccsp.HKDFKeyDerivOpts)
+	if !ok {
+		return nil, fmt.Errorf("unsupported 'KeyDerivOpts' provided [%v]", opts)
+	}
+
+	var ikm []byte
+	switch kk := k.(type) {
+	case *aesPrivateKey:
+		ikm = kk.privKey
+	case *sm4PrivateKey:
+		ikm = kk.privKey
+	case *chacha20Poly1305Key:
+		ikm = kk.privKey
+	default:
+		return nil, fmt.Errorf("unsupported 'Key' provided [%v]", k)
+	}
+
+	return kd.deriveKey(ikm, hkdfOpts)
+}
+
+// deriveKey 用HKDF从输入密钥材料ikm派生出opts指定类型和长度的对称密钥。
+func (kd *hkdfKeyDeriver) deriveKey(ikm []byte, opts *bccsp.HKDFKeyDerivOpts) (bccsp.Key, error) {
+	hashFunction := opts.Hash
+	if hashFunction == "" {
+		hashFunction = bccsp.SHA256
+	}
+	hashOpts, err := bccsp.GetHashOpt(hashFunction)
+	if err != nil {
+		return nil, err
+	}
+	h, err := kd.bccsp.GetHash(hashOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting hash function [%s]: [%w]", hashFunction, err)
+	}
+	newHash := func() hash.Hash {
+		h, _ := kd.bccsp.GetHash(hashOpts)
+		return h
+	}
+
+	length := opts.Length
+	switch opts.KeyType {
+	case "", bccsp.AES:
+		if length == 0 {
+			length = 32
+		}
+		if length != 16 && length != 24 && length != 32 {
+			return nil, fmt.Errorf("invalid AES key length [%d], must be 16, 24 or 32 bytes", length)
+		}
+	case bccsp.HMAC:
+		if length == 0 {
+			length = h.Size()
+		}
+	case bccsp.ChaCha20Poly1305:
+		if length == 0 {
+			length = chacha20poly1305.KeySize
+		}
+		if length != chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("invalid ChaCha20-Poly1305 key length [%d], must be %d bytes", length, chacha20poly1305.KeySize)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type [%s], must be AES, HMAC or ChaCha20Poly1305", opts.KeyType)
+	}
+	if length < 0 || length > 255*h.Size() {
+		return nil, fmt.Errorf("invalid key length [%d], must be at most %d bytes", length, 255*h.Size())
+	}
+
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(newHash, ikm, opts.Salt, opts.Info), key); err != nil {
+		return nil, fmt.Errorf("failed deriving key with HKDF [%w]", err)
+	}
+
+	switch opts.KeyType {
+	case bccsp.HMAC:
+		return &aesPrivateKey{key, true}, nil
+	case bccsp.ChaCha20Poly1305:
+		return &chacha20Poly1305Key{key, false}, nil
+	default:
+		return &aesPrivateKey{key, false}, nil
+	}
+}
@@ -0,0 +1,365 @@
+package sw
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"reflect"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// CSP 是基于软件实现的BCCSP，所有的密码学操作都依据选项或密钥的反射类型，分派给对应的
+// KeyGenerator、KeyDeriver、KeyImporter、Encryptor、Decryptor、Signer、Verifier、
+// Hasher和Aggregator去完成，因此新增算法只需要通过AddWrapper注册相应的实现即可。
+type CSP struct {
+	ks bccsp.KeyStore
+
+	KeyGenerators map[reflect.Type]KeyGenerator
+	KeyDerivers   map[reflect.Type]KeyDeriver
+	KeyImporters  map[reflect.Type]KeyImporter
+	Encryptors    map[reflect.Type]Encryptor
+	Decryptors    map[reflect.Type]Decryptor
+	Signers       map[reflect.Type]Signer
+	Verifiers     map[reflect.Type]Verifier
+	Hashers       map[reflect.Type]Hasher
+	Aggregators   map[reflect.Type]Aggregator
+}
+
+// 确保CSP实现了bccsp.BCCSP、bccsp.KeyExporter和bccsp.BatchVerifier接口。
+var (
+	_ bccsp.BCCSP         = (*CSP)(nil)
+	_ bccsp.KeyExporter   = (*CSP)(nil)
+	_ bccsp.BatchVerifier = (*CSP)(nil)
+)
+
+// New 创建一个不包含任何算法实现的CSP，算法实现需要通过AddWrapper注册进来。
+func New(keyStore bccsp.KeyStore) (*CSP, error) {
+	if keyStore == nil {
+		return nil, errors.New("invalid bccsp.KeyStore instance, it must be different from nil")
+	}
+
+	return &CSP{
+		ks:            keyStore,
+		KeyGenerators: make(map[reflect.Type]KeyGenerator),
+		KeyDerivers:   make(map[reflect.Type]KeyDeriver),
+		KeyImporters:  make(map[reflect.Type]KeyImporter),
+		Encryptors:    make(map[reflect.Type]Encryptor),
+		Decryptors:    make(map[reflect.Type]Decryptor),
+		Signers:       make(map[reflect.Type]Signer),
+		Verifiers:     make(map[reflect.Type]Verifier),
+		Hashers:       make(map[reflect.Type]Hasher),
+		Aggregators:   make(map[reflect.Type]Aggregator),
+	}, nil
+}
+
+// KeyGen 根据选项opts生成一个密钥，如果密钥不是暂时的，则会将其存储到KeyStore中。
+func (csp *CSP) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts, it must not be nil")
+	}
+
+	keyGenerator, found := csp.KeyGenerators[reflect.TypeOf(opts)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'KeyGenOpts' provided [%v]", opts)
+	}
+
+	k, err = keyGenerator.KeyGen(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating key with opts [%v]: [%w]", opts, err)
+	}
+
+	if !opts.Ephemeral() {
+		if err = csp.ks.StoreKey(k); err != nil {
+			return nil, fmt.Errorf("failed storing key [%s]: [%w]", opts.Algorithm(), err)
+		}
+	}
+
+	return k, nil
+}
+
+// KeyDeriv 根据选项opts从密钥k派生出一个新的密钥，如果派生出的密钥不是暂时的，则会将其存储到KeyStore中。
+func (csp *CSP) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (dk bccsp.Key, err error) {
+	if k == nil {
+		return nil, errors.New("invalid key, it must not be nil")
+	}
+	if opts == nil {
+		return nil, errors.New("invalid opts, it must not be nil")
+	}
+
+	keyDeriver, found := csp.KeyDerivers[reflect.TypeOf(k)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'Key' provided [%v]", k)
+	}
+
+	dk, err = keyDeriver.KeyDeriv(k, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed deriving key with opts [%v]: [%w]", opts, err)
+	}
+
+	if !opts.Ephemeral() {
+		if err = csp.ks.StoreKey(dk); err != nil {
+			return nil, fmt.Errorf("failed storing key [%s]: [%w]", opts.Algorithm(), err)
+		}
+	}
+
+	return dk, nil
+}
+
+// KeyImport 根据选项opts从原材料raw中导入一个密钥，如果导入的密钥不是暂时的，则会将其存储到KeyStore中。
+func (csp *CSP) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.Key, err error) {
+	if raw == nil {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+	if opts == nil {
+		return nil, errors.New("invalid opts, it must not be nil")
+	}
+
+	keyImporter, found := csp.KeyImporters[reflect.TypeOf(opts)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'KeyImportOpts' provided [%v]", opts)
+	}
+
+	k, err = keyImporter.KeyImport(raw, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed importing key with opts [%v]: [%w]", opts, err)
+	}
+
+	if !opts.Ephemeral() {
+		if err = csp.ks.StoreKey(k); err != nil {
+			return nil, fmt.Errorf("failed storing imported key with opts [%v]: [%w]", opts, err)
+		}
+	}
+
+	return k, nil
+}
+
+// GetKey 从KeyStore中获取与ski相关联的密钥。
+func (csp *CSP) GetKey(ski []byte) (k bccsp.Key, err error) {
+	k, err = csp.ks.GetKey(ski)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting key for SKI [%x]: [%w]", ski, err)
+	}
+
+	return k, nil
+}
+
+// Hash 根据选项opts计算消息msg的哈希值，如果opts为空，则使用默认的哈希算法。
+func (csp *CSP) Hash(msg []byte, opts bccsp.HashOpts) (digest []byte, err error) {
+	if opts == nil {
+		opts = &bccsp.SHAOpts{}
+	}
+
+	hasher, found := csp.Hashers[reflect.TypeOf(opts)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'HashOpt' provided [%v]", opts)
+	}
+
+	digest, err = hasher.Hash(msg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed hashing with opts [%v]: [%w]", opts, err)
+	}
+
+	return digest, nil
+}
+
+// GetHash 根据选项opts返回一个hash.Hash实例，如果opts为空，则返回默认的哈希函数。
+func (csp *CSP) GetHash(opts bccsp.HashOpts) (h hash.Hash, err error) {
+	if opts == nil {
+		opts = &bccsp.SHAOpts{}
+	}
+
+	hasher, found := csp.Hashers[reflect.TypeOf(opts)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'HashOpt' provided [%v]", opts)
+	}
+
+	h, err = hasher.GetHash(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting hash function with opts [%v]: [%w]", opts, err)
+	}
+
+	return h, nil
+}
+
+// Sign 利用密钥k对摘要digest进行签名，签名算法由密钥的类型决定。
+func (csp *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
+	if k == nil {
+		return nil, errors.New("invalid key, it must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("invalid digest, cannot be empty")
+	}
+
+	keyType := reflect.TypeOf(k)
+	signer, found := csp.Signers[keyType]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'SignKey' provided [%s]", keyType)
+	}
+
+	signature, err = signer.Sign(k, digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed signing with opts [%v]: [%w]", opts, err)
+	}
+
+	return signature, nil
+}
+
+// Verify 利用密钥k验证摘要digest的签名signature是否有效。
+func (csp *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (valid bool, err error) {
+	if k == nil {
+		return false, errors.New("invalid key, it must not be nil")
+	}
+	if len(signature) == 0 {
+		return false, errors.New("invalid signature, cannot be empty")
+	}
+	if len(digest) == 0 {
+		return false, errors.New("invalid digest, cannot be empty")
+	}
+
+	verifier, found := csp.Verifiers[reflect.TypeOf(k)]
+	if !found {
+		return false, fmt.Errorf("unsupported 'VerifyKey' provided [%v]", k)
+	}
+
+	valid, err = verifier.Verify(k, signature, digest, opts)
+	if err != nil {
+		return false, fmt.Errorf("failed verifing with opts [%v]: [%w]", opts, err)
+	}
+
+	return valid, nil
+}
+
+// Encrypt 利用密钥k对明文plaintext进行加密，加密模式由选项opts决定。
+func (csp *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) (ciphertext []byte, err error) {
+	if k == nil {
+		return nil, errors.New("invalid key, it must not be nil")
+	}
+
+	encryptor, found := csp.Encryptors[reflect.TypeOf(k)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'EncryptKey' provided [%v]", k)
+	}
+
+	return encryptor.Encrypt(k, plaintext, opts)
+}
+
+// Decrypt 利用密钥k对密文ciphertext进行解密，解密模式由选项opts决定。
+func (csp *CSP) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error) {
+	if k == nil {
+		return nil, errors.New("invalid key, it must not be nil")
+	}
+
+	decryptor, found := csp.Decryptors[reflect.TypeOf(k)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'DecryptKey' provided [%v]", k)
+	}
+
+	plaintext, err = decryptor.Decrypt(k, ciphertext, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed decrypting with opts [%v]: [%w]", opts, err)
+	}
+
+	return plaintext, nil
+}
+
+// AggregateSignatures 根据选项opts将多个签名聚合成一个签名，聚合算法由选项的类型决定。
+func (csp *CSP) AggregateSignatures(signatures [][]byte, opts bccsp.AggregateOpts) (signature []byte, err error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts, it must not be nil")
+	}
+
+	aggregator, found := csp.Aggregators[reflect.TypeOf(opts)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'AggregateOpts' provided [%v]", opts)
+	}
+
+	signature, err = aggregator.AggregateSignatures(signatures, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed aggregating signatures with opts [%v]: [%w]", opts, err)
+	}
+
+	return signature, nil
+}
+
+// AggregatePublicKeys 根据选项opts将多个公钥聚合成一个公钥，聚合后的公钥是暂时的，不会被存储到KeyStore中。
+func (csp *CSP) AggregatePublicKeys(keys []bccsp.Key, opts bccsp.AggregateOpts) (k bccsp.Key, err error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts, it must not be nil")
+	}
+
+	aggregator, found := csp.Aggregators[reflect.TypeOf(opts)]
+	if !found {
+		return nil, fmt.Errorf("unsupported 'AggregateOpts' provided [%v]", opts)
+	}
+
+	k, err = aggregator.AggregatePublicKeys(keys, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed aggregating public keys with opts [%v]: [%w]", opts, err)
+	}
+
+	return k, nil
+}
+
+// FastAggregateVerify 先将keys聚合成一个公钥，再用聚合公钥验证摘要digest的聚合签名signature。对于BLS12-381，
+// 每个公钥的持有证明都必须已经验证过，否则聚合失败。
+func (csp *CSP) FastAggregateVerify(keys []bccsp.Key, signature, digest []byte, opts bccsp.AggregateOpts) (valid bool, err error) {
+	k, err := csp.AggregatePublicKeys(keys, opts)
+	if err != nil {
+		return false, err
+	}
+
+	return csp.Verify(k, signature, digest, nil)
+}
+
+// KeyExport 返回私钥或对称密钥k的未加密的PEM编码，它可以通过PEMKeyImportOpts重新导入。对称密钥只有在可导出时
+// （例如通过HMACDeriveKeyOpts派生的密钥）才能导出，生成、导入或从KeyStore中读取的对称密钥都是不可导出的。软件
+// 实现的私钥没有这样的标记，KeyStore本身就以PEM格式保存它们，因此总是可以导出，调用者需要自行限制谁能调用该方法。
+func (csp *CSP) KeyExport(k bccsp.Key) (raw []byte, err error) {
+	if k == nil {
+		return nil, errors.New("invalid key, it must not be nil")
+	}
+
+	raw, err = keyToPEM(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed exporting key [%x]: [%w]", k.SKI(), err)
+	}
+
+	return raw, nil
+}
+
+// AddWrapper 将算法实现w注册到CSP中，t是选项或密钥的反射类型，w必须实现KeyGenerator、KeyDeriver、
+// KeyImporter、Encryptor、Decryptor、Signer、Verifier、Hasher或Aggregator中的一个接口。
+func (csp *CSP) AddWrapper(t reflect.Type, w interface{}) error {
+	if t == nil {
+		return errors.New("type cannot be nil")
+	}
+	if w == nil {
+		return errors.New("wrapper cannot be nil")
+	}
+
+	switch dt := w.(type) {
+	case KeyGenerator:
+		csp.KeyGenerators[t] = dt
+	case KeyImporter:
+		csp.KeyImporters[t] = dt
+	case KeyDeriver:
+		csp.KeyDerivers[t] = dt
+	case Encryptor:
+		csp.Encryptors[t] = dt
+	case Decryptor:
+		csp.Decryptors[t] = dt
+	case Signer:
+		csp.Signers[t] = dt
+	case Verifier:
+		csp.Verifiers[t] = dt
+	case Hasher:
+		csp.Hashers[t] = dt
+	case Aggregator:
+		csp.Aggregators[t] = dt
+	default:
+		return fmt.Errorf("wrapper type not valid, must be one of: KeyGenerator, KeyDeriver, KeyImporter, Encryptor, Decryptor, Signer, Verifier, Hasher, Aggregator")
+	}
+
+	return nil
+}
@@ -0,0 +1,1554 @@
+package sw
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+	"github.com/232425wxy/lark/bccsp/secp256k1"
+	"github.com/232425wxy/lark/bccsp/utils"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+func newTestCSP(t *testing.T) bccsp.BCCSP {
+	csp, err := NewWithParams(256, bccsp.SHA2, NewDummyKeyStore())
+	require.NoError(t, err)
+	return csp
+}
+
+func TestNewWithParams(t *testing.T) {
+	_, err := NewWithParams(128, bccsp.SHA2, NewDummyKeyStore())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "security level not supported [128]")
+
+	_, err = NewWithParams(256, "SHA8", NewDummyKeyStore())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "hash family not supported [SHA8]")
+
+	_, err = NewWithParams(256, bccsp.SHA2, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid bccsp.KeyStore instance")
+
+	for _, level := range []int{256, 384} {
+		for _, family := range []string{bccsp.SHA2, bccsp.SHA3} {
+			_, err = NewWithParams(level, family, NewDummyKeyStore())
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestKeyGenInvalidInputs(t *testing.T) {
+	csp := newTestCSP(t)
+
+	_, err := csp.KeyGen(nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid opts, it must not be nil")
+
+	_, err = csp.KeyGen(&bccsp.IdemixIssuerKeyGenOpts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported 'KeyGenOpts' provided")
+}
+
+func TestECDSASignVerify(t *testing.T) {
+	csp := newTestCSP(t)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAKeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP224KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP521KeyGenOpts{Temporary: true},
+		&bccsp.ECDSASecp256k1KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		require.True(t, k.Private())
+		require.False(t, k.Symmetric())
+		require.NotEmpty(t, k.SKI())
+
+		_, err = k.Bytes()
+		require.Error(t, err)
+
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+		require.False(t, pk.Private())
+		require.Equal(t, k.SKI(), pk.SKI())
+
+		digest, err := csp.Hash([]byte("hello world"), &bccsp.SHAOpts{})
+		require.NoError(t, err)
+
+		signature, err := csp.Sign(k, digest, nil)
+		require.NoError(t, err)
+
+		// 签名必须是低S值。
+		_, s, err := utils.UnmarshalECDSASignature(signature)
+		require.NoError(t, err)
+		lowS, err := utils.IsLowS(&k.(*ecdsaPrivateKey).privKey.PublicKey, s)
+		require.NoError(t, err)
+		require.True(t, lowS)
+
+		valid, err := csp.Verify(k, signature, digest, nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+
+		valid, err = csp.Verify(pk, signature, digest, nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+
+		valid, err = csp.Verify(pk, signature, []byte("another digest"), nil)
+		require.NoError(t, err)
+		require.False(t, valid)
+	}
+}
+
+func TestECDSAVerifyHighS(t *testing.T) {
+	csp := newTestCSP(t)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("hello world"))
+	signature, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	r, s, err := utils.UnmarshalECDSASignature(signature)
+	require.NoError(t, err)
+	s.Sub(elliptic.P256().Params().N, s)
+	highS, err := utils.MarshalECDSASignature(r, s)
+	require.NoError(t, err)
+
+	_, err = csp.Verify(k, highS, digest[:], nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid S, must be smaller than half the order")
+}
+
+func TestSignVerifyInvalidInputs(t *testing.T) {
+	csp := newTestCSP(t)
+
+	_, err := csp.Sign(nil, []byte{1}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid key, it must not be nil")
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	_, err = csp.Sign(k, nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid digest, cannot be empty")
+
+	_, err = csp.Verify(k, nil, []byte{1}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signature, cannot be empty")
+
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = csp.Sign(aesKey, []byte{1}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported 'SignKey' provided")
+}
+
+func TestECDSAKeyImport(t *testing.T) {
+	csp := newTestCSP(t)
+
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	k, err := csp.KeyImport(&lowLevelKey.PublicKey, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.False(t, k.Private())
+
+	der, err := k.Bytes()
+	require.NoError(t, err)
+
+	k2, err := csp.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k2.SKI())
+
+	_, err = csp.KeyImport([]byte{0, 1, 2}, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed converting PKIX to ECDSA public key")
+
+	_, err = csp.KeyImport(lowLevelKey, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid raw material, expected *ecdsa.PublicKey")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "lark"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, template, &lowLevelKey.PublicKey, lowLevelKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certRaw)
+	require.NoError(t, err)
+
+	k3, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k3.SKI())
+}
+
+func TestSecp256k1KeyImport(t *testing.T) {
+	csp := newTestCSP(t)
+
+	lowLevelKey, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+	require.NoError(t, err)
+	pub := &lowLevelKey.PublicKey
+
+	k, err := csp.KeyImport(pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+
+	// PKIX格式的公钥通过ECDSAPKIXPublicKeyImportOpts导入。
+	der, err := k.Bytes()
+	require.NoError(t, err)
+	k2, err := csp.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k2.SKI())
+
+	// 钱包使用的非压缩和压缩格式的公钥都可以被导入。
+	uncompressed := elliptic.Marshal(secp256k1.S256(), pub.X, pub.Y)
+	compressed := make([]byte, 33)
+	compressed[0] = byte(2 + pub.Y.Bit(0))
+	pub.X.FillBytes(compressed[1:])
+	for _, raw := range [][]byte{uncompressed, compressed} {
+		k3, err := csp.KeyImport(raw, &bccsp.ECDSASecp256k1PublicKeyImportOpts{Temporary: true})
+		require.NoError(t, err)
+		require.Equal(t, k.SKI(), k3.SKI())
+	}
+
+	_, err = csp.KeyImport([]byte{0x02, 0x01}, &bccsp.ECDSASecp256k1PublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed converting to secp256k1 public key")
+
+	// 钱包产生的高S值签名需要先转换成低S值的签名才能通过验证。
+	digest := sha256.Sum256([]byte("hello world"))
+	r, s, err := ecdsa.Sign(rand.Reader, lowLevelKey, digest[:])
+	require.NoError(t, err)
+	if lowS, _ := utils.IsLowS(pub, s); lowS {
+		s.Sub(secp256k1.S256().Params().N, s)
+	}
+	highS, err := utils.MarshalECDSASignature(r, s)
+	require.NoError(t, err)
+
+	_, err = csp.Verify(k, highS, digest[:], nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid S, must be smaller than half the order")
+
+	lowS, err := utils.SignatureToLowS(pub, highS)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, lowS, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+func TestEd25519SignVerify(t *testing.T) {
+	csp := newTestCSP(t)
+
+	k, err := csp.KeyGen(&bccsp.Ed25519KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	require.True(t, k.Private())
+	require.False(t, k.Symmetric())
+	_, err = k.Bytes()
+	require.Error(t, err)
+
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	require.False(t, pk.Private())
+	require.Equal(t, k.SKI(), pk.SKI())
+
+	// Ed25519直接对消息进行签名，不需要预先计算哈希值。
+	msg := []byte("hello world")
+	signature, err := csp.Sign(k, msg, nil)
+	require.NoError(t, err)
+	require.Len(t, signature, ed25519.SignatureSize)
+	require.True(t, ed25519.Verify(*pk.(*ed25519PublicKey).pubKey, msg, signature))
+
+	valid, err := csp.Verify(k, signature, msg, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	valid, err = csp.Verify(pk, signature, msg, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	valid, err = csp.Verify(pk, signature, []byte("another message"), nil)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	valid, err = csp.Verify(pk, signature[:32], msg, nil)
+	require.NoError(t, err)
+	require.False(t, valid)
+}
+
+func TestEd25519KeyImport(t *testing.T) {
+	csp := newTestCSP(t)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	k, err := csp.KeyImport(pub, &bccsp.Ed25519GoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.False(t, k.Private())
+
+	der, err := k.Bytes()
+	require.NoError(t, err)
+
+	k2, err := csp.KeyImport(der, &bccsp.Ed25519PKIXPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k2.SKI())
+
+	valid, err := csp.Verify(k2, ed25519.Sign(priv, []byte("msg")), []byte("msg"), nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	_, err = csp.KeyImport(pub[:16], &bccsp.Ed25519GoPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	_, err = csp.KeyImport(priv, &bccsp.Ed25519GoPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid raw material, expected ed25519.PublicKey")
+
+	// ECDSA公钥不能以Ed25519的选项导入。
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaDER, err := x509.MarshalPKIXPublicKey(&ecdsaKey.PublicKey)
+	require.NoError(t, err)
+	_, err = csp.KeyImport(ecdsaDER, &bccsp.Ed25519PKIXPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed casting to Ed25519 public key")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "lark"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certRaw)
+	require.NoError(t, err)
+
+	k3, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k3.SKI())
+}
+
+func TestRSAKeyImportAndVerify(t *testing.T) {
+	csp := newTestCSP(t)
+
+	lowLevelKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	k, err := csp.KeyImport(&lowLevelKey.PublicKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.False(t, k.Private())
+	require.False(t, k.Symmetric())
+
+	der, err := k.Bytes()
+	require.NoError(t, err)
+	k2, err := csp.KeyImport(der, &bccsp.RSAPKIXPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k2.SKI())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "lark"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, template, &lowLevelKey.PublicKey, lowLevelKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certRaw)
+	require.NoError(t, err)
+	k3, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k3.SKI())
+
+	digest := sha256.Sum256([]byte("hello world"))
+
+	// PKCS#1 v1.5签名。
+	signature, err := rsa.SignPKCS1v15(rand.Reader, lowLevelKey, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, signature, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = csp.Verify(k, signature, digest[:], &rsa.PSSOptions{Hash: crypto.SHA256})
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	// PSS签名。
+	pssOpts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	signature, err = rsa.SignPSS(rand.Reader, lowLevelKey, crypto.SHA256, digest[:], pssOpts)
+	require.NoError(t, err)
+	valid, err = csp.Verify(k3, signature, digest[:], pssOpts)
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = csp.Verify(k3, signature, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	other := sha256.Sum256([]byte("another message"))
+	valid, err = csp.Verify(k3, signature, other[:], pssOpts)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	_, err = csp.Verify(k, signature, digest[:], nil)
+	require.Error(t, err)
+	_, err = csp.Verify(k, signature, digest[:], &rsa.PSSOptions{})
+	require.Error(t, err)
+	_, err = csp.Verify(k, signature, digest[:16], crypto.SHA256)
+	require.Error(t, err)
+
+	// 公钥无法用于签名。
+	_, err = csp.Sign(k, digest[:], crypto.SHA256)
+	require.Error(t, err)
+
+	_, err = csp.KeyImport(lowLevelKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid raw material, expected *rsa.PublicKey")
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaDER, err := x509.MarshalPKIXPublicKey(&ecdsaKey.PublicKey)
+	require.NoError(t, err)
+	_, err = csp.KeyImport(ecdsaDER, &bccsp.RSAPKIXPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed casting to RSA public key")
+}
+
+func TestSM2SignVerify(t *testing.T) {
+	csp := newTestCSP(t)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	require.True(t, k.Private())
+	require.False(t, k.Symmetric())
+	_, err = k.Bytes()
+	require.Error(t, err)
+
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	require.False(t, pk.Private())
+	require.Equal(t, k.SKI(), pk.SKI())
+
+	// 使用SM2SignerOpts时，传入的是原始的消息，Z值由BCCSP计算。
+	msg := []byte("hello world")
+	opts := &bccsp.SM2SignerOpts{UID: []byte("ALICE123@YAHOO.COM")}
+	signature, err := csp.Sign(k, msg, opts)
+	require.NoError(t, err)
+
+	valid, err := csp.Verify(pk, signature, msg, opts)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	valid, err = csp.Verify(k, signature, msg, &bccsp.SM2SignerOpts{})
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	// 不使用SM2SignerOpts时，传入的是已经计算好的摘要e = SM3(ZA || M)。
+	e, err := gm.MessageDigest(pk.(*sm2PublicKey).pubKey, opts.UID, msg)
+	require.NoError(t, err)
+	valid, err = csp.Verify(pk, signature, e, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	signature, err = csp.Sign(k, e, nil)
+	require.NoError(t, err)
+	valid, err = csp.Verify(pk, signature, msg, opts)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	valid, err = csp.Verify(pk, signature, []byte("another message"), opts)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	_, err = csp.Verify(pk, []byte{0, 1, 2}, msg, opts)
+	require.Error(t, err)
+}
+
+func TestSM2KeyImport(t *testing.T) {
+	csp := newTestCSP(t)
+
+	lowLevelKey, err := gm.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	k, err := csp.KeyImport(&lowLevelKey.PublicKey, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.False(t, k.Private())
+
+	der, err := k.Bytes()
+	require.NoError(t, err)
+	k2, err := csp.KeyImport(der, &bccsp.SM2PKIXPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), k2.SKI())
+
+	// SM2证书只能以DER编码的形式导入。
+	k3, err := csp.KeyImport(sm2Certificate(t, der), &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.IsType(t, &sm2PublicKey{}, k3)
+	require.Equal(t, k.SKI(), k3.SKI())
+
+	_, err = csp.KeyImport([]byte{0, 1, 2}, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+
+	// 其他曲线上的公钥不能以SM2的选项导入。
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = csp.KeyImport(&ecdsaKey.PublicKey, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	ecdsaDER, err := x509.MarshalPKIXPublicKey(&ecdsaKey.PublicKey)
+	require.NoError(t, err)
+	_, err = csp.KeyImport(ecdsaDER, &bccsp.SM2PKIXPublicKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+}
+
+// sm2Certificate 构造一个公钥为spki的自签名证书的DER编码，证书的签名没有意义，只用于测试公钥的导入。
+func sm2Certificate(t *testing.T, spki []byte) []byte {
+	name, err := asn1.Marshal(pkix.Name{CommonName: "lark"}.ToRDNSequence())
+	require.NoError(t, err)
+	validity, err := asn1.Marshal(struct{ NotBefore, NotAfter time.Time }{time.Now(), time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	sigAlg := pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}}
+
+	tbs, err := asn1.Marshal(struct {
+		Version            int `asn1:"explicit,tag:0"`
+		SerialNumber       *big.Int
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Issuer             asn1.RawValue
+		Validity           asn1.RawValue
+		Subject            asn1.RawValue
+		PublicKey          asn1.RawValue
+	}{2, big.NewInt(1), sigAlg, asn1.RawValue{FullBytes: name}, asn1.RawValue{FullBytes: validity}, asn1.RawValue{FullBytes: name}, asn1.RawValue{FullBytes: spki}})
+	require.NoError(t, err)
+
+	cert, err := asn1.Marshal(struct {
+		TBSCertificate     asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		SignatureValue     asn1.BitString
+	}{asn1.RawValue{FullBytes: tbs}, sigAlg, asn1.BitString{Bytes: []byte{0}, BitLength: 8}})
+	require.NoError(t, err)
+	return cert
+}
+
+func TestBLSSignVerify(t *testing.T) {
+	csp := newTestCSP(t)
+
+	// 以太坊共识层BLS签名的测试向量，消息是32字节的0。
+	sk, _ := hex.DecodeString("263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3")
+	expectedPK, _ := hex.DecodeString("a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a")
+	expectedSig, _ := hex.DecodeString("b6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55")
+
+	k, err := csp.KeyImport(sk, &bccsp.BLS12381PrivateKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.True(t, k.Private())
+	require.False(t, k.Symmetric())
+	_, err = k.Bytes()
+	require.Error(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	require.Equal(t, k.SKI(), pk.SKI())
+	raw, err := pk.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, expectedPK, raw)
+
+	digest := make([]byte, 32)
+	signature, err := csp.Sign(k, digest, nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedSig, signature)
+
+	valid, err := csp.Verify(k, signature, digest, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = csp.Verify(pk, signature, digest, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = csp.Verify(pk, signature, []byte("another digest"), nil)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	_, err = csp.Verify(pk, signature[:48], digest, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signature length [48], must be 96 bytes")
+	infinity := make([]byte, blsSignatureSize)
+	infinity[0] = 0xc0
+	_, err = csp.Verify(pk, infinity, digest, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signature, point at infinity")
+
+	// 持有证明对公钥签名，与普通签名使用不同的域分隔标签。
+	pop, err := csp.Sign(k, raw, &bccsp.BLS12381ProofOfPossessionOpts{})
+	require.NoError(t, err)
+	valid, err = csp.Verify(pk, pop, raw, &bccsp.BLS12381ProofOfPossessionOpts{})
+	require.NoError(t, err)
+	require.True(t, valid)
+	sig, err := csp.Sign(k, raw, nil)
+	require.NoError(t, err)
+	require.NotEqual(t, pop, sig)
+	valid, err = csp.Verify(pk, sig, raw, &bccsp.BLS12381ProofOfPossessionOpts{})
+	require.NoError(t, err)
+	require.False(t, valid)
+	_, err = csp.Sign(k, digest, &bccsp.BLS12381ProofOfPossessionOpts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "proof of possession must be computed over the public key")
+}
+
+func TestBLSKeyImport(t *testing.T) {
+	csp := newTestCSP(t)
+
+	k, err := csp.KeyGen(&bccsp.BLS12381KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	raw, err := pk.Bytes()
+	require.NoError(t, err)
+	require.Len(t, raw, blsPublicKeySize)
+
+	pk2, err := csp.KeyImport(raw, &bccsp.BLS12381PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	require.False(t, pk2.Private())
+	require.Equal(t, pk.SKI(), pk2.SKI())
+
+	// 没有持有证明的公钥不能被聚合，带着持有证明导入的公钥可以。
+	opts := &bccsp.BLS12381AggregateOpts{}
+	_, err = csp.AggregatePublicKeys([]bccsp.Key{k, pk2}, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid key at index [1], its proof of possession has not been verified")
+	pop, err := csp.Sign(k, raw, &bccsp.BLS12381ProofOfPossessionOpts{})
+	require.NoError(t, err)
+	pk3, err := csp.KeyImport(raw, &bccsp.BLS12381PublicKeyImportOpts{Temporary: true, ProofOfPossession: pop})
+	require.NoError(t, err)
+	_, err = csp.AggregatePublicKeys([]bccsp.Key{k, pk3}, opts)
+	require.NoError(t, err)
+
+	// 对消息的签名或者其他公钥的持有证明都不是合法的持有证明。
+	digest := sha256.Sum256([]byte("hello world"))
+	sig, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	other, err := csp.KeyGen(&bccsp.BLS12381KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	otherPK, err := other.PublicKey()
+	require.NoError(t, err)
+	otherPop := otherPK.(*blsPublicKey).pop
+
+	for _, tc := range []struct {
+		raw      interface{}
+		opts     bccsp.KeyImportOpts
+		errorMsg string
+	}{
+		{"not bytes", &bccsp.BLS12381PublicKeyImportOpts{}, "invalid raw material, expected byte array"},
+		{raw[:32], &bccsp.BLS12381PublicKeyImportOpts{}, "invalid BLS12-381 public key length [32], must be 48 bytes"},
+		{append([]byte{0xc0}, make([]byte, 47)...), &bccsp.BLS12381PublicKeyImportOpts{}, "invalid BLS12-381 public key, point at infinity"},
+		{make([]byte, 31), &bccsp.BLS12381PrivateKeyImportOpts{}, "invalid BLS12-381 private key length [31], must be 32 bytes"},
+		{make([]byte, 32), &bccsp.BLS12381PrivateKeyImportOpts{}, "invalid BLS12-381 private key, it must be in range [1, r-1]"},
+		{raw, &bccsp.BLS12381PublicKeyImportOpts{ProofOfPossession: sig}, "invalid proof of possession"},
+		{raw, &bccsp.BLS12381PublicKeyImportOpts{ProofOfPossession: otherPop}, "invalid proof of possession"},
+		{raw, &bccsp.BLS12381PublicKeyImportOpts{ProofOfPossession: []byte{1, 2, 3}}, "failed verifying proof of possession"},
+	} {
+		_, err = csp.KeyImport(tc.raw, tc.opts)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), tc.errorMsg)
+	}
+}
+
+func TestBLSAggregate(t *testing.T) {
+	csp := newTestCSP(t)
+	opts := &bccsp.BLS12381AggregateOpts{}
+	digest := sha256.Sum256([]byte("endorsed transaction"))
+
+	var keys []bccsp.Key
+	var signatures [][]byte
+	for i := 0; i < 16; i++ {
+		k, err := csp.KeyGen(&bccsp.BLS12381KeyGenOpts{Temporary: true})
+		require.NoError(t, err)
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+		signature, err := csp.Sign(k, digest[:], nil)
+		require.NoError(t, err)
+		keys = append(keys, pk)
+		signatures = append(signatures, signature)
+	}
+
+	aggSig, err := csp.AggregateSignatures(signatures, opts)
+	require.NoError(t, err)
+	require.Len(t, aggSig, blsSignatureSize)
+
+	valid, err := csp.FastAggregateVerify(keys, aggSig, digest[:], opts)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// 聚合公钥可以直接交给Verify验证聚合签名。
+	aggPK, err := csp.AggregatePublicKeys(keys, opts)
+	require.NoError(t, err)
+	valid, err = csp.Verify(aggPK, aggSig, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+	_, err = csp.AggregatePublicKeys([]bccsp.Key{aggPK, keys[0]}, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "its proof of possession has not been verified")
+
+	// 缺少任何一个签名者，或者摘要不同，验证都会失败。
+	valid, err = csp.FastAggregateVerify(keys[1:], aggSig, digest[:], opts)
+	require.NoError(t, err)
+	require.False(t, valid)
+	valid, err = csp.FastAggregateVerify(keys, aggSig, []byte("another digest"), opts)
+	require.NoError(t, err)
+	require.False(t, valid)
+	partial, err := csp.AggregateSignatures(signatures[1:], opts)
+	require.NoError(t, err)
+	valid, err = csp.FastAggregateVerify(keys, partial, digest[:], opts)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	_, err = csp.AggregateSignatures(nil, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "at least one signature is required")
+	_, err = csp.AggregateSignatures([][]byte{signatures[0], {1, 2, 3}}, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signature at index [1]")
+	_, err = csp.AggregatePublicKeys(nil, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "at least one key is required")
+	ed25519Key, err := csp.KeyGen(&bccsp.Ed25519KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = csp.AggregatePublicKeys([]bccsp.Key{keys[0], ed25519Key}, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid key at index [1], expected a BLS12-381 key")
+	_, err = csp.AggregateSignatures(signatures, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid opts, it must not be nil")
+}
+
+func TestSchnorrSignVerify(t *testing.T) {
+	csp := newTestCSP(t)
+
+	// BIP-340的测试向量。
+	vectors := []struct {
+		seckey, pubkey, aux, msg, sig string
+	}{
+		{
+			seckey: "0000000000000000000000000000000000000000000000000000000000000003",
+			pubkey: "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			aux:    "0000000000000000000000000000000000000000000000000000000000000000",
+			msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:    "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		},
+		{
+			seckey: "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			pubkey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			aux:    "0000000000000000000000000000000000000000000000000000000000000001",
+			msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig:    "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		},
+	}
+	for _, v := range vectors {
+		d := mustDecodeHex(t, v.seckey)
+		x, y := secp256k1.S256().ScalarBaseMult(d)
+		k := &ecdsaPrivateKey{&ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: secp256k1.S256(), X: x, Y: y}, D: new(big.Int).SetBytes(d)}}
+		msg := mustDecodeHex(t, v.msg)
+
+		signature, err := csp.Sign(k, msg, &bccsp.SchnorrSignerOpts{AuxRand: mustDecodeHex(t, v.aux)})
+		require.NoError(t, err)
+		require.Equal(t, mustDecodeHex(t, v.sig), signature)
+
+		pk, err := csp.KeyImport(mustDecodeHex(t, v.pubkey), &bccsp.SchnorrPublicKeyImportOpts{})
+		require.NoError(t, err)
+		raw, err := pk.Bytes()
+		require.NoError(t, err)
+		require.Equal(t, mustDecodeHex(t, v.pubkey), raw)
+		valid, err := csp.Verify(pk, signature, msg, nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+
+		// ECDSA公钥也可以验证Schnorr签名。
+		valid, err = csp.Verify(k, signature, msg, &bccsp.SchnorrSignerOpts{})
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+
+	// P-256上的签名使用随机的辅助数据。
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("hello schnorr"))
+	signature, err := csp.Sign(k, digest[:], &bccsp.SchnorrSignerOpts{})
+	require.NoError(t, err)
+	require.Len(t, signature, schnorrSignatureSize)
+	valid, err := csp.Verify(pk, signature, digest[:], &bccsp.SchnorrSignerOpts{})
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = csp.Verify(pk, signature, []byte("another digest"), &bccsp.SchnorrSignerOpts{})
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	// 不带SchnorrSignerOpts时Schnorr签名不是合法的ECDSA签名。
+	_, err = csp.Verify(pk, signature, digest[:], nil)
+	require.Error(t, err)
+
+	xonly := scalarBytes(pk.(*ecdsaPublicKey).pubKey.X)
+	xk, err := csp.KeyImport(xonly, &bccsp.SchnorrPublicKeyImportOpts{Curve: "P-256"})
+	require.NoError(t, err)
+	valid, err = csp.Verify(xk, signature, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	p384Key, err := csp.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = csp.Sign(p384Key, digest[:], &bccsp.SchnorrSignerOpts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "curve [P-384] not supported")
+	_, err = csp.Sign(k, digest[:], &bccsp.SchnorrSignerOpts{AuxRand: []byte{1}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid auxiliary randomness length [1]")
+	_, err = csp.KeyImport(xonly, &bccsp.SchnorrPublicKeyImportOpts{Curve: "P-384"})
+	require.Error(t, err)
+	_, err = csp.KeyImport(xonly[1:], &bccsp.SchnorrPublicKeyImportOpts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid x-only public key length [31]")
+}
+
+func TestMuSig2KeyAgg(t *testing.T) {
+	csp := newTestCSP(t)
+
+	// BIP-327的KeyAgg测试向量。
+	var keys []bccsp.Key
+	for _, raw := range []string{
+		"02F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		"03DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"023590A94E768F8E1815C2F24B4D80A8E3149316C3518CE7B7AD338368D038CA66",
+	} {
+		k, err := csp.KeyImport(mustDecodeHex(t, raw), &bccsp.ECDSASecp256k1PublicKeyImportOpts{Temporary: true})
+		require.NoError(t, err)
+		keys = append(keys, k)
+	}
+
+	for _, tc := range []struct {
+		indices  []int
+		expected string
+	}{
+		{[]int{0, 1, 2}, "90539EEDE565F5D054F32CC0C220126889ED1E5D193BAF15AEF344FE59D4610C"},
+		{[]int{2, 1, 0}, "6204DE8B083426DC6EAF9502D27024D53FC826BF7D2012148A0575435DF54B2B"},
+		{[]int{0, 0, 0}, "B436E3BAD62B8CD409969A224731C193D051162D8C5AE8B109306127DA3AA935"},
+	} {
+		var ks []bccsp.Key
+		for _, i := range tc.indices {
+			ks = append(ks, keys[i])
+		}
+		aggPK, err := csp.AggregatePublicKeys(ks, &bccsp.MuSig2AggregateOpts{})
+		require.NoError(t, err)
+		raw, err := aggPK.Bytes()
+		require.NoError(t, err)
+		require.Equal(t, mustDecodeHex(t, tc.expected), raw)
+	}
+}
+
+func TestMuSig2NonceGen(t *testing.T) {
+	// BIP-327 NonceGen的测试向量，期望值是k1 || k2。
+	for i, tc := range []struct {
+		sk, pk, aggpk, msg, extraIn, expected string
+		noMsg                                 bool
+	}{
+		{
+			sk:       "0202020202020202020202020202020202020202020202020202020202020202",
+			pk:       "024D4B6CD1361032CA9BD2AEB9D900AA4D45D9EAD80AC9423374C451A7254D0766",
+			aggpk:    "0707070707070707070707070707070707070707070707070707070707070707",
+			msg:      "0101010101010101010101010101010101010101010101010101010101010101",
+			extraIn:  "0808080808080808080808080808080808080808080808080808080808080808",
+			expected: "227243DCB40EF2A13A981DB188FA433717B506BDFA14B1AE47D5DC027C9C3B9EF2370B2AD206E724243215137C86365699361126991E6FEC816845F837BDDAC3",
+		},
+		{
+			sk:       "0202020202020202020202020202020202020202020202020202020202020202",
+			pk:       "024D4B6CD1361032CA9BD2AEB9D900AA4D45D9EAD80AC9423374C451A7254D0766",
+			aggpk:    "0707070707070707070707070707070707070707070707070707070707070707",
+			extraIn:  "0808080808080808080808080808080808080808080808080808080808080808",
+			expected: "CD0F47FE471D6788FF3243F47345EA0A179AEF69476BE8348322EF39C2723318870C2065AFB52DEDF02BF4FDBF6D2F442E608692F50C2374C08FFFE57042A61C",
+		},
+		{
+			sk:       "0202020202020202020202020202020202020202020202020202020202020202",
+			pk:       "024D4B6CD1361032CA9BD2AEB9D900AA4D45D9EAD80AC9423374C451A7254D0766",
+			aggpk:    "0707070707070707070707070707070707070707070707070707070707070707",
+			msg:      "2626262626262626262626262626262626262626262626262626262626262626262626262626",
+			extraIn:  "0808080808080808080808080808080808080808080808080808080808080808",
+			expected: "011F8BC60EF061DEEF4D72A0A87200D9994B3F0CD9867910085C38D5366E3E6B9FF03BC0124E56B24069E91EC3F162378983F194E8BD0ED89BE3059649EAE262",
+		},
+		{
+			pk:       "02F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			noMsg:    true,
+			expected: "890E83616A3BC4640AB9B6374F21C81FF89CDDDBAFAA7475AE2A102A92E3EDB29FD7E874E23342813A60D9646948242646B7951CA046B4B36D7D6078506D3C94",
+		},
+	} {
+		var msg []byte
+		if !tc.noMsg {
+			msg = mustDecodeHex(t, tc.msg)
+		}
+		k1, k2 := musig2NonceGen(secp256k1.S256(), make([]byte, 32), mustDecodeHex(t, tc.sk), mustDecodeHex(t, tc.pk),
+			mustDecodeHex(t, tc.aggpk), msg, mustDecodeHex(t, tc.extraIn))
+		require.Equal(t, tc.expected, fmt.Sprintf("%X%X", scalarBytes(k1), scalarBytes(k2)), "vector %d", i)
+	}
+
+	csp := newTestCSP(t)
+	k, err := csp.KeyGen(&bccsp.ECDSASecp256k1KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	_, err = csp.KeyGen(&bccsp.MuSig2NonceGenOpts{PublicKey: k, AggregateKey: pk})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected a 32 bytes x-only public key")
+}
+
+func TestMuSig2SignVerify(t *testing.T) {
+	csp := newTestCSP(t)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSASecp256k1KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+	} {
+		digest := sha256.Sum256([]byte("3-of-3 multisig"))
+
+		var keys, publicKeys []bccsp.Key
+		for i := 0; i < 3; i++ {
+			k, err := csp.KeyGen(opts)
+			require.NoError(t, err)
+			pk, err := k.PublicKey()
+			require.NoError(t, err)
+			keys = append(keys, k)
+			publicKeys = append(publicKeys, pk)
+		}
+
+		var sessions []*bccsp.MuSig2Session
+		var nonces [][]byte
+		for _, k := range keys {
+			session, err := bccsp.NewMuSig2Session(csp, k, publicKeys, digest[:])
+			require.NoError(t, err)
+			nonce, err := session.PublicNonce()
+			require.NoError(t, err)
+			require.Len(t, nonce, musig2NonceSize)
+			sessions = append(sessions, session)
+			nonces = append(nonces, nonce)
+		}
+
+		var partials [][]byte
+		for _, session := range sessions {
+			partial, err := session.Sign(nonces)
+			require.NoError(t, err)
+			partials = append(partials, partial)
+		}
+		for i, partial := range partials {
+			valid, err := sessions[0].VerifyPartial(i, partial)
+			require.NoError(t, err)
+			require.True(t, valid)
+		}
+
+		signature, err := sessions[1].Aggregate(partials)
+		require.NoError(t, err)
+		require.Len(t, signature, schnorrSignatureSize)
+
+		// 聚合签名是普通的BIP-340签名，可以用聚合公钥或者它的x-only编码验证。
+		aggPK, err := sessions[2].AggregatePublicKey()
+		require.NoError(t, err)
+		valid, err := csp.Verify(aggPK, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+		valid, err = csp.FastAggregateVerify(publicKeys, signature, digest[:], &bccsp.MuSig2AggregateOpts{})
+		require.NoError(t, err)
+		require.True(t, valid)
+		xonly, err := aggPK.Bytes()
+		require.NoError(t, err)
+		curve := keys[0].(*ecdsaPrivateKey).privKey.Params().Name
+		xk, err := csp.KeyImport(xonly, &bccsp.SchnorrPublicKeyImportOpts{Curve: curve})
+		require.NoError(t, err)
+		valid, err = csp.Verify(xk, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+
+		// nonce在签名以后失效。
+		_, err = sessions[0].Sign(nonces)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "it has already been used")
+
+		// 篡改的部分签名无法通过验证，聚合以后的签名也不合法。
+		tampered := utils.Clone(partials[1])
+		tampered[31] ^= 1
+		valid, err = sessions[0].VerifyPartial(1, tampered)
+		require.NoError(t, err)
+		require.False(t, valid)
+		valid, err = sessions[0].VerifyPartial(2, partials[1])
+		require.NoError(t, err)
+		require.False(t, valid)
+		bad, err := sessions[0].Aggregate([][]byte{partials[0], tampered, partials[2]})
+		require.NoError(t, err)
+		valid, err = csp.Verify(aggPK, bad, digest[:], nil)
+		require.NoError(t, err)
+		require.False(t, valid)
+
+		_, err = sessions[0].Aggregate(partials[1:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expected [3] signatures, but got [2]")
+	}
+}
+
+func TestMuSig2InvalidInputs(t *testing.T) {
+	csp := newTestCSP(t)
+	digest := sha256.Sum256([]byte("msg"))
+
+	k1, err := csp.KeyGen(&bccsp.ECDSASecp256k1KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	k2, err := csp.KeyGen(&bccsp.ECDSASecp256k1KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	p256Key, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	p384Key, err := csp.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	_, err = csp.KeyGen(&bccsp.MuSig2NonceGenOpts{PublicKey: p384Key})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "curve [P-384] not supported")
+	_, err = csp.AggregatePublicKeys([]bccsp.Key{k1, p256Key}, &bccsp.MuSig2AggregateOpts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid public key at index [1]")
+
+	n1, err := csp.KeyGen(&bccsp.MuSig2NonceGenOpts{PublicKey: k1})
+	require.NoError(t, err)
+	n2, err := csp.KeyGen(&bccsp.MuSig2NonceGenOpts{PublicKey: k2})
+	require.NoError(t, err)
+	pn1, err := n1.PublicKey()
+	require.NoError(t, err)
+	pn2, err := n2.PublicKey()
+	require.NoError(t, err)
+	raw1, err := pn1.Bytes()
+	require.NoError(t, err)
+	raw2, err := pn2.Bytes()
+	require.NoError(t, err)
+	_, err = n1.Bytes()
+	require.Error(t, err)
+
+	keys := []bccsp.Key{k1, k2}
+	nonces := [][]byte{raw1, raw2}
+
+	// 签名者的nonce必须是为自己生成的，并且出现在会话中。签名失败也会消耗nonce，所以每次都使用新的nonce。
+	for _, tc := range []struct {
+		opts     func(n bccsp.Key, raw []byte) *bccsp.MuSig2SignerOpts
+		errorMsg string
+	}{
+		{func(n bccsp.Key, raw []byte) *bccsp.MuSig2SignerOpts {
+			return &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: nonces, Nonce: n2}
+		}, "it was generated for another key"},
+		{func(n bccsp.Key, raw []byte) *bccsp.MuSig2SignerOpts {
+			return &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: [][]byte{raw2, raw}, Nonce: n}
+		}, "not part of the session"},
+		{func(n bccsp.Key, raw []byte) *bccsp.MuSig2SignerOpts {
+			return &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: [][]byte{raw}, Nonce: n}
+		}, "expected [2] nonces, but got [1]"},
+		{func(n bccsp.Key, raw []byte) *bccsp.MuSig2SignerOpts {
+			return &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: [][]byte{raw, raw2[1:]}, Nonce: n}
+		}, "invalid public nonce length [65] at index [1]"},
+		{func(n bccsp.Key, raw []byte) *bccsp.MuSig2SignerOpts {
+			return &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: [][]byte{raw, raw2}, Nonce: k2}
+		}, "expected a MuSig2 secret nonce"},
+	} {
+		n, err := csp.KeyGen(&bccsp.MuSig2NonceGenOpts{PublicKey: k1})
+		require.NoError(t, err)
+		pn, err := n.PublicKey()
+		require.NoError(t, err)
+		raw, err := pn.Bytes()
+		require.NoError(t, err)
+		_, err = csp.Sign(k1, digest[:], tc.opts(n, raw))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), tc.errorMsg)
+	}
+
+	// 签名失败以后nonce同样失效。
+	_, err = csp.Sign(k1, digest[:], &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: nonces[:1], Nonce: n1})
+	require.Error(t, err)
+	_, err = csp.Sign(k1, digest[:], &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: nonces, Nonce: n1})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "it has already been used")
+
+	// 并发地用同一个nonce签名时，只有一次签名能够成功。
+	n3, err := csp.KeyGen(&bccsp.MuSig2NonceGenOpts{PublicKey: k1})
+	require.NoError(t, err)
+	pn3, err := n3.PublicKey()
+	require.NoError(t, err)
+	raw3, err := pn3.Bytes()
+	require.NoError(t, err)
+	var (
+		wg        sync.WaitGroup
+		succeeded int32
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := csp.Sign(k1, digest[:], &bccsp.MuSig2SignerOpts{PublicKeys: keys, PublicNonces: [][]byte{raw3, raw2}, Nonce: n3}); err == nil {
+				atomic.AddInt32(&succeeded, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), succeeded)
+
+	_, err = bccsp.NewMuSig2Session(csp, p256Key, nil, digest[:])
+	require.Error(t, err)
+	pk, err := k1.PublicKey()
+	require.NoError(t, err)
+	_, err = bccsp.NewMuSig2Session(csp, pk, keys, digest[:])
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "it must be a private key")
+	session, err := bccsp.NewMuSig2Session(csp, k1, keys, digest[:])
+	require.NoError(t, err)
+	_, err = session.Sign(nonces)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "public nonce has not been generated")
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	raw, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestKeyExportImport(t *testing.T) {
+	csp := newTestCSP(t)
+	exporter, ok := csp.(bccsp.KeyExporter)
+	require.True(t, ok)
+
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	hmacKey, err := csp.KeyDeriv(aesKey, &bccsp.HMACDeriveKeyOpts{Temporary: true, Arg: []byte("backup")})
+	require.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+		&bccsp.X25519KeyGenOpts{Temporary: true},
+		nil,
+	} {
+		k := hmacKey
+		if opts != nil {
+			k, err = csp.KeyGen(opts)
+			require.NoError(t, err)
+		}
+		raw, err := exporter.KeyExport(k)
+		require.NoError(t, err)
+
+		k2, err := csp.KeyImport(raw, &bccsp.PEMKeyImportOpts{Temporary: true})
+		require.NoError(t, err)
+		require.Equal(t, k.SKI(), k2.SKI())
+		require.Equal(t, reflect.TypeOf(k), reflect.TypeOf(k2))
+	}
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	_, err = exporter.KeyExport(pk)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot be exported")
+	_, err = exporter.KeyExport(nil)
+	require.Error(t, err)
+
+	// 不可导出的对称密钥不能被导出。
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.AES256KeyGenOpts{Temporary: true},
+		&bccsp.SM4KeyGenOpts{Temporary: true},
+		&bccsp.ChaCha20Poly1305KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		_, err = exporter.KeyExport(k)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "symmetric key is not exportable")
+	}
+
+	// 加密的PEM不能直接导入。
+	raw, err := privateKeyToPEM(k.(*ecdsaPrivateKey).privKey, []byte("passphrase"), nil)
+	require.NoError(t, err)
+	_, err = csp.KeyImport(raw, &bccsp.PEMKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "PEM block type [ENCRYPTED PRIVATE KEY] not supported")
+	_, err = csp.KeyImport([]byte("not a PEM"), &bccsp.PEMKeyImportOpts{Temporary: true})
+	require.Error(t, err)
+}
+
+func TestAESEncryptDecrypt(t *testing.T) {
+	csp := newTestCSP(t)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.AESKeyGenOpts{Temporary: true},
+		&bccsp.AES128KeyGenOpts{Temporary: true},
+		&bccsp.AES192KeyGenOpts{Temporary: true},
+		&bccsp.AES256KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		require.True(t, k.Symmetric())
+		_, err = k.PublicKey()
+		require.Error(t, err)
+
+		msg := []byte("hello world")
+		ct, err := csp.Encrypt(k, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+		require.NoError(t, err)
+
+		pt, err := csp.Decrypt(k, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+		require.NoError(t, err)
+		require.Equal(t, msg, pt)
+
+		ct, err = csp.Encrypt(k, msg, bccsp.AESCBCPKCS7ModeOpts{PRNG: rand.Reader})
+		require.NoError(t, err)
+		pt, err = csp.Decrypt(k, ct, bccsp.AESCBCPKCS7ModeOpts{})
+		require.NoError(t, err)
+		require.Equal(t, msg, pt)
+	}
+}
+
+func TestAESEncryptWithIV(t *testing.T) {
+	csp := newTestCSP(t)
+
+	raw, err := GetRandomBytes(32)
+	require.NoError(t, err)
+	k, err := csp.KeyImport(raw, &bccsp.AES256ImportKeyOpts{Temporary: true})
+	require.NoError(t, err)
+
+	iv := make([]byte, 16)
+	ct1, err := csp.Encrypt(k, []byte("hello world"), &bccsp.AESCBCPKCS7ModeOpts{IV: iv})
+	require.NoError(t, err)
+	ct2, err := csp.Encrypt(k, []byte("hello world"), &bccsp.AESCBCPKCS7ModeOpts{IV: iv})
+	require.NoError(t, err)
+	require.Equal(t, ct1, ct2)
+	require.Equal(t, iv, ct1[:16])
+
+	_, err = csp.Encrypt(k, []byte("hello world"), &bccsp.AESCBCPKCS7ModeOpts{IV: iv, PRNG: rand.Reader})
+	require.Error(t, err)
+
+	_, err = csp.Encrypt(k, []byte("hello world"), &bccsp.SHAOpts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "mode not recognized")
+
+	_, err = csp.KeyImport(raw[:16], &bccsp.AES256ImportKeyOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid key length [16], must be 32 bytes")
+}
+
+func TestAESGCMEncryptDecrypt(t *testing.T) {
+	csp := newTestCSP(t)
+
+	raw, err := GetRandomBytes(32)
+	require.NoError(t, err)
+	imported, err := csp.KeyImport(raw, &bccsp.AES256ImportKeyOpts{Temporary: true})
+	require.NoError(t, err)
+
+	keys := []bccsp.Key{imported}
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.AES128KeyGenOpts{Temporary: true},
+		&bccsp.AES192KeyGenOpts{Temporary: true},
+		&bccsp.AES256KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		keys = append(keys, k)
+	}
+
+	msg := []byte("hello world")
+	aad := []byte("header")
+	for _, k := range keys {
+		ct, err := csp.Encrypt(k, msg, &bccsp.AESGCMModeOpts{AdditionalData: aad})
+		require.NoError(t, err)
+		require.Len(t, ct, 12+len(msg)+16)
+
+		pt, err := csp.Decrypt(k, ct, &bccsp.AESGCMModeOpts{AdditionalData: aad})
+		require.NoError(t, err)
+		require.Equal(t, msg, pt)
+
+		// 篡改密文或者使用不同的附加数据都会导致认证失败。
+		tampered := utils.Clone(ct)
+		tampered[len(tampered)-1] ^= 1
+		_, err = csp.Decrypt(k, tampered, &bccsp.AESGCMModeOpts{AdditionalData: aad})
+		require.True(t, errors.Is(err, bccsp.ErrAuthenticationFailed))
+		_, err = csp.Decrypt(k, ct, bccsp.AESGCMModeOpts{AdditionalData: []byte("other")})
+		require.True(t, errors.Is(err, bccsp.ErrAuthenticationFailed))
+
+		_, err = csp.Decrypt(k, ct[:20], &bccsp.AESGCMModeOpts{AdditionalData: aad})
+		require.Error(t, err)
+	}
+
+	// 指定nonce时，密文以该nonce开头，并且结果是确定的。
+	nonce := make([]byte, 12)
+	ct1, err := csp.Encrypt(imported, msg, &bccsp.AESGCMModeOpts{Nonce: nonce})
+	require.NoError(t, err)
+	ct2, err := AESGCMEncryptWithNonce(nonce, raw, msg, nil)
+	require.NoError(t, err)
+	require.Equal(t, ct1, ct2)
+	require.Equal(t, nonce, ct1[:12])
+	pt, err := AESGCMDecrypt(raw, ct1, nil)
+	require.NoError(t, err)
+	require.Equal(t, msg, pt)
+
+	ct3, err := csp.Encrypt(imported, msg, bccsp.AESGCMModeOpts{PRNG: rand.Reader})
+	require.NoError(t, err)
+	require.NotEqual(t, ct1[:12], ct3[:12])
+
+	_, err = csp.Encrypt(imported, msg, &bccsp.AESGCMModeOpts{Nonce: nonce, PRNG: rand.Reader})
+	require.Error(t, err)
+	_, err = csp.Encrypt(imported, msg, &bccsp.AESGCMModeOpts{Nonce: make([]byte, 16)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid nonce")
+}
+
+func TestSM4EncryptDecrypt(t *testing.T) {
+	csp := newTestCSP(t)
+
+	generated, err := csp.KeyGen(&bccsp.SM4KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	require.True(t, generated.Symmetric())
+	_, err = generated.Bytes()
+	require.Error(t, err)
+
+	raw, err := GetRandomBytes(16)
+	require.NoError(t, err)
+	imported, err := csp.KeyImport(raw, &bccsp.SM4ImportKeyOpts{Temporary: true})
+	require.NoError(t, err)
+
+	// 相同字节的AES密钥与SM4密钥的SKI不同。
+	aesKey, err := csp.KeyImport(raw, &bccsp.HMACImportKeyOpts{Temporary: true})
+	require.NoError(t, err)
+	require.NotEqual(t, aesKey.SKI(), imported.SKI())
+
+	msg := []byte("hello world")
+	aad := []byte("header")
+	for _, k := range []bccsp.Key{generated, imported} {
+		ct, err := csp.Encrypt(k, msg, &bccsp.SM4CBCPKCS7ModeOpts{})
+		require.NoError(t, err)
+		require.Len(t, ct, 32)
+		pt, err := csp.Decrypt(k, ct, bccsp.SM4CBCPKCS7ModeOpts{})
+		require.NoError(t, err)
+		require.Equal(t, msg, pt)
+
+		ct, err = csp.Encrypt(k, msg, &bccsp.SM4GCMModeOpts{AdditionalData: aad})
+		require.NoError(t, err)
+		require.Len(t, ct, 12+len(msg)+16)
+		pt, err = csp.Decrypt(k, ct, &bccsp.SM4GCMModeOpts{AdditionalData: aad})
+		require.NoError(t, err)
+		require.Equal(t, msg, pt)
+
+		tampered := utils.Clone(ct)
+		tampered[len(tampered)-1] ^= 1
+		_, err = csp.Decrypt(k, tampered, &bccsp.SM4GCMModeOpts{AdditionalData: aad})
+		require.True(t, errors.Is(err, bccsp.ErrAuthenticationFailed))
+		_, err = csp.Decrypt(k, ct, bccsp.SM4GCMModeOpts{})
+		require.True(t, errors.Is(err, bccsp.ErrAuthenticationFailed))
+
+		// SM4密钥不能用于AES的模式。
+		_, err = csp.Encrypt(k, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+		require.Error(t, err)
+	}
+
+	// 指定IV时，密文以该IV开头，并且与标准的CBC模式的结果相同。
+	iv := make([]byte, 16)
+	ct, err := csp.Encrypt(imported, msg, &bccsp.SM4CBCPKCS7ModeOpts{IV: iv})
+	require.NoError(t, err)
+	require.Equal(t, iv, ct[:16])
+	block, err := gm.NewSM4Cipher(raw)
+	require.NoError(t, err)
+	expected := make([]byte, 16)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(expected, pkcs7Padding(utils.Clone(msg)))
+	require.Equal(t, expected, ct[16:])
+
+	_, err = csp.Encrypt(imported, msg, &bccsp.SM4CBCPKCS7ModeOpts{IV: iv, PRNG: rand.Reader})
+	require.Error(t, err)
+	_, err = csp.Encrypt(imported, msg, &bccsp.SM4CBCPKCS7ModeOpts{IV: iv[:8]})
+	require.Error(t, err)
+	_, err = csp.Decrypt(imported, ct[:20], &bccsp.SM4CBCPKCS7ModeOpts{})
+	require.Error(t, err)
+
+	_, err = csp.KeyImport(append(raw, raw...), &bccsp.SM4ImportKeyOpts{Temporary: true})
+	require.Error(t, err)
+}
+
+func TestChaCha20Poly1305EncryptDecrypt(t *testing.T) {
+	csp := newTestCSP(t)
+
+	generated, err := csp.KeyGen(&bccsp.ChaCha20Poly1305KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	require.True(t, generated.Symmetric())
+	require.True(t, generated.Private())
+	_, err = generated.Bytes()
+	require.Error(t, err)
+
+	raw, err := GetRandomBytes(32)
+	require.NoError(t, err)
+	imported, err := csp.KeyImport(raw, &bccsp.ChaCha20Poly1305ImportKeyOpts{Temporary: true})
+	require.NoError(t, err)
+
+	// 相同字节的AES密钥与ChaCha20-Poly1305密钥的SKI不同。
+	aesKey, err := csp.KeyImport(raw, &bccsp.AES256ImportKeyOpts{Temporary: true})
+	require.NoError(t, err)
+	require.NotEqual(t, aesKey.SKI(), imported.SKI())
+
+	msg := []byte("hello world")
+	aad := []byte("header")
+	for _, tc := range []struct {
+		name      string
+		opts      bccsp.EncrypterOpts
+		otherOpts bccsp.EncrypterOpts
+		nonceSize int
+	}{
+		{"ChaCha20Poly1305", &bccsp.ChaCha20Poly1305ModeOpts{AdditionalData: aad}, bccsp.ChaCha20Poly1305ModeOpts{AdditionalData: []byte("other")}, 12},
+		{"XChaCha20Poly1305", &bccsp.XChaCha20Poly1305ModeOpts{AdditionalData: aad}, bccsp.XChaCha20Poly1305ModeOpts{AdditionalData: []byte("other")}, 24},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, k := range []bccsp.Key{generated, imported} {
+				ct, err := csp.Encrypt(k, msg, tc.opts)
+				require.NoError(t, err)
+				require.Len(t, ct, tc.nonceSize+len(msg)+16)
+
+				pt, err := csp.Decrypt(k, ct, tc.opts)
+				require.NoError(t, err)
+				require.Equal(t, msg, pt)
+
+				// 篡改密文或者使用不同的附加数据都会导致认证失败。
+				tampered := utils.Clone(ct)
+				tampered[len(tampered)-1] ^= 1
+				_, err = csp.Decrypt(k, tampered, tc.opts)
+				require.True(t, errors.Is(err, bccsp.ErrAuthenticationFailed))
+				_, err = csp.Decrypt(k, ct, tc.otherOpts)
+				require.True(t, errors.Is(err, bccsp.ErrAuthenticationFailed))
+
+				_, err = csp.Decrypt(k, ct[:tc.nonceSize], tc.opts)
+				require.Error(t, err)
+			}
+		})
+	}
+
+	// 指定nonce时，密文以该nonce开头，nonce的长度必须与模式相符。
+	nonce := make([]byte, 24)
+	ct, err := csp.Encrypt(imported, msg, &bccsp.XChaCha20Poly1305ModeOpts{Nonce: nonce})
+	require.NoError(t, err)
+	require.Equal(t, nonce, ct[:24])
+	_, err = csp.Encrypt(imported, msg, &bccsp.ChaCha20Poly1305ModeOpts{Nonce: nonce})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid nonce")
+	_, err = csp.Encrypt(imported, msg, &bccsp.ChaCha20Poly1305ModeOpts{Nonce: nonce[:12], PRNG: rand.Reader})
+	require.Error(t, err)
+
+	// 密钥不能用于AES的模式。
+	_, err = csp.Encrypt(imported, msg, &bccsp.AESGCMModeOpts{})
+	require.Error(t, err)
+
+	_, err = csp.KeyImport(raw[:16], &bccsp.ChaCha20Poly1305ImportKeyOpts{Temporary: true})
+	require.Error(t, err)
+	_, err = csp.KeyImport("key", &bccsp.ChaCha20Poly1305ImportKeyOpts{Temporary: true})
+	require.Error(t, err)
+}
+
+func TestPKCS7Padding(t *testing.T) {
+	for i := 0; i <= 32; i++ {
+		src := make([]byte, i)
+		padded := pkcs7Padding(src)
+		require.Zero(t, len(padded)%16)
+		unpadded, err := pkcs7UnPadding(padded)
+		require.NoError(t, err)
+		require.Equal(t, src, unpadded)
+	}
+
+	_, err := pkcs7UnPadding([]byte{1, 2, 3, 0})
+	require.Error(t, err)
+	_, err = pkcs7UnPadding([]byte{1, 2, 3, 2})
+	require.Error(t, err)
+}
+
+func TestHash(t *testing.T) {
+	csp := newTestCSP(t)
+	msg := []byte("hello world")
+
+	for opts, h := range map[bccsp.HashOpts][]byte{
+		&bccsp.SHAOpts{}:      sha256Sum(msg),
+		&bccsp.SHA256Opts{}:   sha256Sum(msg),
+		&bccsp.SHA384Opts{}:   sha384Sum(msg),
+		&bccsp.SHA3_256Opts{}: sha3_256Sum(msg),
+		&bccsp.SHA3_384Opts{}: sha3_384Sum(msg),
+		&bccsp.SM3Opts{}:      sm3Sum(msg),
+	} {
+		digest, err := csp.Hash(msg, opts)
+		require.NoError(t, err)
+		require.Equal(t, h, digest)
+
+		hf, err := csp.GetHash(opts)
+		require.NoError(t, err)
+		hf.Write(msg)
+		require.Equal(t, h, hf.Sum(nil))
+	}
+
+	// 空的选项使用默认的哈希函数。
+	digest, err := csp.Hash(msg, nil)
+	require.NoError(t, err)
+	require.Equal(t, sha256Sum(msg), digest)
+
+	csp3, err := NewWithParams(384, bccsp.SHA3, NewDummyKeyStore())
+	require.NoError(t, err)
+	digest, err = csp3.Hash(msg, nil)
+	require.NoError(t, err)
+	require.Equal(t, sha3_384Sum(msg), digest)
+}
+
+func TestAddWrapper(t *testing.T) {
+	csp, err := New(NewDummyKeyStore())
+	require.NoError(t, err)
+
+	_, err = csp.Hash([]byte("hello world"), &bccsp.SHA256Opts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported 'HashOpt' provided")
+
+	require.NoError(t, csp.AddWrapper(reflect.TypeOf(&bccsp.SHA256Opts{}), &hasher{hash: sha256.New}))
+	_, err = csp.Hash([]byte("hello world"), &bccsp.SHA256Opts{})
+	require.NoError(t, err)
+
+	require.Error(t, csp.AddWrapper(nil, &hasher{}))
+	require.Error(t, csp.AddWrapper(reflect.TypeOf(&bccsp.SHA256Opts{}), nil))
+	err = csp.AddWrapper(reflect.TypeOf(&bccsp.SHA256Opts{}), "hasher")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wrapper type not valid")
+}
+
+func sha256Sum(msg []byte) []byte {
+	h := sha256.Sum256(msg)
+	return h[:]
+}
+
+func sm3Sum(msg []byte) []byte {
+	h := gm.SumSM3(msg)
+	return h[:]
+}
+
+func sha384Sum(msg []byte) []byte {
+	h := sha512.Sum384(msg)
+	return h[:]
+}
+
+func sha3_256Sum(msg []byte) []byte {
+	h := sha3.Sum256(msg)
+	return h[:]
+}
+
+func sha3_384Sum(msg []byte) []byte {
+	h := sha3.Sum384(msg)
+	return h[:]
+}
@@ -0,0 +1,78 @@
+package sw
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// InMemoryKeyStore 是基于内存实现的KeyStore，可以被多个协程并发访问，适用于测试以及不需要持久化
+// 密钥的场景。调用Freeze以后，KeyStore变为只读的。
+type InMemoryKeyStore struct {
+	keys   map[string]bccsp.Key
+	frozen bool
+	m      sync.RWMutex
+}
+
+// NewInMemoryKeyStore 创建一个空的、可写的基于内存的KeyStore。
+func NewInMemoryKeyStore() *InMemoryKeyStore {
+	return &InMemoryKeyStore{keys: make(map[string]bccsp.Key)}
+}
+
+// ReadOnly 在调用Freeze之前返回false，之后返回true。
+func (ks *InMemoryKeyStore) ReadOnly() bool {
+	ks.m.RLock()
+	defer ks.m.RUnlock()
+
+	return ks.frozen
+}
+
+// Freeze 将KeyStore冻结为只读的，此后调用StoreKey都会失败，已经存储的密钥仍然可以获取。
+func (ks *InMemoryKeyStore) Freeze() {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	ks.frozen = true
+}
+
+// GetKey 返回与ski相关联的密钥。
+func (ks *InMemoryKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	if len(ski) == 0 {
+		return nil, errors.New("invalid SKI, cannot be of zero length")
+	}
+
+	ks.m.RLock()
+	defer ks.m.RUnlock()
+
+	key, found := ks.keys[hex.EncodeToString(ski)]
+	if !found {
+		return nil, fmt.Errorf("no key found for ski %x", ski)
+	}
+
+	return key, nil
+}
+
+// StoreKey 存储密钥，如果KeyStore是只读的，或者已经存在相同SKI的密钥，则返回错误。
+func (ks *InMemoryKeyStore) StoreKey(k bccsp.Key) error {
+	if k == nil {
+		return errors.New("invalid key, it must be different from nil")
+	}
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	if ks.frozen {
+		return errors.New("read only KeyStore")
+	}
+
+	ski := hex.EncodeToString(k.SKI())
+	if _, found := ks.keys[ski]; found {
+		return fmt.Errorf("ski %s already exists in the keystore", ski)
+	}
+	ks.keys[ski] = k
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package sw
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryKeyStore(t *testing.T) {
+	ks := NewInMemoryKeyStore()
+	require.False(t, ks.ReadOnly())
+
+	k := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	require.NoError(t, ks.StoreKey(k))
+
+	err := ks.StoreKey(k)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already exists in the keystore")
+
+	k2, err := ks.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.Equal(t, k, k2)
+
+	_, err = ks.GetKey([]byte("unknown"))
+	require.Error(t, err)
+	_, err = ks.GetKey(nil)
+	require.Error(t, err)
+	require.Error(t, ks.StoreKey(nil))
+
+	ks.Freeze()
+	require.True(t, ks.ReadOnly())
+	err = ks.StoreKey(&aesPrivateKey{[]byte("fedcba9876543210fedcba9876543210"), false})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "read only KeyStore")
+
+	_, err = ks.GetKey(k.SKI())
+	require.NoError(t, err)
+}
+
+func TestInMemoryKeyStoreConcurrency(t *testing.T) {
+	ks := NewInMemoryKeyStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := &aesPrivateKey{[]byte(fmt.Sprintf("%032d", i)), false}
+			assert.NoError(t, ks.StoreKey(k))
+			_, err := ks.GetKey(k.SKI())
+			assert.NoError(t, err)
+			ks.ReadOnly()
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCompositeKeyStore(t *testing.T) {
+	_, err := NewCompositeKeyStore()
+	require.Error(t, err)
+	_, err = NewCompositeKeyStore(NewInMemoryKeyStore(), nil)
+	require.Error(t, err)
+
+	provisioned := NewInMemoryKeyStore()
+	pre := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	require.NoError(t, provisioned.StoreKey(pre))
+	provisioned.Freeze()
+
+	fileKS, err := NewFileBasedKeyStore(t.TempDir(), false)
+	require.NoError(t, err)
+
+	ks, err := NewCompositeKeyStore(provisioned, fileKS)
+	require.NoError(t, err)
+	require.False(t, ks.ReadOnly())
+
+	// 新生成的密钥被存储到第一个可写的KeyStore中。
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	generated, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+
+	_, err = provisioned.GetKey(generated.SKI())
+	require.Error(t, err)
+	_, err = fileKS.GetKey(generated.SKI())
+	require.NoError(t, err)
+
+	// 两个KeyStore中的密钥都能被找到。
+	k, err := csp.GetKey(pre.SKI())
+	require.NoError(t, err)
+	require.Equal(t, pre, k)
+	k, err = csp.GetKey(generated.SKI())
+	require.NoError(t, err)
+	require.True(t, k.Private())
+
+	_, err = ks.GetKey([]byte("unknown"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found in any keystore")
+
+	readOnly, err := NewCompositeKeyStore(provisioned, NewDummyKeyStore())
+	require.NoError(t, err)
+	require.True(t, readOnly.ReadOnly())
+	require.Error(t, readOnly.StoreKey(pre))
+}
@@ -0,0 +1,67 @@
+package sw
+
+import (
+	"hash"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// KeyGenerator 根据给定的选项生成密钥。
+type KeyGenerator interface {
+	// KeyGen 根据选项opts生成一个密钥。
+	KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error)
+}
+
+// KeyDeriver 根据给定的选项，从已有的密钥派生出新的密钥。
+type KeyDeriver interface {
+	// KeyDeriv 利用选项opts从密钥k派生出一个新的密钥。
+	KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (dk bccsp.Key, err error)
+}
+
+// KeyImporter 根据给定的选项，从密钥原材料中导入密钥。
+type KeyImporter interface {
+	// KeyImport 利用选项opts从原材料raw中导入一个密钥。
+	KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.Key, err error)
+}
+
+// Encryptor 利用给定的密钥对明文进行加密。
+type Encryptor interface {
+	// Encrypt 利用密钥k和选项opts对明文plaintext进行加密。
+	Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) (ciphertext []byte, err error)
+}
+
+// Decryptor 利用给定的密钥对密文进行解密。
+type Decryptor interface {
+	// Decrypt 利用密钥k和选项opts对密文ciphertext进行解密。
+	Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error)
+}
+
+// Signer 利用给定的密钥对消息摘要进行签名。
+type Signer interface {
+	// Sign 利用密钥k对摘要digest进行签名，选项opts决定了签名的细节。
+	Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error)
+}
+
+// Verifier 利用给定的密钥对签名进行验证。
+type Verifier interface {
+	// Verify 利用密钥k验证摘要digest的签名signature是否有效。
+	Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (valid bool, err error)
+}
+
+// Hasher 根据给定的选项计算消息的哈希值。
+type Hasher interface {
+	// Hash 利用选项opts计算消息msg的哈希值。
+	Hash(msg []byte, opts bccsp.HashOpts) (hash []byte, err error)
+
+	// GetHash 根据选项opts返回一个hash.Hash实例。
+	GetHash(opts bccsp.HashOpts) (h hash.Hash, err error)
+}
+
+// Aggregator 根据给定的选项聚合签名和公钥。
+type Aggregator interface {
+	// AggregateSignatures 利用选项opts将多个签名聚合成一个签名。
+	AggregateSignatures(signatures [][]byte, opts bccsp.AggregateOpts) (signature []byte, err error)
+
+	// AggregatePublicKeys 利用选项opts将多个公钥聚合成一个公钥。
+	AggregatePublicKeys(keys []bccsp.Key, opts bccsp.AggregateOpts) (k bccsp.Key, err error)
+}
@@ -0,0 +1,146 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+type ecdsaPublicKeyKeyDeriver struct{}
+
+// KeyDeriv 将公钥点Q重新随机化为Q + k·G，其中k由reRandFactor计算得到。派生公钥不需要知道私钥，并且与
+// ecdsaPrivateKeyKeyDeriver用相同的扩展值派生出的私钥相对应。
+func (kd *ecdsaPublicKeyKeyDeriver) KeyDeriv(key bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts parameter, it must not be nil")
+	}
+
+	ecdsaK := key.(*ecdsaPublicKey)
+
+	reRandOpts, ok := opts.(*bccsp.ECDSAReRandKeyOpts)
+	if !ok {
+		return nil, fmt.Errorf("unsupported 'KeyDerivOpts' provided [%v]", opts)
+	}
+
+	curve := ecdsaK.pubKey.Curve
+	k, err := reRandFactor(curve, reRandOpts.ExpansionValue())
+	if err != nil {
+		return nil, err
+	}
+
+	tempSK := &ecdsa.PublicKey{Curve: curve}
+	kx, ky := curve.ScalarBaseMult(k.Bytes())
+	tempSK.X, tempSK.Y = curve.Add(ecdsaK.pubKey.X, ecdsaK.pubKey.Y, kx, ky)
+
+	// 检查派生出的公钥点是否在曲线上，两个点相加的结果可能是无穷远点。
+	if !curve.IsOnCurve(tempSK.X, tempSK.Y) {
+		return nil, errors.New("failed temporary public key IsOnCurve check")
+	}
+
+	return &ecdsaPublicKey{tempSK}, nil
+}
+
+type ecdsaPrivateKeyKeyDeriver struct {
+	hkdf *hkdfKeyDeriver
+}
+
+// KeyDeriv 将私钥d重新随机化为(d + k) mod N，其中k由reRandFactor计算得到，派生出的私钥对应的公钥等于
+// ecdsaPublicKeyKeyDeriver用相同的扩展值派生出的公钥。如果opts是ECDHKeyDerivOpts，则与对方的公钥进行
+// ECDH密钥协商，派生出共享的AES密钥。
+func (kd *ecdsaPrivateKeyKeyDeriver) KeyDeriv(key bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts parameter, it must not be nil")
+	}
+
+	ecdsaK := key.(*ecdsaPrivateKey)
+
+	if ecdhOpts, ok := opts.(*bccsp.ECDHKeyDerivOpts); ok {
+		return ecdhKeyDeriv(ecdsaK.privKey, ecdhOpts, kd.hkdf)
+	}
+
+	reRandOpts, ok := opts.(*bccsp.ECDSAReRandKeyOpts)
+	if !ok {
+		return nil, fmt.Errorf("unsupported 'KeyDerivOpts' provided [%v]", opts)
+	}
+
+	curve := ecdsaK.privKey.Curve
+	k, err := reRandFactor(curve, reRandOpts.ExpansionValue())
+	if err != nil {
+		return nil, err
+	}
+
+	d := new(big.Int).Add(ecdsaK.privKey.D, k)
+	d.Mod(d, curve.Params().N)
+	if d.Sign() == 0 {
+		return nil, errors.New("derived private key is zero")
+	}
+
+	tempSK := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve},
+		D:         d,
+	}
+	tempSK.PublicKey.X, tempSK.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+
+	if !curve.IsOnCurve(tempSK.PublicKey.X, tempSK.PublicKey.Y) {
+		return nil, errors.New("failed temporary public key IsOnCurve check")
+	}
+
+	return &ecdsaPrivateKey{tempSK}, nil
+}
+
+// reRandFactor 根据扩展值计算重新随机化系数k = SHA256(expansion) mod (N-1) + 1，k的取值范围是[1, N-1]。
+func reRandFactor(curve elliptic.Curve, expansion []byte) (*big.Int, error) {
+	if len(expansion) == 0 {
+		return nil, errors.New("invalid expansion value, it must not be empty")
+	}
+
+	h := sha256.Sum256(expansion)
+	k := new(big.Int).SetBytes(h[:])
+	one := big.NewInt(1)
+	n := new(big.Int).Sub(curve.Params().N, one)
+	k.Mod(k, n)
+	k.Add(k, one)
+
+	return k, nil
+}
+
+type aesPrivateKeyKeyDeriver struct {
+	conf *config
+	hkdf *hkdfKeyDeriver
+}
+
+// KeyDeriv 以AES密钥为HMAC的密钥、以选项中的参数为消息计算HMAC，从而派生出新的密钥：
+//   - HMACTruncated256AESDeriveKeyOpts：取HMAC-SHA256的输出作为新的AES-256密钥；
+//   - HMACDeriveKeyOpts：以配置的哈希函数计算HMAC，输出作为新的HMAC密钥，该密钥是可导出的；
+//   - HKDFKeyDerivOpts：以AES密钥为输入密钥材料，用HKDF派生出新的对称密钥。
+func (kd *aesPrivateKeyKeyDeriver) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("invalid opts parameter, it must not be nil")
+	}
+
+	aesK := k.(*aesPrivateKey)
+
+	switch hmacOpts := opts.(type) {
+	case *bccsp.HMACTruncated256AESDeriveKeyOpts:
+		mac := hmac.New(sha256.New, aesK.privKey)
+		mac.Write(hmacOpts.Argument())
+		return &aesPrivateKey{mac.Sum(nil)[:32], false}, nil
+
+	case *bccsp.HMACDeriveKeyOpts:
+		mac := hmac.New(kd.conf.hashFunction, aesK.privKey)
+		mac.Write(hmacOpts.Argument())
+		return &aesPrivateKey{mac.Sum(nil), true}, nil
+
+	case *bccsp.HKDFKeyDerivOpts:
+		return kd.hkdf.deriveKey(aesK.privKey, hmacOpts)
+
+	default:
+		return nil, fmt.Errorf("unsupported 'KeyDerivOpts' provided [%v]", opts)
+	}
+}
@@ -0,0 +1,327 @@
+package sw
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECDSAReRandKeyDeriv(t *testing.T) {
+	csp := newTestCSP(t)
+
+	for _, opts := range []bccsp.KeyGenOpts{&bccsp.ECDSAP256KeyGenOpts{Temporary: true}, &bccsp.ECDSAP384KeyGenOpts{Temporary: true}} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+
+		reRandOpts := &bccsp.ECDSAReRandKeyOpts{Temporary: true, Expansion: []byte("transaction-1")}
+		dk, err := csp.KeyDeriv(k, reRandOpts)
+		require.NoError(t, err)
+		require.True(t, dk.Private())
+		require.NotEqual(t, k.SKI(), dk.SKI())
+
+		// 不需要私钥就可以派生出对应的公钥。
+		dpk, err := csp.KeyDeriv(pk, reRandOpts)
+		require.NoError(t, err)
+		require.False(t, dpk.Private())
+		dkPub, err := dk.PublicKey()
+		require.NoError(t, err)
+		require.Equal(t, dkPub.SKI(), dpk.SKI())
+
+		digest := sha256.Sum256([]byte("hello world"))
+		signature, err := csp.Sign(dk, digest[:], nil)
+		require.NoError(t, err)
+		valid, err := csp.Verify(dpk, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+		valid, err = csp.Verify(pk, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.False(t, valid)
+
+		// 不同的扩展值派生出不同的密钥，相同的扩展值派生出相同的密钥。
+		other, err := csp.KeyDeriv(pk, &bccsp.ECDSAReRandKeyOpts{Temporary: true, Expansion: []byte("transaction-2")})
+		require.NoError(t, err)
+		require.NotEqual(t, dpk.SKI(), other.SKI())
+		again, err := csp.KeyDeriv(pk, reRandOpts)
+		require.NoError(t, err)
+		require.Equal(t, dpk.SKI(), again.SKI())
+	}
+}
+
+func TestECDSAReRandKeyDerivInvalidInputs(t *testing.T) {
+	csp := newTestCSP(t)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	_, err = csp.KeyDeriv(k, &bccsp.ECDSAReRandKeyOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid expansion value")
+
+	_, err = csp.KeyDeriv(k, nil)
+	require.Error(t, err)
+
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = csp.KeyDeriv(aesKey, &bccsp.ECDSAReRandKeyOpts{Temporary: true, Expansion: []byte{1}})
+	require.Error(t, err)
+}
+
+func TestHMACKeyDeriv(t *testing.T) {
+	ks := NewInMemoryKeyStore()
+	csp, err := NewWithParams(256, bccsp.SHA2, ks)
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	raw := k.(*aesPrivateKey).privKey
+
+	// 派生出的AES-256密钥是HMAC-SHA256的输出，非暂时的密钥会被存储到KeyStore中。
+	dk, err := csp.KeyDeriv(k, &bccsp.HMACTruncated256AESDeriveKeyOpts{Temporary: false, Arg: []byte("collection-1")})
+	require.NoError(t, err)
+	mac := hmac.New(sha256.New, raw)
+	mac.Write([]byte("collection-1"))
+	require.Equal(t, mac.Sum(nil), dk.(*aesPrivateKey).privKey)
+	_, err = dk.Bytes()
+	require.Error(t, err)
+
+	stored, err := ks.GetKey(dk.SKI())
+	require.NoError(t, err)
+	require.Equal(t, dk.SKI(), stored.SKI())
+
+	ct, err := csp.Encrypt(dk, []byte("private data"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	pt, err := csp.Decrypt(stored, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	require.Equal(t, []byte("private data"), pt)
+
+	// 派生出的HMAC密钥是可导出的，暂时的密钥不会被存储。
+	hk, err := csp.KeyDeriv(k, &bccsp.HMACDeriveKeyOpts{Temporary: true, Arg: []byte("mac-key")})
+	require.NoError(t, err)
+	hkRaw, err := hk.Bytes()
+	require.NoError(t, err)
+	mac = hmac.New(sha256.New, raw)
+	mac.Write([]byte("mac-key"))
+	require.Equal(t, mac.Sum(nil), hkRaw)
+	_, err = ks.GetKey(hk.SKI())
+	require.Error(t, err)
+
+	other, err := csp.KeyDeriv(k, &bccsp.HMACTruncated256AESDeriveKeyOpts{Temporary: true, Arg: []byte("collection-2")})
+	require.NoError(t, err)
+	require.NotEqual(t, dk.SKI(), other.SKI())
+
+	_, err = csp.KeyDeriv(k, &bccsp.ECDSAReRandKeyOpts{Temporary: true, Expansion: []byte{1}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported 'KeyDerivOpts' provided")
+}
+
+func TestECDHKeyDeriv(t *testing.T) {
+	csp := newTestCSP(t)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+		&bccsp.X25519KeyGenOpts{Temporary: true},
+	} {
+		alice, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		bob, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		alicePub, err := alice.PublicKey()
+		require.NoError(t, err)
+		bobPub, err := bob.PublicKey()
+		require.NoError(t, err)
+
+		// 对方的公钥可以是从PKIX格式导入的。
+		raw, err := bobPub.Bytes()
+		require.NoError(t, err)
+		var importOpts bccsp.KeyImportOpts = &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true}
+		if _, ok := opts.(*bccsp.X25519KeyGenOpts); ok {
+			importOpts = &bccsp.X25519PublicKeyImportOpts{Temporary: true}
+		}
+		bobPub, err = csp.KeyImport(raw, importOpts)
+		require.NoError(t, err)
+
+		aliceShared, err := csp.KeyDeriv(alice, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: bobPub, SharedInfo: []byte("gossip")})
+		require.NoError(t, err)
+		require.True(t, aliceShared.Symmetric())
+		bobShared, err := csp.KeyDeriv(bob, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: alicePub, SharedInfo: []byte("gossip")})
+		require.NoError(t, err)
+		require.Equal(t, aliceShared.SKI(), bobShared.SKI())
+
+		ct, err := csp.Encrypt(aliceShared, []byte("private data"), &bccsp.AESCBCPKCS7ModeOpts{})
+		require.NoError(t, err)
+		pt, err := csp.Decrypt(bobShared, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+		require.NoError(t, err)
+		require.Equal(t, []byte("private data"), pt)
+
+		// 共享信息不同，派生出的密钥也不同。
+		other, err := csp.KeyDeriv(bob, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: alicePub})
+		require.NoError(t, err)
+		require.NotEqual(t, aliceShared.SKI(), other.SKI())
+	}
+}
+
+func TestX25519KeyDerivVector(t *testing.T) {
+	csp := newTestCSP(t)
+
+	// RFC 7748第6.1节的测试向量。
+	alicePriv, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bobPub, _ := hex.DecodeString("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	shared, _ := hex.DecodeString("4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	peer, err := csp.KeyImport(bobPub, &bccsp.X25519PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+
+	opts := &bccsp.ECDHKeyDerivOpts{
+		Temporary:     true,
+		PeerPublicKey: peer,
+		KDF:           func(secret []byte) ([]byte, error) { return secret, nil },
+	}
+	k, err := csp.KeyDeriv(&x25519PrivateKey{alicePriv}, opts)
+	require.NoError(t, err)
+	require.Equal(t, shared, k.(*aesPrivateKey).privKey)
+}
+
+func TestECDHKeyDerivInvalidInputs(t *testing.T) {
+	csp := newTestCSP(t)
+
+	p256, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	p384, err := csp.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	x25519, err := csp.KeyGen(&bccsp.X25519KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	_, err = csp.KeyDeriv(p256, &bccsp.ECDHKeyDerivOpts{Temporary: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid peer public key, it must not be nil")
+
+	_, err = csp.KeyDeriv(p256, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: p384})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "curve [P-384] does not match [P-256]")
+
+	_, err = csp.KeyDeriv(p256, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: x25519})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected an ECDSA public key")
+
+	_, err = csp.KeyDeriv(x25519, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: p256})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected an X25519 public key")
+
+	// 小阶点会导致共享秘密全为0。
+	_, err = csp.KeyDeriv(x25519, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: &x25519PublicKey{make([]byte, 32)}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed computing X25519 shared secret")
+
+	_, err = csp.KeyDeriv(x25519, &bccsp.ECDSAReRandKeyOpts{Temporary: true, Expansion: []byte{1}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported 'KeyDerivOpts' provided")
+}
+
+func TestHKDFKeyDeriv(t *testing.T) {
+	csp := newTestCSP(t)
+
+	// RFC 5869附录A.1的测试向量。
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm, _ := hex.DecodeString("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")
+
+	k, err := csp.KeyImport(ikm, &bccsp.HMACImportKeyOpts{Temporary: true})
+	require.NoError(t, err)
+	dk, err := csp.KeyDeriv(k, &bccsp.HKDFKeyDerivOpts{Temporary: true, Hash: bccsp.SHA256, Salt: salt, Info: info, KeyType: bccsp.HMAC, Length: 42})
+	require.NoError(t, err)
+	raw, err := dk.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, okm, raw)
+
+	// 默认派生出32字节的AES密钥，HMAC密钥的默认长度等于哈希函数的输出长度。
+	dk, err = csp.KeyDeriv(k, &bccsp.HKDFKeyDerivOpts{Temporary: true})
+	require.NoError(t, err)
+	require.IsType(t, &aesPrivateKey{}, dk)
+	require.Len(t, dk.(*aesPrivateKey).privKey, 32)
+	dk, err = csp.KeyDeriv(k, &bccsp.HKDFKeyDerivOpts{Temporary: true, Hash: bccsp.SHA384, KeyType: bccsp.HMAC})
+	require.NoError(t, err)
+	require.Len(t, dk.(*aesPrivateKey).privKey, 48)
+
+	// 可以从SM4和ChaCha20-Poly1305密钥派生，并选择不同的哈希函数。
+	sm4Key, err := csp.KeyGen(&bccsp.SM4KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	chachaKey, err := csp.KeyGen(&bccsp.ChaCha20Poly1305KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	var derived [][]byte
+	for _, src := range []bccsp.Key{sm4Key, chachaKey} {
+		for _, hashFunction := range []string{bccsp.SHA256, bccsp.SHA3_256, bccsp.SM3} {
+			dk, err := csp.KeyDeriv(src, &bccsp.HKDFKeyDerivOpts{Temporary: true, Hash: hashFunction, Info: []byte("gossip"), KeyType: bccsp.ChaCha20Poly1305})
+			require.NoError(t, err)
+			require.IsType(t, &chacha20Poly1305Key{}, dk)
+			derived = append(derived, dk.(*chacha20Poly1305Key).privKey)
+
+			ct, err := csp.Encrypt(dk, []byte("private data"), &bccsp.ChaCha20Poly1305ModeOpts{})
+			require.NoError(t, err)
+			pt, err := csp.Decrypt(dk, ct, &bccsp.ChaCha20Poly1305ModeOpts{})
+			require.NoError(t, err)
+			require.Equal(t, []byte("private data"), pt)
+		}
+	}
+	for i := range derived {
+		for j := i + 1; j < len(derived); j++ {
+			require.NotEqual(t, derived[i], derived[j])
+		}
+	}
+}
+
+func TestHKDFKeyDerivInvalidInputs(t *testing.T) {
+	csp := newTestCSP(t)
+
+	k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		opts     *bccsp.HKDFKeyDerivOpts
+		errorMsg string
+	}{
+		{&bccsp.HKDFKeyDerivOpts{Hash: "SHA8"}, "hash function not recognized [SHA8]"},
+		{&bccsp.HKDFKeyDerivOpts{Length: 20}, "invalid AES key length [20], must be 16, 24 or 32 bytes"},
+		{&bccsp.HKDFKeyDerivOpts{KeyType: bccsp.ChaCha20Poly1305, Length: 16}, "invalid ChaCha20-Poly1305 key length [16], must be 32 bytes"},
+		{&bccsp.HKDFKeyDerivOpts{KeyType: bccsp.HMAC, Length: 255*32 + 1}, "invalid key length [8161], must be at most 8160 bytes"},
+		{&bccsp.HKDFKeyDerivOpts{KeyType: bccsp.SM4}, "unsupported key type [SM4]"},
+	} {
+		tc.opts.Temporary = true
+		_, err = csp.KeyDeriv(k, tc.opts)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), tc.errorMsg)
+	}
+
+	chachaKey, err := csp.KeyGen(&bccsp.ChaCha20Poly1305KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = csp.KeyDeriv(chachaKey, &bccsp.HMACDeriveKeyOpts{Temporary: true, Arg: []byte{1}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported 'KeyDerivOpts' provided")
+}
+
+func TestECDHWithHKDF(t *testing.T) {
+	csp := newTestCSP(t)
+
+	alice, err := csp.KeyGen(&bccsp.X25519KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	bob, err := csp.KeyGen(&bccsp.X25519KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	alicePub, err := alice.PublicKey()
+	require.NoError(t, err)
+	bobPub, err := bob.PublicKey()
+	require.NoError(t, err)
+
+	hkdfOpts := &bccsp.HKDFKeyDerivOpts{Hash: bccsp.SHA384, Salt: []byte("salt"), Info: []byte("gossip"), KeyType: bccsp.ChaCha20Poly1305}
+	aliceShared, err := csp.KeyDeriv(alice, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: bobPub, HKDF: hkdfOpts})
+	require.NoError(t, err)
+	require.IsType(t, &chacha20Poly1305Key{}, aliceShared)
+	bobShared, err := csp.KeyDeriv(bob, &bccsp.ECDHKeyDerivOpts{Temporary: true, PeerPublicKey: alicePub, HKDF: hkdfOpts})
+	require.NoError(t, err)
+	require.Equal(t, aliceShared.SKI(), bobShared.SKI())
+}
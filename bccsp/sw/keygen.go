@@ -0,0 +1,110 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+	bls12381 "github.com/kilic/bls12-381"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+type ecdsaKeyGenerator struct {
+	curve elliptic.Curve
+}
+
+func (kg *ecdsaKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	privKey, err := ecdsa.GenerateKey(kg.curve, rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating ECDSA key for [%v]: [%s]", kg.curve, err)
+	}
+
+	return &ecdsaPrivateKey{privKey}, nil
+}
+
+type ed25519KeyGenerator struct{}
+
+func (kg *ed25519KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating Ed25519 key: [%s]", err)
+	}
+
+	return &ed25519PrivateKey{&privKey}, nil
+}
+
+type x25519KeyGenerator struct{}
+
+func (kg *x25519KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	privKey := make([]byte, x25519KeySize)
+	if _, err := rand.Read(privKey); err != nil {
+		return nil, fmt.Errorf("failed generating X25519 key: [%s]", err)
+	}
+
+	return &x25519PrivateKey{privKey}, nil
+}
+
+type blsKeyGenerator struct{}
+
+func (kg *blsKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	// 私钥是[1, r-1]范围内均匀分布的随机标量。
+	limit := new(big.Int).Sub(bls12381.NewG1().Q(), big.NewInt(1))
+	d, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating BLS12-381 key: [%s]", err)
+	}
+	d.Add(d, big.NewInt(1))
+
+	return &blsPrivateKey{d.FillBytes(make([]byte, blsPrivateKeySize))}, nil
+}
+
+type sm2KeyGenerator struct{}
+
+func (kg *sm2KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	privKey, err := gm.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating SM2 key: [%s]", err)
+	}
+
+	return &sm2PrivateKey{privKey}, nil
+}
+
+type aesKeyGenerator struct {
+	length int
+}
+
+func (kg *aesKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	lowLevelKey, err := GetRandomBytes(kg.length)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating AES %d key [%s]", kg.length, err)
+	}
+
+	return &aesPrivateKey{lowLevelKey, false}, nil
+}
+
+type sm4KeyGenerator struct{}
+
+func (kg *sm4KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	lowLevelKey, err := GetRandomBytes(gm.SM4KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating SM4 key [%s]", err)
+	}
+
+	return &sm4PrivateKey{lowLevelKey, false}, nil
+}
+
+type chacha20Poly1305KeyGenerator struct{}
+
+func (kg *chacha20Poly1305KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	lowLevelKey, err := GetRandomBytes(chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating ChaCha20-Poly1305 key [%s]", err)
+	}
+
+	return &chacha20Poly1305Key{lowLevelKey, false}, nil
+}
@@ -0,0 +1,405 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+	"github.com/232425wxy/lark/bccsp/secp256k1"
+	"github.com/232425wxy/lark/bccsp/utils"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+type aes256ImportKeyOptsKeyImporter struct{}
+
+func (*aes256ImportKeyOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	aesRaw, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if aesRaw == nil {
+		return nil, errors.New("invalid raw material, it must not be nil")
+	}
+
+	if len(aesRaw) != 32 {
+		return nil, fmt.Errorf("invalid key length [%d], must be 32 bytes", len(aesRaw))
+	}
+
+	return &aesPrivateKey{utils.Clone(aesRaw), false}, nil
+}
+
+type hmacImportKeyOptsKeyImporter struct{}
+
+func (*hmacImportKeyOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	aesRaw, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(aesRaw) == 0 {
+		return nil, errors.New("invalid raw material, it must not be nil")
+	}
+
+	return &aesPrivateKey{utils.Clone(aesRaw), false}, nil
+}
+
+type sm4ImportKeyOptsKeyImporter struct{}
+
+func (*sm4ImportKeyOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	keyRaw, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(keyRaw) != gm.SM4KeySize {
+		return nil, fmt.Errorf("invalid key length [%d], must be %d bytes", len(keyRaw), gm.SM4KeySize)
+	}
+
+	return &sm4PrivateKey{utils.Clone(keyRaw), false}, nil
+}
+
+type chacha20Poly1305ImportKeyOptsKeyImporter struct{}
+
+func (*chacha20Poly1305ImportKeyOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	keyRaw, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(keyRaw) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("invalid key length [%d], must be %d bytes", len(keyRaw), chacha20poly1305.KeySize)
+	}
+
+	return &chacha20Poly1305Key{utils.Clone(keyRaw), false}, nil
+}
+
+type ecdsaPKIXPublicKeyImportOptsKeyImporter struct{}
+
+func (*ecdsaPKIXPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	der, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(der) == 0 {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+
+	lowLevelKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		// 标准库无法解析的曲线交给曲线注册表解析，SM2公钥需要通过SM2PKIXPublicKeyImportOpts导入。
+		if ecKey, ecErr := utils.ParseECPKIXPublicKey(der); ecErr == nil && !gm.IsSM2Curve(ecKey.Curve) {
+			return &ecdsaPublicKey{ecKey}, nil
+		}
+		return nil, fmt.Errorf("failed converting PKIX to ECDSA public key [%s]", err)
+	}
+
+	ecdsaPK, ok := lowLevelKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("failed casting to ECDSA public key, invalid raw material")
+	}
+
+	return &ecdsaPublicKey{ecdsaPK}, nil
+}
+
+type ecdsaGoPublicKeyImportOptsKeyImporter struct{}
+
+func (*ecdsaGoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	lowLevelKey, ok := raw.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected *ecdsa.PublicKey")
+	}
+
+	return &ecdsaPublicKey{lowLevelKey}, nil
+}
+
+type ecdsaSecp256k1PublicKeyImportOptsKeyImporter struct{}
+
+func (*ecdsaSecp256k1PublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	point, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	lowLevelKey, err := secp256k1.ParsePublicKey(point)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting to secp256k1 public key [%s]", err)
+	}
+
+	return &ecdsaPublicKey{lowLevelKey}, nil
+}
+
+type ed25519PKIXPublicKeyImportOptsKeyImporter struct{}
+
+func (*ed25519PKIXPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	der, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(der) == 0 {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+
+	lowLevelKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting PKIX to Ed25519 public key [%s]", err)
+	}
+
+	ed25519PK, ok := lowLevelKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("failed casting to Ed25519 public key, invalid raw material")
+	}
+
+	return &ed25519PublicKey{&ed25519PK}, nil
+}
+
+type x25519PublicKeyImportOptsKeyImporter struct{}
+
+func (*x25519PublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	der, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(der) == 0 {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+
+	// 32字节的原材料是RFC 7748定义的公钥编码，否则按照PKIX格式解析。
+	if len(der) == x25519KeySize {
+		return &x25519PublicKey{utils.Clone(der)}, nil
+	}
+
+	pub, err := parseX25519PKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting PKIX to X25519 public key [%s]", err)
+	}
+
+	return &x25519PublicKey{pub}, nil
+}
+
+type blsPrivateKeyImportOptsKeyImporter struct{}
+
+func (*blsPrivateKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	privKey, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	return newBLSPrivateKey(privKey)
+}
+
+type blsPublicKeyImportOptsKeyImporter struct{}
+
+func (*blsPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	pubKey, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	k, err := newBLSPublicKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	if pop := opts.(*bccsp.BLS12381PublicKeyImportOpts).ProofOfPossession; len(pop) != 0 {
+		if err = k.setProofOfPossession(pop); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+type schnorrPublicKeyImportOptsKeyImporter struct{}
+
+func (*schnorrPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	pubKey, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	name := opts.(*bccsp.SchnorrPublicKeyImportOpts).Curve
+	if name == "" {
+		name = "secp256k1"
+	}
+	info, ok := utils.LookupCurveByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown curve [%s]", name)
+	}
+	if err := checkSchnorrCurve(info.Curve); err != nil {
+		return nil, err
+	}
+
+	x, y, err := liftX(info.Curve, pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x-only public key [%s]", err)
+	}
+	return &schnorrPublicKey{&ecdsa.PublicKey{Curve: info.Curve, X: x, Y: y}}, nil
+}
+
+type pemKeyImportOptsKeyImporter struct{}
+
+func (*pemKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	pemRaw, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+	if len(pemRaw) == 0 {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+
+	return pemToKey(pemRaw)
+}
+
+type ed25519GoPublicKeyImportOptsKeyImporter struct{}
+
+func (*ed25519GoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	var lowLevelKey ed25519.PublicKey
+	switch pk := raw.(type) {
+	case ed25519.PublicKey:
+		lowLevelKey = pk
+	case *ed25519.PublicKey:
+		if pk == nil {
+			return nil, errors.New("invalid raw material, it must not be nil")
+		}
+		lowLevelKey = *pk
+	default:
+		return nil, errors.New("invalid raw material, expected ed25519.PublicKey")
+	}
+
+	if len(lowLevelKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 public key length [%d], must be %d bytes", len(lowLevelKey), ed25519.PublicKeySize)
+	}
+
+	return &ed25519PublicKey{&lowLevelKey}, nil
+}
+
+type rsaPKIXPublicKeyImportOptsKeyImporter struct{}
+
+func (*rsaPKIXPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	der, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(der) == 0 {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+
+	lowLevelKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting PKIX to RSA public key [%s]", err)
+	}
+
+	rsaPK, ok := lowLevelKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("failed casting to RSA public key, invalid raw material")
+	}
+
+	return &rsaPublicKey{rsaPK}, nil
+}
+
+type rsaGoPublicKeyImportOptsKeyImporter struct{}
+
+func (*rsaGoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	lowLevelKey, ok := raw.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected *rsa.PublicKey")
+	}
+
+	if lowLevelKey.N == nil {
+		return nil, errors.New("invalid raw material, the modulus must not be nil")
+	}
+
+	return &rsaPublicKey{lowLevelKey}, nil
+}
+
+type sm2PKIXPublicKeyImportOptsKeyImporter struct{}
+
+func (*sm2PKIXPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	der, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected byte array")
+	}
+
+	if len(der) == 0 {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+
+	lowLevelKey, err := gm.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting PKIX to SM2 public key [%s]", err)
+	}
+
+	return &sm2PublicKey{lowLevelKey}, nil
+}
+
+type sm2GoPublicKeyImportOptsKeyImporter struct{}
+
+func (*sm2GoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	lowLevelKey, ok := raw.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid raw material, expected *ecdsa.PublicKey")
+	}
+
+	if !gm.IsSM2Curve(lowLevelKey.Curve) || lowLevelKey.X == nil || lowLevelKey.Y == nil ||
+		!lowLevelKey.Curve.IsOnCurve(lowLevelKey.X, lowLevelKey.Y) {
+		return nil, errors.New("invalid raw material, the public key must be on the SM2 curve")
+	}
+
+	return &sm2PublicKey{lowLevelKey}, nil
+}
+
+// x509PublicKeyImportOptsKeyImporter 从X509证书中取出公钥，然后根据公钥的类型将导入工作
+// 交给相应的KeyImporter完成。
+type x509PublicKeyImportOptsKeyImporter struct {
+	bccsp *CSP
+}
+
+func (ki *x509PublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	var pk interface{}
+	switch cert := raw.(type) {
+	case *x509.Certificate:
+		pk = cert.PublicKey
+	case []byte:
+		// 优先用标准库解析证书，标准库无法解析时再尝试按照SM2证书解析。
+		if x509Cert, err := x509.ParseCertificate(cert); err == nil {
+			pk = x509Cert.PublicKey
+		} else if sm2PK, sm2Err := gm.ParseCertificatePublicKey(cert); sm2Err == nil {
+			pk = sm2PK
+		} else {
+			return nil, fmt.Errorf("failed parsing certificate [%s]", err)
+		}
+	default:
+		return nil, errors.New("invalid raw material, expected *x509.Certificate or DER encoded certificate")
+	}
+
+	switch pk := pk.(type) {
+	case *ecdsa.PublicKey:
+		if gm.IsSM2Curve(pk.Curve) {
+			return ki.bccsp.KeyImporters[reflect.TypeOf(&bccsp.SM2GoPublicKeyImportOpts{})].KeyImport(
+				pk,
+				&bccsp.SM2GoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
+		}
+		return ki.bccsp.KeyImporters[reflect.TypeOf(&bccsp.ECDSAGoPublicKeyImportOpts{})].KeyImport(
+			pk,
+			&bccsp.ECDSAGoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
+	case ed25519.PublicKey:
+		return ki.bccsp.KeyImporters[reflect.TypeOf(&bccsp.Ed25519GoPublicKeyImportOpts{})].KeyImport(
+			pk,
+			&bccsp.Ed25519GoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
+	case *rsa.PublicKey:
+		return ki.bccsp.KeyImporters[reflect.TypeOf(&bccsp.RSAGoPublicKeyImportOpts{})].KeyImport(
+			pk,
+			&bccsp.RSAGoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
+	default:
+		return nil, errors.New("certificate's public key type not recognized, supported keys: [ECDSA, Ed25519, RSA, SM2]")
+	}
+}
@@ -0,0 +1,345 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/utils"
+)
+
+// privateKeyToPEM 将私钥编码成PKCS#8格式的PEM块，如果口令pwd不为空，则利用opts从口令派生出加密密钥，
+// 以PBES2方案将其加密为"ENCRYPTED PRIVATE KEY"类型的PEM块。
+func privateKeyToPEM(privateKey interface{}, pwd []byte, opts *KDFOpts) ([]byte, error) {
+	der, err := privateKeyToDER(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pwd) == 0 {
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+
+	block, err := encryptPEMBlock("ENCRYPTED PRIVATE KEY", der, pwd, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed encrypting private key [%w]", err)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// privateKeyToDER 将私钥编码成PKCS#8格式的DER字节序列。
+func privateKeyToDER(privateKey interface{}) ([]byte, error) {
+	if privateKey == nil {
+		return nil, errors.New("invalid private key, it must be different from nil")
+	}
+
+	switch k := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		if k == nil {
+			return nil, errors.New("invalid ecdsa private key, it must be different from nil")
+		}
+		return utils.MarshalECPKCS8PrivateKey(k)
+	case *ed25519.PrivateKey:
+		if k == nil {
+			return nil, errors.New("invalid ed25519 private key, it must be different from nil")
+		}
+		return x509.MarshalPKCS8PrivateKey(*k)
+	case *x25519PrivateKey:
+		if k == nil {
+			return nil, errors.New("invalid x25519 private key, it must be different from nil")
+		}
+		return marshalX25519PKCS8PrivateKey(k.privKey)
+	default:
+		return nil, fmt.Errorf("invalid key type, it must be *ecdsa.PrivateKey, *ed25519.PrivateKey or an X25519 private key, but got [%T]", privateKey)
+	}
+}
+
+// pemToPrivateKey 从PEM块中解析出私钥，如果PEM块是加密的，则用口令pwd对其进行解密。
+func pemToPrivateKey(raw []byte, pwd []byte) (interface{}, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("failed decoding PEM, block must be different from nil [% x]", raw)
+	}
+
+	if block.Type != "ENCRYPTED PRIVATE KEY" {
+		return derToPrivateKey(block.Bytes)
+	}
+
+	der, err := decryptPEMBlock(block, pwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed decrypting private key: [%w]", err)
+	}
+	key, err := derToPrivateKey(der)
+	if err != nil {
+		// 填充正确但内容无法解析，说明使用了错误的口令。
+		return nil, fmt.Errorf("failed decrypting private key: [%w]", ErrIncorrectPassphrase)
+	}
+	return key, nil
+}
+
+// derToPrivateKey 依次尝试以PKCS#8和SEC1格式解析DER编码的私钥，X25519私钥以*x25519PrivateKey的形式返回。
+func derToPrivateKey(der []byte) (key interface{}, err error) {
+	// X25519私钥需要先于标准库解析，较新版本的标准库会将其解析为crypto/ecdh中的类型。
+	if priv, err := parseX25519PKCS8PrivateKey(der); err == nil {
+		return &x25519PrivateKey{priv}, nil
+	}
+
+	if key, err = x509.ParsePKCS8PrivateKey(der); err == nil {
+		switch k := key.(type) {
+		case *ecdsa.PrivateKey:
+			return k, nil
+		case ed25519.PrivateKey:
+			return &k, nil
+		default:
+			return nil, errors.New("found unknown private key type in PKCS#8 wrapping")
+		}
+	}
+
+	if key, err = x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	// 标准库无法解析的曲线交给曲线注册表解析。
+	if key, err = utils.ParseECPKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("invalid key type, the DER must contain an ecdsa.PrivateKey, an ed25519.PrivateKey or an X25519 private key")
+}
+
+// publicKeyToPEM 将公钥编码成PKIX格式的PEM块。
+func publicKeyToPEM(publicKey interface{}) ([]byte, error) {
+	if publicKey == nil {
+		return nil, errors.New("invalid public key, it must be different from nil")
+	}
+
+	switch k := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if k == nil {
+			return nil, errors.New("invalid ecdsa public key, it must be different from nil")
+		}
+		der, err := utils.MarshalECPKIXPublicKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case *ed25519.PublicKey:
+		if k == nil {
+			return nil, errors.New("invalid ed25519 public key, it must be different from nil")
+		}
+		der, err := x509.MarshalPKIXPublicKey(*k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case *rsa.PublicKey:
+		if k == nil {
+			return nil, errors.New("invalid rsa public key, it must be different from nil")
+		}
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case *x25519PublicKey:
+		if k == nil {
+			return nil, errors.New("invalid x25519 public key, it must be different from nil")
+		}
+		der, err := marshalX25519PKIXPublicKey(k.pubKey)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case *blsPublicKey:
+		if k == nil {
+			return nil, errors.New("invalid bls public key, it must be different from nil")
+		}
+		block := &pem.Block{Type: blsPublicKeyPEMType, Bytes: k.pubKey}
+		if len(k.pop) != 0 {
+			block.Headers = map[string]string{blsProofOfPossessionHeader: hex.EncodeToString(k.pop)}
+		}
+		return pem.EncodeToMemory(block), nil
+	default:
+		return nil, fmt.Errorf("invalid key type, it must be *ecdsa.PublicKey, *ed25519.PublicKey, *rsa.PublicKey, an X25519 or a BLS12-381 public key, but got [%T]", publicKey)
+	}
+}
+
+// pemToPublicKey 从PEM块中解析出公钥。
+func pemToPublicKey(raw []byte) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("invalid PEM, it must be different from nil")
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("failed decoding PEM, block must be different from nil [% x]", raw)
+	}
+
+	// BLS12-381公钥没有标准的PKIX编码，以压缩编码的形式保存在专门类型的PEM块中，持有证明保存在PEM头部，
+	// 读取时重新验证。
+	if block.Type == blsPublicKeyPEMType {
+		return pemToBLSPublicKey(block)
+	}
+
+	// X25519公钥需要先于标准库解析，原因与derToPrivateKey相同。
+	if pub, err := parseX25519PKIXPublicKey(block.Bytes); err == nil {
+		return &x25519PublicKey{pub}, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		// 标准库无法解析的曲线交给曲线注册表解析。
+		if ecKey, ecErr := utils.ParseECPKIXPublicKey(block.Bytes); ecErr == nil {
+			return ecKey, nil
+		}
+		return nil, fmt.Errorf("failed parsing PKIX public key [%s]", err)
+	}
+
+	// 统一以指针的形式返回Ed25519公钥，与ECDSA公钥保持一致。
+	if k, ok := key.(ed25519.PublicKey); ok {
+		return &k, nil
+	}
+
+	return key, nil
+}
+
+// pemToBLSPublicKey 从PEM块中解析出BLS12-381公钥，如果PEM头部带有持有证明，则验证它。
+func pemToBLSPublicKey(block *pem.Block) (*blsPublicKey, error) {
+	k, err := newBLSPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if h, ok := block.Headers[blsProofOfPossessionHeader]; ok {
+		pop, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding proof of possession [%s]", err)
+		}
+		if err = k.setProofOfPossession(pop); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// 对称密钥的PEM块类型，加密后的PEM块类型在前面加上"ENCRYPTED "前缀。BLS12-381私钥没有标准的PKCS#8编码，
+// 它的32字节编码与对称密钥一样以专门类型的PEM块保存。
+const (
+	aesKeyPEMType              = "AES PRIVATE KEY"
+	sm4KeyPEMType              = "SM4 PRIVATE KEY"
+	chacha20Poly1305KeyPEMType = "CHACHA20-POLY1305 PRIVATE KEY"
+	blsPrivateKeyPEMType       = "BLS12-381 PRIVATE KEY"
+	blsPublicKeyPEMType        = "BLS12-381 PUBLIC KEY"
+	encryptedPEMTypePrefix     = "ENCRYPTED "
+)
+
+// errNotExportable 表示对称密钥在生成或导入时没有被标记为可导出的。
+var errNotExportable = errors.New("symmetric key is not exportable")
+
+// blsProofOfPossessionHeader 是BLS12-381公钥的PEM块中保存持有证明的头部字段。
+const blsProofOfPossessionHeader = "Proof-Of-Possession"
+
+// symmetricKeyToPEM 将对称密钥编码成blockType类型的PEM块，如果口令pwd不为空，则利用opts从口令派生出加密
+// 密钥，以PBES2方案将其加密为"ENCRYPTED "+blockType类型的PEM块，被加密的内容是ASN.1编码的OCTET STRING。
+func symmetricKeyToPEM(blockType string, raw []byte, pwd []byte, opts *KDFOpts) ([]byte, error) {
+	if len(pwd) == 0 {
+		return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: raw}), nil
+	}
+
+	der, err := asn1.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	block, err := encryptPEMBlock(encryptedPEMTypePrefix+blockType, der, pwd, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed encrypting symmetric key [%w]", err)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// pemToSymmetricKey 从PEM块中解析出对称密钥，如果PEM块是加密的，则用口令pwd对其进行解密。返回的blockType
+// 是去掉"ENCRYPTED "前缀以后的PEM块类型，用于区分密钥的种类。
+func pemToSymmetricKey(raw []byte, pwd []byte) (blockType string, key []byte, err error) {
+	if len(raw) == 0 {
+		return "", nil, errors.New("invalid PEM, it must be different from nil")
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return "", nil, fmt.Errorf("failed decoding PEM, block must be different from nil [% x]", raw)
+	}
+
+	if !strings.HasPrefix(block.Type, encryptedPEMTypePrefix) {
+		return block.Type, block.Bytes, nil
+	}
+	blockType = strings.TrimPrefix(block.Type, encryptedPEMTypePrefix)
+
+	der, err := decryptPEMBlock(block, pwd)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed decrypting symmetric key: [%w]", err)
+	}
+	if rest, err := asn1.Unmarshal(der, &key); err != nil || len(rest) != 0 {
+		return "", nil, fmt.Errorf("failed decrypting symmetric key: [%w]", ErrIncorrectPassphrase)
+	}
+	return blockType, key, nil
+}
+
+// keyToPEM 将私钥或对称密钥编码成未加密的PEM块，编码方式与KeyStore保存密钥的方式相同。对称密钥与Bytes方法
+// 一样，只有可导出时才能被编码。
+func keyToPEM(k bccsp.Key) ([]byte, error) {
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		return privateKeyToPEM(kk.privKey, nil, nil)
+	case *sm2PrivateKey:
+		return privateKeyToPEM(kk.privKey, nil, nil)
+	case *ed25519PrivateKey:
+		return privateKeyToPEM(kk.privKey, nil, nil)
+	case *x25519PrivateKey:
+		return privateKeyToPEM(kk, nil, nil)
+	case *blsPrivateKey:
+		return symmetricKeyToPEM(blsPrivateKeyPEMType, kk.privKey, nil, nil)
+	case *aesPrivateKey:
+		if !kk.exportable {
+			return nil, errNotExportable
+		}
+		return symmetricKeyToPEM(aesKeyPEMType, kk.privKey, nil, nil)
+	case *sm4PrivateKey:
+		if !kk.exportable {
+			return nil, errNotExportable
+		}
+		return symmetricKeyToPEM(sm4KeyPEMType, kk.privKey, nil, nil)
+	case *chacha20Poly1305Key:
+		if !kk.exportable {
+			return nil, errNotExportable
+		}
+		return symmetricKeyToPEM(chacha20Poly1305KeyPEMType, kk.privKey, nil, nil)
+	default:
+		return nil, fmt.Errorf("key type [%T] cannot be exported, only private and symmetric keys are supported", k)
+	}
+}
+
+// pemToKey 从未加密的PEM块中解析出私钥或对称密钥，PEM块的类型决定了密钥的种类。
+func pemToKey(raw []byte) (bccsp.Key, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("failed decoding PEM, block must be different from nil")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY", "EC PRIVATE KEY":
+		key, err := derToPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return privateKeyToBCCSPKey(key)
+	case aesKeyPEMType, sm4KeyPEMType, chacha20Poly1305KeyPEMType, blsPrivateKeyPEMType:
+		return symmetricKeyToBCCSPKey(block.Type, utils.Clone(block.Bytes))
+	default:
+		return nil, fmt.Errorf("PEM block type [%s] not supported, encrypted keys must be decrypted first", block.Type)
+	}
+}
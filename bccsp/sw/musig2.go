@@ -0,0 +1,394 @@
+package sw
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/utils"
+)
+
+// musig2NonceSize 是MuSig2公开nonce的字节长度，即两个压缩格式的点R1 || R2。
+const musig2NonceSize = 66
+
+// musig2SignerPublicKey 从签名者的密钥中取出ECDSA公钥，密钥可以来自其他的BCCSP实现（例如PKCS#11）。
+func musig2SignerPublicKey(k bccsp.Key) (*ecdsa.PublicKey, error) {
+	if k == nil {
+		return nil, errors.New("invalid key, it must not be nil")
+	}
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		return &kk.privKey.PublicKey, nil
+	case *ecdsaPublicKey:
+		return kk.pubKey, nil
+	}
+
+	if k.Private() {
+		var err error
+		if k, err = k.PublicKey(); err != nil {
+			return nil, fmt.Errorf("failed getting public key [%w]", err)
+		}
+	}
+	raw, err := k.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling public key [%w]", err)
+	}
+	pub, err := utils.ParseECPKIXPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key, expected an ECDSA public key [%w]", err)
+	}
+	return pub, nil
+}
+
+// musig2KeyAggContext 是BIP-327中KeyAgg的结果，记录了每个签名者的公钥、系数以及聚合公钥Q。
+type musig2KeyAggContext struct {
+	curve elliptic.Curve
+	pubs  []*ecdsa.PublicKey
+	keys  [][]byte
+	coefs []*big.Int
+	// Q 是聚合公钥，签名时使用的是y坐标为偶数的Q，negated表示Q的y坐标是否为奇数。
+	qx, qy  *big.Int
+	negated bool
+}
+
+// musig2KeyAgg 按照BIP-327聚合签名者的公钥：Q = Σ a_i·P_i，其中a_i = H(L || P_i)，L是所有公钥的哈希值，
+// 第二个与第一个公钥不同的公钥的系数为1。
+func musig2KeyAgg(keys []bccsp.Key) (*musig2KeyAggContext, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("invalid public keys, at least one key is required")
+	}
+
+	ctx := &musig2KeyAggContext{}
+	for i, k := range keys {
+		pub, err := musig2SignerPublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key at index [%d]: [%w]", i, err)
+		}
+		if i == 0 {
+			if err = checkSchnorrCurve(pub.Curve); err != nil {
+				return nil, err
+			}
+			ctx.curve = pub.Curve
+		} else if pub.Curve.Params() != ctx.curve.Params() {
+			return nil, fmt.Errorf("invalid public key at index [%d], curve [%s] does not match [%s]", i, pub.Curve.Params().Name, ctx.curve.Params().Name)
+		}
+		ctx.pubs = append(ctx.pubs, pub)
+		ctx.keys = append(ctx.keys, elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y))
+	}
+
+	var second []byte
+	for _, pk := range ctx.keys[1:] {
+		if !bytes.Equal(pk, ctx.keys[0]) {
+			second = pk
+			break
+		}
+	}
+
+	c := ctx.curve
+	l := taggedHash("KeyAgg list", ctx.keys...)
+	ctx.qx, ctx.qy = new(big.Int), new(big.Int)
+	for i, pk := range ctx.keys {
+		a := big.NewInt(1)
+		if !bytes.Equal(pk, second) {
+			a = hashToScalar(c, "KeyAgg coefficient", l, pk)
+		}
+		ctx.coefs = append(ctx.coefs, a)
+
+		x, y := c.ScalarMult(ctx.pubs[i].X, ctx.pubs[i].Y, scalarBytes(a))
+		ctx.qx, ctx.qy = c.Add(ctx.qx, ctx.qy, x, y)
+	}
+	if isInfinity(ctx.qx, ctx.qy) {
+		return nil, errors.New("invalid aggregated public key, point at infinity")
+	}
+	ctx.negated = !hasEvenY(ctx.qy)
+
+	return ctx, nil
+}
+
+// musig2Session 是一次MuSig2签名会话中所有签名者共享的值：聚合nonce的系数b、最终的nonce R和挑战e。
+type musig2Session struct {
+	ctx    *musig2KeyAggContext
+	r1, r2 [][2]*big.Int
+	b, e   *big.Int
+	rx, ry *big.Int
+}
+
+// newMuSig2Session 聚合所有签名者的公开nonce，并计算签名会话的共享值。
+func newMuSig2Session(keys []bccsp.Key, pubNonces [][]byte, msg []byte) (*musig2Session, error) {
+	ctx, err := musig2KeyAgg(keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(pubNonces) != len(keys) {
+		return nil, fmt.Errorf("invalid public nonces, expected [%d] nonces, but got [%d]", len(keys), len(pubNonces))
+	}
+
+	c := ctx.curve
+	sess := &musig2Session{ctx: ctx}
+	r1x, r1y, r2x, r2y := new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	for i, pubNonce := range pubNonces {
+		if len(pubNonce) != musig2NonceSize {
+			return nil, fmt.Errorf("invalid public nonce length [%d] at index [%d], must be %d bytes", len(pubNonce), i, musig2NonceSize)
+		}
+		x1, y1, err := unmarshalCompressedPoint(c, pubNonce[:33])
+		if err != nil {
+			return nil, fmt.Errorf("invalid public nonce at index [%d]: [%s]", i, err)
+		}
+		x2, y2, err := unmarshalCompressedPoint(c, pubNonce[33:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid public nonce at index [%d]: [%s]", i, err)
+		}
+		sess.r1 = append(sess.r1, [2]*big.Int{x1, y1})
+		sess.r2 = append(sess.r2, [2]*big.Int{x2, y2})
+		r1x, r1y = c.Add(r1x, r1y, x1, y1)
+		r2x, r2y = c.Add(r2x, r2y, x2, y2)
+	}
+
+	// 聚合nonce中的无穷远点编码为33字节的0。
+	aggNonce := make([]byte, 0, musig2NonceSize)
+	for _, r := range [][2]*big.Int{{r1x, r1y}, {r2x, r2y}} {
+		if isInfinity(r[0], r[1]) {
+			aggNonce = append(aggNonce, make([]byte, 33)...)
+		} else {
+			aggNonce = append(aggNonce, elliptic.MarshalCompressed(c, r[0], r[1])...)
+		}
+	}
+
+	qx := scalarBytes(ctx.qx)
+	sess.b = hashToScalar(c, "MuSig/noncecoef", aggNonce, qx, msg)
+	bx, by := c.ScalarMult(r2x, r2y, scalarBytes(sess.b))
+	sess.rx, sess.ry = c.Add(r1x, r1y, bx, by)
+	if isInfinity(sess.rx, sess.ry) {
+		sess.rx, sess.ry = c.Params().Gx, c.Params().Gy
+	}
+	sess.e = hashToScalar(c, "BIP0340/challenge", scalarBytes(sess.rx), qx, msg)
+
+	return sess, nil
+}
+
+// signMuSig2 生成本方的部分签名s = k1 + b·k2 + e·a·d mod n，其中nonce和私钥d会根据R和Q的y坐标的奇偶性取相反数。
+func signMuSig2(k *ecdsa.PrivateKey, msg []byte, opts *bccsp.MuSig2SignerOpts) ([]byte, error) {
+	nonce, ok := opts.Nonce.(*musig2SecretNonce)
+	if !ok {
+		return nil, fmt.Errorf("invalid nonce, expected a MuSig2 secret nonce, but got [%T]", opts.Nonce)
+	}
+	// 在做任何其他事情之前先取出nonce，从这里开始nonce就失效了，即使签名失败或者调用者再次传入同一个nonce也
+	// 无法签名。
+	k1, k2 := nonce.take()
+	if k1 == nil {
+		return nil, errors.New("invalid nonce, it has already been used")
+	}
+	if !nonce.pubKey.Equal(&k.PublicKey) {
+		return nil, errors.New("invalid nonce, it was generated for another key")
+	}
+
+	sess, err := newMuSig2Session(opts.PublicKeys, opts.PublicNonces, msg)
+	if err != nil {
+		return nil, err
+	}
+	pk := elliptic.MarshalCompressed(k.Curve, k.X, k.Y)
+	index := -1
+	for i := range sess.ctx.keys {
+		if bytes.Equal(sess.ctx.keys[i], pk) && bytes.Equal(opts.PublicNonces[i], nonce.pubNonce) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("invalid signer, its public key and nonce are not part of the session")
+	}
+
+	n := k.Curve.Params().N
+	if !hasEvenY(sess.ry) {
+		k1 = new(big.Int).Sub(n, k1)
+		k2 = new(big.Int).Sub(n, k2)
+	}
+	d := new(big.Int).Set(k.D)
+	if sess.ctx.negated {
+		d.Sub(n, d)
+	}
+
+	s := new(big.Int).Mul(sess.e, sess.ctx.coefs[index])
+	s.Mul(s, d)
+	s.Add(s, k1)
+	s.Add(s, new(big.Int).Mul(sess.b, k2))
+	s.Mod(s, n)
+
+	return scalarBytes(s), nil
+}
+
+// verifyMuSig2 验证签名者pub的部分签名，检查s·G = R1 + b·R2 + e·a·P是否成立，其中的点会根据R和Q的y坐标的
+// 奇偶性取相反数。
+func verifyMuSig2(pub *ecdsa.PublicKey, partial, msg []byte, opts *bccsp.MuSig2SignerOpts) (bool, error) {
+	if len(partial) != 32 {
+		return false, fmt.Errorf("invalid partial signature length [%d], must be 32 bytes", len(partial))
+	}
+	sess, err := newMuSig2Session(opts.PublicKeys, opts.PublicNonces, msg)
+	if err != nil {
+		return false, err
+	}
+
+	c := sess.ctx.curve
+	s := new(big.Int).SetBytes(partial)
+	if s.Cmp(c.Params().N) >= 0 {
+		return false, nil
+	}
+	sx, sy := c.ScalarBaseMult(partial)
+
+	pk := elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y)
+	found := false
+	for i := range sess.ctx.keys {
+		// 同一个公钥可能出现多次，只要有一个位置上的nonce能够验证通过即可。
+		if !bytes.Equal(sess.ctx.keys[i], pk) {
+			continue
+		}
+		found = true
+
+		bx, by := c.ScalarMult(sess.r2[i][0], sess.r2[i][1], scalarBytes(sess.b))
+		rx, ry := c.Add(sess.r1[i][0], sess.r1[i][1], bx, by)
+		if !hasEvenY(sess.ry) {
+			rx, ry = negatePoint(c, rx, ry)
+		}
+		px, py := pub.X, pub.Y
+		if sess.ctx.negated {
+			px, py = negatePoint(c, px, py)
+		}
+		ea := new(big.Int).Mul(sess.e, sess.ctx.coefs[i])
+		ea.Mod(ea, c.Params().N)
+		ex, ey := c.ScalarMult(px, py, scalarBytes(ea))
+		ex, ey = c.Add(rx, ry, ex, ey)
+
+		if ex.Cmp(sx) == 0 && ey.Cmp(sy) == 0 {
+			return true, nil
+		}
+	}
+	if !found {
+		return false, errors.New("invalid signer, its public key is not part of the session")
+	}
+	return false, nil
+}
+
+type musig2NonceGenerator struct{}
+
+// KeyGen 按照BIP-327的NonceGen算法生成两个nonce k1和k2，公开nonce为k1·G || k2·G。除了32字节的随机数以外，
+// 签名者的私钥（如果opts.PublicKey是软件私钥）、聚合公钥和消息也会被混入nonce中，即使随机数生成器有缺陷，
+// 不同的会话也不会得到相同的nonce。
+func (kg *musig2NonceGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	nonceOpts := opts.(*bccsp.MuSig2NonceGenOpts)
+	pub, err := musig2SignerPublicKey(nonceOpts.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	c := pub.Curve
+	if err = checkSchnorrCurve(c); err != nil {
+		return nil, err
+	}
+
+	var sk, aggpk []byte
+	if k, ok := nonceOpts.PublicKey.(*ecdsaPrivateKey); ok {
+		sk = scalarBytes(k.privKey.D)
+	}
+	if nonceOpts.AggregateKey != nil {
+		if aggpk, err = nonceOpts.AggregateKey.Bytes(); err != nil {
+			return nil, fmt.Errorf("failed marshalling aggregate key [%w]", err)
+		}
+		if len(aggpk) != 32 {
+			return nil, fmt.Errorf("invalid aggregate key length [%d], expected a 32 bytes x-only public key", len(aggpk))
+		}
+	}
+
+	rnd := make([]byte, 32)
+	if _, err = rand.Read(rnd); err != nil {
+		return nil, fmt.Errorf("failed generating MuSig2 nonce: [%s]", err)
+	}
+	k1, k2 := musig2NonceGen(c, rnd, sk, elliptic.MarshalCompressed(c, pub.X, pub.Y), aggpk, nonceOpts.Message, nil)
+	if k1.Sign() == 0 || k2.Sign() == 0 {
+		return nil, errors.New("failed generating MuSig2 nonce, nonce is zero")
+	}
+
+	nonce := &musig2SecretNonce{k1: k1, k2: k2, pubKey: pub}
+	for _, k := range []*big.Int{k1, k2} {
+		x, y := c.ScalarBaseMult(scalarBytes(k))
+		nonce.pubNonce = append(nonce.pubNonce, elliptic.MarshalCompressed(c, x, y)...)
+	}
+
+	return nonce, nil
+}
+
+// musig2NonceGen 实现BIP-327的NonceGen算法：
+//
+//	rand = sk XOR hash_MuSig/aux(rnd)，sk为空时rand = rnd
+//	k_i = hash_MuSig/nonce(rand || len(pk) || pk || len(aggpk) || aggpk || msg_prefixed || len(extra_in) || extra_in || i-1) mod n
+//
+// 其中msg为nil时msg_prefixed = 0x00，否则msg_prefixed = 0x01 || 8字节的len(msg) || msg，len(extra_in)是4字节的长度。
+func musig2NonceGen(c elliptic.Curve, rnd, sk, pk, aggpk, msg, extraIn []byte) (k1, k2 *big.Int) {
+	r := utils.Clone(rnd)
+	if len(sk) != 0 {
+		r = taggedHash("MuSig/aux", rnd)
+		for i := range r {
+			r[i] ^= sk[i]
+		}
+	}
+
+	msgPrefixed := []byte{0}
+	if msg != nil {
+		msgPrefixed = make([]byte, 9, 9+len(msg))
+		msgPrefixed[0] = 1
+		binary.BigEndian.PutUint64(msgPrefixed[1:], uint64(len(msg)))
+		msgPrefixed = append(msgPrefixed, msg...)
+	}
+
+	extraLen := make([]byte, 4)
+	binary.BigEndian.PutUint32(extraLen, uint32(len(extraIn)))
+	nonce := func(i byte) *big.Int {
+		return hashToScalar(c, "MuSig/nonce", r, []byte{byte(len(pk))}, pk, []byte{byte(len(aggpk))}, aggpk, msgPrefixed, extraLen, extraIn, []byte{i})
+	}
+	return nonce(0), nonce(1)
+}
+
+type musig2Aggregator struct{}
+
+// AggregateSignatures 将所有签名者的部分签名相加，得到BIP-340签名R.x || Σs_i。
+func (a *musig2Aggregator) AggregateSignatures(signatures [][]byte, opts bccsp.AggregateOpts) ([]byte, error) {
+	musig2Opts := opts.(*bccsp.MuSig2AggregateOpts)
+	sess, err := newMuSig2Session(musig2Opts.PublicKeys, musig2Opts.PublicNonces, musig2Opts.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if len(signatures) != len(musig2Opts.PublicKeys) {
+		return nil, fmt.Errorf("invalid partial signatures, expected [%d] signatures, but got [%d]", len(musig2Opts.PublicKeys), len(signatures))
+	}
+
+	n := sess.ctx.curve.Params().N
+	s := new(big.Int)
+	for i, signature := range signatures {
+		si := new(big.Int).SetBytes(signature)
+		if len(signature) != 32 || si.Cmp(n) >= 0 {
+			return nil, fmt.Errorf("invalid partial signature at index [%d]", i)
+		}
+		s.Add(s, si)
+	}
+	s.Mod(s, n)
+
+	return append(scalarBytes(sess.rx), scalarBytes(s)...), nil
+}
+
+// AggregatePublicKeys 聚合签名者的公钥，返回y坐标为偶数的x-only聚合公钥。
+func (a *musig2Aggregator) AggregatePublicKeys(keys []bccsp.Key, opts bccsp.AggregateOpts) (bccsp.Key, error) {
+	ctx, err := musig2KeyAgg(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	qx, qy := ctx.qx, ctx.qy
+	if ctx.negated {
+		qx, qy = negatePoint(ctx.curve, qx, qy)
+	}
+	return &schnorrPublicKey{&ecdsa.PublicKey{Curve: ctx.curve, X: qx, Y: qy}}, nil
+}
@@ -0,0 +1,134 @@
+package sw
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"reflect"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+	"github.com/232425wxy/lark/bccsp/secp256k1"
+	"golang.org/x/crypto/sha3"
+)
+
+// NewDefaultSecurityLevel 以默认的安全级别(256)和哈希族(SHA2)创建一个基于软件的BCCSP，密钥存储在
+// keyStorePath目录下。
+func NewDefaultSecurityLevel(keyStorePath string) (bccsp.BCCSP, error) {
+	ks, err := NewFileBasedKeyStore(keyStorePath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDefaultSecurityLevelWithKeystore(ks)
+}
+
+// NewDefaultSecurityLevelWithKeystore 以默认的安全级别(256)和哈希族(SHA2)创建一个基于软件的BCCSP。
+func NewDefaultSecurityLevelWithKeystore(keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	return NewWithParams(256, bccsp.SHA2, keyStore)
+}
+
+// NewWithParams 以给定的安全级别、哈希族和KeyStore创建一个基于软件的BCCSP，并注册所有支持的算法实现。
+func NewWithParams(securityLevel int, hashFamily string, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	conf := &config{}
+	if err := conf.setSecurityLevel(securityLevel, hashFamily); err != nil {
+		return nil, fmt.Errorf("failed initializing configuration at [%d, %s]: [%w]", securityLevel, hashFamily, err)
+	}
+
+	swbccsp, err := New(keyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	// 注册加密器
+	swbccsp.AddWrapper(reflect.TypeOf(&aesPrivateKey{}), &aesEncryptor{})
+	swbccsp.AddWrapper(reflect.TypeOf(&sm4PrivateKey{}), &sm4Encryptor{})
+	swbccsp.AddWrapper(reflect.TypeOf(&chacha20Poly1305Key{}), &chacha20Poly1305Encryptor{})
+
+	// 注册解密器
+	swbccsp.AddWrapper(reflect.TypeOf(&aesPrivateKey{}), &aesDecryptor{})
+	swbccsp.AddWrapper(reflect.TypeOf(&sm4PrivateKey{}), &sm4Decryptor{})
+	swbccsp.AddWrapper(reflect.TypeOf(&chacha20Poly1305Key{}), &chacha20Poly1305Decryptor{})
+
+	// 注册签名器
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaSigner{})
+	swbccsp.AddWrapper(reflect.TypeOf(&ed25519PrivateKey{}), &ed25519Signer{})
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2Signer{})
+	swbccsp.AddWrapper(reflect.TypeOf(&blsPrivateKey{}), &blsSigner{})
+
+	// 注册验证器
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaPrivateKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPublicKey{}), &ecdsaPublicKeyKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&ed25519PrivateKey{}), &ed25519PrivateKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&ed25519PublicKey{}), &ed25519PublicKeyKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&rsaPublicKey{}), &rsaPublicKeyKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2PrivateKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PublicKey{}), &sm2PublicKeyKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&blsPrivateKey{}), &blsPrivateKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&blsPublicKey{}), &blsPublicKeyKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&schnorrPublicKey{}), &schnorrPublicKeyKeyVerifier{})
+
+	// 注册密钥派生器
+	hkdfDeriver := &hkdfKeyDeriver{bccsp: swbccsp}
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaPrivateKeyKeyDeriver{hkdf: hkdfDeriver})
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPublicKey{}), &ecdsaPublicKeyKeyDeriver{})
+	swbccsp.AddWrapper(reflect.TypeOf(&x25519PrivateKey{}), &x25519PrivateKeyKeyDeriver{hkdf: hkdfDeriver})
+	swbccsp.AddWrapper(reflect.TypeOf(&aesPrivateKey{}), &aesPrivateKeyKeyDeriver{conf: conf, hkdf: hkdfDeriver})
+	swbccsp.AddWrapper(reflect.TypeOf(&sm4PrivateKey{}), hkdfDeriver)
+	swbccsp.AddWrapper(reflect.TypeOf(&chacha20Poly1305Key{}), hkdfDeriver)
+
+	// 注册哈希函数
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHAOpts{}), &hasher{hash: conf.hashFunction})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA256Opts{}), &hasher{hash: sha256.New})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA384Opts{}), &hasher{hash: sha512.New384})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA3_256Opts{}), &hasher{hash: sha3.New256})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA3_384Opts{}), &hasher{hash: sha3.New384})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM3Opts{}), &hasher{hash: gm.NewSM3})
+
+	// 注册密钥生成器
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAKeyGenOpts{}), &ecdsaKeyGenerator{curve: conf.ellipticCurve})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP224KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P224()})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP256KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P256()})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP384KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P384()})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP521KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P521()})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSASecp256k1KeyGenOpts{}), &ecdsaKeyGenerator{curve: secp256k1.S256()})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.Ed25519KeyGenOpts{}), &ed25519KeyGenerator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.X25519KeyGenOpts{}), &x25519KeyGenerator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2KeyGenOpts{}), &sm2KeyGenerator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.BLS12381KeyGenOpts{}), &blsKeyGenerator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.MuSig2NonceGenOpts{}), &musig2NonceGenerator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AESKeyGenOpts{}), &aesKeyGenerator{length: conf.aesByteLength})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES256KeyGenOpts{}), &aesKeyGenerator{length: 32})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES192KeyGenOpts{}), &aesKeyGenerator{length: 24})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES128KeyGenOpts{}), &aesKeyGenerator{length: 16})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM4KeyGenOpts{}), &sm4KeyGenerator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ChaCha20Poly1305KeyGenOpts{}), &chacha20Poly1305KeyGenerator{})
+
+	// 注册密钥导入器
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES256ImportKeyOpts{}), &aes256ImportKeyOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.HMACImportKeyOpts{}), &hmacImportKeyOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM4ImportKeyOpts{}), &sm4ImportKeyOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ChaCha20Poly1305ImportKeyOpts{}), &chacha20Poly1305ImportKeyOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAPKIXPublicKeyImportOpts{}), &ecdsaPKIXPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAGoPublicKeyImportOpts{}), &ecdsaGoPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSASecp256k1PublicKeyImportOpts{}), &ecdsaSecp256k1PublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.Ed25519PKIXPublicKeyImportOpts{}), &ed25519PKIXPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.Ed25519GoPublicKeyImportOpts{}), &ed25519GoPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.X25519PublicKeyImportOpts{}), &x25519PublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.RSAPKIXPublicKeyImportOpts{}), &rsaPKIXPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.RSAGoPublicKeyImportOpts{}), &rsaGoPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2PKIXPublicKeyImportOpts{}), &sm2PKIXPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2GoPublicKeyImportOpts{}), &sm2GoPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.BLS12381PrivateKeyImportOpts{}), &blsPrivateKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.BLS12381PublicKeyImportOpts{}), &blsPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SchnorrPublicKeyImportOpts{}), &schnorrPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.PEMKeyImportOpts{}), &pemKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.X509PublicKeyImportOpts{}), &x509PublicKeyImportOptsKeyImporter{bccsp: swbccsp})
+
+	// 注册聚合器
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.BLS12381AggregateOpts{}), &blsAggregator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.MuSig2AggregateOpts{}), &musig2Aggregator{})
+
+	return swbccsp, nil
+}
@@ -0,0 +1,300 @@
+package sw
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PBKDF2 代表PKCS#5 v2.0中定义的基于口令的密钥派生函数，采用HMAC-SHA256作为伪随机函数。
+	PBKDF2 = "PBKDF2"
+
+	// Scrypt 代表RFC 7914中定义的scrypt密钥派生函数。
+	Scrypt = "SCRYPT"
+)
+
+// 密钥派生参数的上限。密钥文件中的参数在派生之前都要检查，防止被篡改或损坏的密钥文件耗尽CPU或内存。
+const (
+	maxPBKDF2Iterations = 1 << 24
+	maxScryptN          = 1 << 20
+	maxScryptR          = 32
+	maxScryptP          = 16
+	// maxScryptMemory 是scrypt占用内存128·N·r的上限。
+	maxScryptMemory = 1 << 30
+)
+
+// ErrIncorrectPassphrase 表示无法用给定的口令解密密钥，通常是因为口令错误。
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+// KDFOpts 包含从口令派生密钥加密密钥的选项，派生出的密钥用于以AES-256-CBC模式加密存储在
+// KeyStore中的密钥。
+type KDFOpts struct {
+	// Algorithm 是密钥派生函数，取值为PBKDF2或Scrypt。
+	Algorithm string
+	// SaltSize 是随机盐的字节长度。
+	SaltSize int
+
+	// Iterations 是PBKDF2的迭代次数。
+	Iterations int
+
+	// N 是scrypt的CPU/内存开销参数，必须是大于1的2的幂。
+	N int
+	// R 是scrypt的块大小参数。
+	R int
+	// P 是scrypt的并行化参数。
+	P int
+}
+
+// DefaultKDFOpts 返回默认的密钥派生选项：scrypt，N=32768，r=8，p=1，盐长16字节。
+func DefaultKDFOpts() *KDFOpts {
+	return &KDFOpts{
+		Algorithm: Scrypt,
+		SaltSize:  16,
+		N:         1 << 15,
+		R:         8,
+		P:         1,
+	}
+}
+
+// Validate 检查密钥派生选项是否合法。
+func (opts *KDFOpts) Validate() error {
+	if opts.SaltSize < 8 {
+		return fmt.Errorf("invalid salt size [%d], must be at least 8 bytes", opts.SaltSize)
+	}
+
+	switch opts.Algorithm {
+	case PBKDF2:
+		return checkPBKDF2Params(opts.Iterations)
+	case Scrypt:
+		return checkScryptParams(opts.N, opts.R, opts.P)
+	default:
+		return fmt.Errorf("key derivation function not recognized [%s]", opts.Algorithm)
+	}
+}
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidScrypt         = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo 是RFC 5958中定义的EncryptedPrivateKeyInfo结构。
+type encryptedPrivateKeyInfo struct {
+	EncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+// pbes2Params 是RFC 8018中定义的PBES2-params结构。
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params 是RFC 8018中定义的PBKDF2-params结构。
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// scryptParams 是RFC 7914中定义的scrypt-params结构。
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+// encryptPEMBlock 利用从口令pwd派生出的密钥，以PBES2方案加密data，并将结果编码为PEM块。
+func encryptPEMBlock(blockType string, data, pwd []byte, opts *KDFOpts) (*pem.Block, error) {
+	if len(pwd) == 0 {
+		return nil, errors.New("invalid passphrase, it must not be empty")
+	}
+	if opts == nil {
+		opts = DefaultKDFOpts()
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	salt, err := GetRandomBytes(opts.SaltSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating salt [%s]", err)
+	}
+
+	var kdf pkix.AlgorithmIdentifier
+	var key []byte
+	switch opts.Algorithm {
+	case PBKDF2:
+		params, err := asn1.Marshal(pbkdf2Params{
+			Salt:           salt,
+			IterationCount: opts.Iterations,
+			PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+		})
+		if err != nil {
+			return nil, err
+		}
+		kdf = pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: params}}
+		key = pbkdf2.Key(pwd, salt, opts.Iterations, 32, sha256.New)
+	case Scrypt:
+		params, err := asn1.Marshal(scryptParams{
+			Salt:                     salt,
+			CostParameter:            opts.N,
+			BlockSize:                opts.R,
+			ParallelizationParameter: opts.P,
+		})
+		if err != nil {
+			return nil, err
+		}
+		kdf = pkix.AlgorithmIdentifier{Algorithm: oidScrypt, Parameters: asn1.RawValue{FullBytes: params}}
+		if key, err = scrypt.Key(pwd, salt, opts.N, opts.R, opts.P, 32); err != nil {
+			return nil, fmt.Errorf("failed deriving key with scrypt [%s]", err)
+		}
+	}
+
+	iv, err := GetRandomBytes(aes.BlockSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating IV [%s]", err)
+	}
+	ciphertext, err := aesCBCEncryptWithIV(iv, key, pkcs7Padding(data))
+	if err != nil {
+		return nil, err
+	}
+
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: kdf,
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		// aesCBCEncryptWithIV得到的密文的前16个字节是IV，IV已经记录在参数里了。
+		EncryptedData: ciphertext[aes.BlockSize:],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pem.Block{Type: blockType, Bytes: der}, nil
+}
+
+// decryptPEMBlock 利用从口令pwd派生出的密钥，解密以PBES2方案加密的PEM块。如果口令错误，返回的错误
+// 包装了ErrIncorrectPassphrase。
+func decryptPEMBlock(block *pem.Block, pwd []byte) ([]byte, error) {
+	if len(pwd) == 0 {
+		return nil, errors.New("encrypted key, a passphrase is required")
+	}
+
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling EncryptedPrivateKeyInfo [%s]", err)
+	}
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("encryption scheme not supported [%s], only PBES2 is supported", info.EncryptionAlgorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling PBES2 parameters [%s]", err)
+	}
+	if !params.EncryptionScheme.Algorithm.Equal(oidAES256CBC) {
+		return nil, fmt.Errorf("cipher not supported [%s], only AES-256-CBC is supported", params.EncryptionScheme.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling IV [%s]", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid IV length [%d]", len(iv))
+	}
+
+	var key []byte
+	switch kdf := params.KeyDerivationFunc; {
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var p pbkdf2Params
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &p); err != nil {
+			return nil, fmt.Errorf("failed unmarshalling PBKDF2 parameters [%s]", err)
+		}
+		// 缺省的伪随机函数是hmacWithSHA1，这里只支持hmacWithSHA256。
+		if !p.PRF.Algorithm.Equal(oidHMACWithSHA256) {
+			return nil, fmt.Errorf("PBKDF2 pseudorandom function not supported [%s]", p.PRF.Algorithm)
+		}
+		if err := checkPBKDF2Params(p.IterationCount); err != nil {
+			return nil, err
+		}
+		key = pbkdf2.Key(pwd, p.Salt, p.IterationCount, 32, sha256.New)
+	case kdf.Algorithm.Equal(oidScrypt):
+		var p scryptParams
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &p); err != nil {
+			return nil, fmt.Errorf("failed unmarshalling scrypt parameters [%s]", err)
+		}
+		if err := checkScryptParams(p.CostParameter, p.BlockSize, p.ParallelizationParameter); err != nil {
+			return nil, err
+		}
+		var err error
+		if key, err = scrypt.Key(pwd, p.Salt, p.CostParameter, p.BlockSize, p.ParallelizationParameter, 32); err != nil {
+			return nil, fmt.Errorf("failed deriving key with scrypt [%s]", err)
+		}
+	default:
+		return nil, fmt.Errorf("key derivation function not supported [%s]", kdf.Algorithm)
+	}
+
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted data, it must be a multiple of the block size")
+	}
+	blockCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(blockCipher, iv).CryptBlocks(plaintext, info.EncryptedData)
+
+	plaintext, err = pkcs7UnPadding(plaintext)
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return plaintext, nil
+}
+
+// checkPBKDF2Params 检查PBKDF2的迭代次数是否在(0, maxPBKDF2Iterations]范围内。
+func checkPBKDF2Params(iterations int) error {
+	if iterations <= 0 || iterations > maxPBKDF2Iterations {
+		return fmt.Errorf("invalid PBKDF2 iteration count [%d], must be in range [1, %d]", iterations, maxPBKDF2Iterations)
+	}
+	return nil
+}
+
+// checkScryptParams 检查scrypt的参数是否合法，并且所需的计算量和内存不超过上限。
+func checkScryptParams(n, r, p int) error {
+	if n <= 1 || n&(n-1) != 0 || n > maxScryptN {
+		return fmt.Errorf("invalid scrypt cost parameter N [%d], must be a power of 2 in range [2, %d]", n, maxScryptN)
+	}
+	if r <= 0 || r > maxScryptR || p <= 0 || p > maxScryptP {
+		return fmt.Errorf("invalid scrypt parameters r [%d] and p [%d], must be in range [1, %d] and [1, %d]", r, p, maxScryptR, maxScryptP)
+	}
+	if 128*n*r > maxScryptMemory {
+		return fmt.Errorf("invalid scrypt parameters N [%d] and r [%d], memory cost exceeds %d bytes", n, r, maxScryptMemory)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package sw
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+// verifyRSA 利用RSA公钥验证摘要的签名。如果opts是*rsa.PSSOptions，则按照PSS方案验证，否则按照
+// PKCS#1 v1.5方案验证，摘要所用的哈希函数由opts.HashFunc()给出。
+func verifyRSA(k *rsa.PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if opts == nil {
+		return false, errors.New("invalid options, it must be different from nil")
+	}
+
+	switch o := opts.(type) {
+	case *rsa.PSSOptions:
+		if o.Hash == 0 {
+			return false, errors.New("invalid PSS options, the hash function must be specified")
+		}
+		err := rsa.VerifyPSS(k, o.Hash, digest, signature, o)
+		return err == nil, nil
+	default:
+		h := opts.HashFunc()
+		if h != 0 && len(digest) != h.Size() {
+			return false, fmt.Errorf("invalid digest length [%d], expected %d bytes for %s", len(digest), h.Size(), h)
+		}
+		err := rsa.VerifyPKCS1v15(k, h, digest, signature)
+		return err == nil, nil
+	}
+}
+
+type rsaPublicKeyKeyVerifier struct{}
+
+func (v *rsaPublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifyRSA(k.(*rsaPublicKey).pubKey, signature, digest, opts)
+}
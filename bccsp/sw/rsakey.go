@@ -0,0 +1,55 @@
+package sw
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+type rsaPublicKey struct {
+	pubKey *rsa.PublicKey
+}
+
+// Bytes 将公钥按照PKIX格式序列化成一串字节序列。
+func (k *rsaPublicKey) Bytes() (raw []byte, err error) {
+	if k.pubKey == nil {
+		return nil, errors.New("failed marshalling key, key is nil")
+	}
+	raw, err = x509.MarshalPKIXPublicKey(k.pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling key [%s]", err)
+	}
+	return raw, nil
+}
+
+// SKI 返回RSA公钥的标识符，它等于PKCS#1格式的公钥的SHA256哈希值。
+func (k *rsaPublicKey) SKI() []byte {
+	if k.pubKey == nil {
+		return nil
+	}
+
+	raw := x509.MarshalPKCS1PublicKey(k.pubKey)
+
+	hash := sha256.New()
+	hash.Write(raw)
+	return hash.Sum(nil)
+}
+
+// Symmetric RSA是一个非对称密码方案，所以此方法返回false。
+func (k *rsaPublicKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是公钥，所以返回false。
+func (k *rsaPublicKey) Private() bool {
+	return false
+}
+
+// PublicKey 返回公钥自己。
+func (k *rsaPublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
@@ -0,0 +1,180 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/secp256k1"
+)
+
+// schnorrSignatureSize 是BIP-340签名的字节长度，即R.x || s。
+const schnorrSignatureSize = 64
+
+// taggedHash 计算BIP-340定义的带标签的哈希值SHA256(SHA256(tag) || SHA256(tag) || msgs...)。
+func taggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	return h.Sum(nil)
+}
+
+// hashToScalar 将带标签的哈希值解释为大端序的整数，并对曲线的阶取模。
+func hashToScalar(c elliptic.Curve, tag string, msgs ...[]byte) *big.Int {
+	e := new(big.Int).SetBytes(taggedHash(tag, msgs...))
+	return e.Mod(e, c.Params().N)
+}
+
+// checkSchnorrCurve 检查曲线是否支持Schnorr签名，BIP-340只定义了secp256k1，这里按照相同的方式支持P-256。
+func checkSchnorrCurve(c elliptic.Curve) error {
+	if secp256k1.IsS256(c) || c.Params() == elliptic.P256().Params() {
+		return nil
+	}
+	return fmt.Errorf("curve [%s] not supported, Schnorr signatures require secp256k1 or P-256", c.Params().Name)
+}
+
+// scalarBytes 将标量或坐标编码成32字节的大端序字节序列。
+func scalarBytes(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, 32))
+}
+
+// hasEvenY 判断点的y坐标是否为偶数。
+func hasEvenY(y *big.Int) bool {
+	return y.Bit(0) == 0
+}
+
+// negatePoint 返回点(x, y)的相反数(x, p - y)。
+func negatePoint(c elliptic.Curve, x, y *big.Int) (*big.Int, *big.Int) {
+	if y.Sign() == 0 {
+		return x, y
+	}
+	return x, new(big.Int).Sub(c.Params().P, y)
+}
+
+// isInfinity 判断点是否是无穷远点，crypto/elliptic以(0, 0)表示无穷远点。
+func isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Sign() == 0
+}
+
+// unmarshalCompressedPoint 解析33字节的压缩格式的点。crypto/elliptic只能解压参数a等于-3的曲线上的点，
+// secp256k1上的点交给secp256k1包解压。
+func unmarshalCompressedPoint(c elliptic.Curve, raw []byte) (*big.Int, *big.Int, error) {
+	if len(raw) != 33 || (raw[0] != 2 && raw[0] != 3) {
+		return nil, nil, fmt.Errorf("invalid compressed point encoding of length [%d]", len(raw))
+	}
+	if secp256k1.IsS256(c) {
+		pub, err := secp256k1.ParsePublicKey(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pub.X, pub.Y, nil
+	}
+	x, y := elliptic.UnmarshalCompressed(c, raw)
+	if x == nil {
+		return nil, nil, errors.New("invalid point, it is not on the curve")
+	}
+	return x, y, nil
+}
+
+// liftX 返回x坐标为x且y坐标为偶数的点，即BIP-340中的lift_x。
+func liftX(c elliptic.Curve, x []byte) (*big.Int, *big.Int, error) {
+	if len(x) != 32 {
+		return nil, nil, fmt.Errorf("invalid x-only public key length [%d], must be 32 bytes", len(x))
+	}
+	return unmarshalCompressedPoint(c, append([]byte{2}, x...))
+}
+
+// signSchnorr 按照BIP-340对消息msg进行签名，私钥对应的公钥的y坐标为奇数时，使用私钥的相反数签名。
+func signSchnorr(k *ecdsa.PrivateKey, msg []byte, opts *bccsp.SchnorrSignerOpts) ([]byte, error) {
+	c := k.Curve
+	if err := checkSchnorrCurve(c); err != nil {
+		return nil, err
+	}
+	n := c.Params().N
+
+	aux := opts.AuxRand
+	if len(aux) == 0 {
+		aux = make([]byte, 32)
+		if _, err := rand.Read(aux); err != nil {
+			return nil, fmt.Errorf("failed generating auxiliary randomness [%s]", err)
+		}
+	} else if len(aux) != 32 {
+		return nil, fmt.Errorf("invalid auxiliary randomness length [%d], must be 32 bytes", len(aux))
+	}
+
+	d := new(big.Int).Set(k.D)
+	if !hasEvenY(k.Y) {
+		d.Sub(n, d)
+	}
+	px := scalarBytes(k.X)
+
+	t := scalarBytes(d)
+	for i, b := range taggedHash("BIP0340/aux", aux) {
+		t[i] ^= b
+	}
+	k0 := hashToScalar(c, "BIP0340/nonce", t, px, msg)
+	if k0.Sign() == 0 {
+		return nil, errors.New("invalid nonce, it must not be zero")
+	}
+
+	rx, ry := c.ScalarBaseMult(scalarBytes(k0))
+	if !hasEvenY(ry) {
+		k0.Sub(n, k0)
+	}
+	e := hashToScalar(c, "BIP0340/challenge", scalarBytes(rx), px, msg)
+
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k0)
+	s.Mod(s, n)
+
+	return append(scalarBytes(rx), scalarBytes(s)...), nil
+}
+
+// verifySchnorr 按照BIP-340验证签名，公钥以32字节的x坐标给出。
+func verifySchnorr(c elliptic.Curve, pubX, signature, msg []byte) (bool, error) {
+	if err := checkSchnorrCurve(c); err != nil {
+		return false, err
+	}
+	if len(signature) != schnorrSignatureSize {
+		return false, fmt.Errorf("invalid signature length [%d], must be %d bytes", len(signature), schnorrSignatureSize)
+	}
+
+	px, py, err := liftX(c, pubX)
+	if err != nil {
+		return false, fmt.Errorf("invalid public key [%s]", err)
+	}
+
+	params := c.Params()
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if r.Cmp(params.P) >= 0 || s.Cmp(params.N) >= 0 {
+		return false, nil
+	}
+	e := hashToScalar(c, "BIP0340/challenge", signature[:32], pubX, msg)
+
+	// R = s·G - e·P
+	sx, sy := c.ScalarBaseMult(scalarBytes(s))
+	ex, ey := c.ScalarMult(px, py, scalarBytes(e))
+	ex, ey = negatePoint(c, ex, ey)
+	rx, ry := c.Add(sx, sy, ex, ey)
+	if isInfinity(rx, ry) || !hasEvenY(ry) {
+		return false, nil
+	}
+	return rx.Cmp(r) == 0, nil
+}
+
+type schnorrPublicKeyKeyVerifier struct{}
+
+func (v *schnorrPublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	pub := k.(*schnorrPublicKey).pubKey
+	return verifySchnorr(pub.Curve, scalarBytes(pub.X), signature, digest)
+}
@@ -0,0 +1,125 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/utils"
+)
+
+// schnorrPublicKey 是BIP-340定义的x-only公钥，它的y坐标总是偶数，例如MuSig2的聚合公钥。这种公钥只能用于验证
+// Schnorr签名。
+type schnorrPublicKey struct {
+	pubKey *ecdsa.PublicKey
+}
+
+// Bytes 返回公钥的32字节x坐标。
+func (k *schnorrPublicKey) Bytes() ([]byte, error) {
+	return scalarBytes(k.pubKey.X), nil
+}
+
+// SKI 返回公钥的标识符，它等于公钥32字节x坐标的SHA256哈希值。
+func (k *schnorrPublicKey) SKI() []byte {
+	if k.pubKey == nil {
+		return nil
+	}
+
+	hash := sha256.New()
+	hash.Write(scalarBytes(k.pubKey.X))
+	return hash.Sum(nil)
+}
+
+// Symmetric Schnorr是一个非对称密码方案，所以此方法返回false。
+func (k *schnorrPublicKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是公钥，所以返回false。
+func (k *schnorrPublicKey) Private() bool {
+	return false
+}
+
+// PublicKey 返回公钥本身。
+func (k *schnorrPublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
+
+// musig2SecretNonce 是MuSig2签名者在第一轮生成的秘密nonce (k1, k2)，签名时k1和k2会被取出并清除，防止nonce被重复
+// 使用而泄露私钥。
+type musig2SecretNonce struct {
+	mutex    sync.Mutex
+	k1, k2   *big.Int
+	pubKey   *ecdsa.PublicKey
+	pubNonce []byte
+}
+
+// take 取出并清除k1和k2，nonce已经被使用过时返回nil。并发签名时只有一个调用者能够取到nonce。
+func (k *musig2SecretNonce) take() (k1, k2 *big.Int) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k1, k2 = k.k1, k.k2
+	k.k1, k.k2 = nil, nil
+	return k1, k2
+}
+
+// Bytes 秘密nonce的字节序列表现形式不予支持。
+func (k *musig2SecretNonce) Bytes() ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回秘密nonce的标识符，它等于对应公开nonce的标识符。
+func (k *musig2SecretNonce) SKI() []byte {
+	return (&musig2PublicNonce{k.pubNonce}).SKI()
+}
+
+// Symmetric nonce属于非对称密码方案，所以此方法返回false。
+func (k *musig2SecretNonce) Symmetric() bool {
+	return false
+}
+
+// Private 秘密nonce是私有的，所以返回true。
+func (k *musig2SecretNonce) Private() bool {
+	return true
+}
+
+// PublicKey 返回对应的公开nonce。
+func (k *musig2SecretNonce) PublicKey() (bccsp.Key, error) {
+	return &musig2PublicNonce{k.pubNonce}, nil
+}
+
+// musig2PublicNonce 是MuSig2签名者在第一轮发送给其他签名者的公开nonce，即两个压缩格式的点R1 || R2。
+type musig2PublicNonce struct {
+	pubNonce []byte
+}
+
+// Bytes 返回66字节的公开nonce。
+func (k *musig2PublicNonce) Bytes() ([]byte, error) {
+	return utils.Clone(k.pubNonce), nil
+}
+
+// SKI 返回公开nonce的标识符，它等于公开nonce的SHA256哈希值。
+func (k *musig2PublicNonce) SKI() []byte {
+	hash := sha256.New()
+	hash.Write(k.pubNonce)
+	return hash.Sum(nil)
+}
+
+// Symmetric nonce属于非对称密码方案，所以此方法返回false。
+func (k *musig2PublicNonce) Symmetric() bool {
+	return false
+}
+
+// Private 公开nonce不是私有的，所以返回false。
+func (k *musig2PublicNonce) Private() bool {
+	return false
+}
+
+// PublicKey 返回公开nonce本身。
+func (k *musig2PublicNonce) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
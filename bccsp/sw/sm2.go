@@ -0,0 +1,68 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+	"github.com/232425wxy/lark/bccsp/utils"
+)
+
+// sm2Digest 返回实际被签名的摘要：如果opts是SM2SignerOpts，则digest是原始的消息，需要结合用户标识计算
+// e = SM3(ZA || M)，否则digest就是e。
+func sm2Digest(k *ecdsa.PublicKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if o, ok := opts.(*bccsp.SM2SignerOpts); ok {
+		return gm.MessageDigest(k, o.UID, digest)
+	}
+	return digest, nil
+}
+
+// signSM2 利用SM2私钥进行签名，签名以ASN.1编码的(r, s)的形式返回。
+func signSM2(k *ecdsa.PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	e, err := sm2Digest(&k.PublicKey, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r, s, err := gm.Sign(rand.Reader, k, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.MarshalECDSASignature(r, s)
+}
+
+// verifySM2 利用SM2公钥验证签名。
+func verifySM2(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	r, s, err := utils.UnmarshalECDSASignature(signature)
+	if err != nil {
+		return false, fmt.Errorf("failed unmashalling signature [%s]", err)
+	}
+
+	e, err := sm2Digest(k, digest, opts)
+	if err != nil {
+		return false, err
+	}
+
+	return gm.Verify(k, e, r, s), nil
+}
+
+type sm2Signer struct{}
+
+func (s *sm2Signer) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return signSM2(k.(*sm2PrivateKey).privKey, digest, opts)
+}
+
+type sm2PrivateKeyVerifier struct{}
+
+func (v *sm2PrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifySM2(&(k.(*sm2PrivateKey).privKey.PublicKey), signature, digest, opts)
+}
+
+type sm2PublicKeyKeyVerifier struct{}
+
+func (v *sm2PublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return verifySM2(k.(*sm2PublicKey).pubKey, signature, digest, opts)
+}
@@ -0,0 +1,90 @@
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+)
+
+type sm2PrivateKey struct {
+	privKey *ecdsa.PrivateKey
+}
+
+// Bytes SM2私钥的字节序列表现形式不予支持。
+func (k *sm2PrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回SM2私钥的标识符，它等于对应公钥的标识符。
+func (k *sm2PrivateKey) SKI() []byte {
+	if k.privKey == nil {
+		return nil
+	}
+
+	raw := elliptic.Marshal(k.privKey.Curve, k.privKey.PublicKey.X, k.privKey.PublicKey.Y)
+
+	hash := sha256.New()
+	hash.Write(raw)
+	return hash.Sum(nil)
+}
+
+// Symmetric SM2是一个非对称密码方案，所以此方法返回false。
+func (k *sm2PrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private SM2是非对称密码方案，且该密钥是私钥，所以返回true。
+func (k *sm2PrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey 返回SM2私钥对应的公钥。
+func (k *sm2PrivateKey) PublicKey() (bccsp.Key, error) {
+	return &sm2PublicKey{&k.privKey.PublicKey}, nil
+}
+
+type sm2PublicKey struct {
+	pubKey *ecdsa.PublicKey
+}
+
+// Bytes 将公钥按照PKIX格式序列化成一串字节序列。
+func (k *sm2PublicKey) Bytes() (raw []byte, err error) {
+	raw, err = gm.MarshalPKIXPublicKey(k.pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling key [%s]", err)
+	}
+	return raw, nil
+}
+
+// SKI 返回SM2公钥的标识符。
+func (k *sm2PublicKey) SKI() []byte {
+	if k.pubKey == nil {
+		return nil
+	}
+
+	raw := elliptic.Marshal(k.pubKey.Curve, k.pubKey.X, k.pubKey.Y)
+
+	hash := sha256.New()
+	hash.Write(raw)
+	return hash.Sum(nil)
+}
+
+// Symmetric SM2是一个非对称密码方案，所以此方法返回false。
+func (k *sm2PublicKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是公钥，所以返回false。
+func (k *sm2PublicKey) Private() bool {
+	return false
+}
+
+// PublicKey 返回公钥自己。
+func (k *sm2PublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
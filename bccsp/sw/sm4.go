@@ -0,0 +1,120 @@
+package sw
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/gm"
+)
+
+// sm4CBCPKCS7Encrypt 先对明文进行PKCS7填充，然后以CBC模式进行SM4加密，得到的密文的前16个字节是IV。
+// iv为空时用prng生成随机的IV，prng为nil时使用crypto/rand。
+func sm4CBCPKCS7Encrypt(prng io.Reader, iv, key, src []byte) ([]byte, error) {
+	block, err := gm.NewSM4Cipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := pkcs7Padding(src)
+	ciphertext := make([]byte, gm.SM4BlockSize+len(padded))
+	if len(iv) != 0 {
+		if len(iv) != gm.SM4BlockSize {
+			return nil, errors.New("invalid IV, it must have length the block size")
+		}
+		copy(ciphertext, iv)
+	} else {
+		if prng == nil {
+			prng = rand.Reader
+		}
+		if _, err := io.ReadFull(prng, ciphertext[:gm.SM4BlockSize]); err != nil {
+			return nil, err
+		}
+	}
+
+	mode := cipher.NewCBCEncrypter(block, ciphertext[:gm.SM4BlockSize])
+	mode.CryptBlocks(ciphertext[gm.SM4BlockSize:], padded)
+
+	return ciphertext, nil
+}
+
+// sm4CBCPKCS7Decrypt 以CBC模式进行SM4解密，然后去掉PKCS7填充。
+func sm4CBCPKCS7Decrypt(key, src []byte) ([]byte, error) {
+	block, err := gm.NewSM4Cipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(src) < gm.SM4BlockSize || len(src)%gm.SM4BlockSize != 0 {
+		return nil, errors.New("invalid ciphertext, it must be a multiple of the block size")
+	}
+	iv := src[:gm.SM4BlockSize]
+	src = src[gm.SM4BlockSize:]
+
+	plaintext := make([]byte, len(src))
+	mode := cipher.NewCBCDecrypter(block, iv)
+	mode.CryptBlocks(plaintext, src)
+
+	return pkcs7UnPadding(plaintext)
+}
+
+func newSM4GCM(key []byte) (cipher.AEAD, error) {
+	block, err := gm.NewSM4Cipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type sm4Encryptor struct{}
+
+func (e *sm4Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	key := k.(*sm4PrivateKey).privKey
+
+	switch o := opts.(type) {
+	case *bccsp.SM4CBCPKCS7ModeOpts:
+		if len(o.IV) != 0 && o.PRNG != nil {
+			return nil, errors.New("invalid options, either IV or PRNG should be different from nil, or both nil")
+		}
+		return sm4CBCPKCS7Encrypt(o.PRNG, o.IV, key, plaintext)
+	case bccsp.SM4CBCPKCS7ModeOpts:
+		return e.Encrypt(k, plaintext, &o)
+	case *bccsp.SM4GCMModeOpts:
+		if len(o.Nonce) != 0 && o.PRNG != nil {
+			return nil, errors.New("invalid options, either Nonce or PRNG should be different from nil, or both nil")
+		}
+		aead, err := newSM4GCM(key)
+		if err != nil {
+			return nil, err
+		}
+		return aeadSeal(aead, o.PRNG, o.Nonce, plaintext, o.AdditionalData)
+	case bccsp.SM4GCMModeOpts:
+		return e.Encrypt(k, plaintext, &o)
+	default:
+		return nil, fmt.Errorf("mode not recognized [%s]", opts)
+	}
+}
+
+type sm4Decryptor struct{}
+
+func (d *sm4Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	key := k.(*sm4PrivateKey).privKey
+
+	switch o := opts.(type) {
+	case *bccsp.SM4CBCPKCS7ModeOpts, bccsp.SM4CBCPKCS7ModeOpts:
+		return sm4CBCPKCS7Decrypt(key, ciphertext)
+	case *bccsp.SM4GCMModeOpts:
+		aead, err := newSM4GCM(key)
+		if err != nil {
+			return nil, err
+		}
+		return aeadOpen(aead, ciphertext, o.AdditionalData)
+	case bccsp.SM4GCMModeOpts:
+		return d.Decrypt(k, ciphertext, &o)
+	default:
+		return nil, fmt.Errorf("mode not recognized [%s]", opts)
+	}
+}
@@ -0,0 +1,45 @@
+package sw
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/232425wxy/lark/bccsp"
+)
+
+type sm4PrivateKey struct {
+	privKey    []byte
+	exportable bool
+}
+
+// Bytes 只有当密钥是可导出的时候才返回密钥的原始字节，否则返回错误。
+func (k *sm4PrivateKey) Bytes() (raw []byte, err error) {
+	if k.exportable {
+		return k.privKey, nil
+	}
+
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回SM4密钥的标识符，它等于SHA256(0x03 || key)。
+func (k *sm4PrivateKey) SKI() []byte {
+	hash := sha256.New()
+	hash.Write([]byte{0x03})
+	hash.Write(k.privKey)
+	return hash.Sum(nil)
+}
+
+// Symmetric SM4是一个对称密码方案，所以此方法返回true。
+func (k *sm4PrivateKey) Symmetric() bool {
+	return true
+}
+
+// Private SM4密钥是需要保密的，所以此方法返回true。
+func (k *sm4PrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey 对称密钥没有公钥，所以此方法返回错误。
+func (k *sm4PrivateKey) PublicKey() (bccsp.Key, error) {
+	return nil, errors.New("cannot call this method on a symmetric key")
+}
@@ -0,0 +1,168 @@
+package sw
+
+import (
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	"github.com/232425wxy/lark/bccsp"
+	"golang.org/x/crypto/curve25519"
+)
+
+// x25519KeySize 是RFC 7748定义的X25519私钥和公钥的字节长度。
+const x25519KeySize = 32
+
+type x25519PrivateKey struct {
+	privKey []byte
+}
+
+// Bytes X25519私钥的字节序列表现形式不予支持。
+func (k *x25519PrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+// SKI 返回X25519私钥的标识符，它等于对应公钥的标识符。
+func (k *x25519PrivateKey) SKI() []byte {
+	pk, err := k.publicKey()
+	if err != nil {
+		return nil
+	}
+	return pk.SKI()
+}
+
+// Symmetric X25519是一个非对称密码方案，所以此方法返回false。
+func (k *x25519PrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是私钥，所以返回true。
+func (k *x25519PrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey 返回X25519私钥对应的公钥。
+func (k *x25519PrivateKey) PublicKey() (bccsp.Key, error) {
+	return k.publicKey()
+}
+
+func (k *x25519PrivateKey) publicKey() (*x25519PublicKey, error) {
+	pub, err := curve25519.X25519(k.privKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed computing X25519 public key [%s]", err)
+	}
+	return &x25519PublicKey{pub}, nil
+}
+
+type x25519PublicKey struct {
+	pubKey []byte
+}
+
+// Bytes 将公钥按照PKIX格式序列化成一串字节序列。
+func (k *x25519PublicKey) Bytes() ([]byte, error) {
+	return marshalX25519PKIXPublicKey(k.pubKey)
+}
+
+// SKI 返回X25519公钥的标识符，它等于公钥32字节编码的SHA256哈希值。
+func (k *x25519PublicKey) SKI() []byte {
+	if len(k.pubKey) == 0 {
+		return nil
+	}
+
+	hash := sha256.New()
+	hash.Write(k.pubKey)
+	return hash.Sum(nil)
+}
+
+// Symmetric X25519是一个非对称密码方案，所以此方法返回false。
+func (k *x25519PublicKey) Symmetric() bool {
+	return false
+}
+
+// Private 该密钥是公钥，所以返回false。
+func (k *x25519PublicKey) Private() bool {
+	return false
+}
+
+// PublicKey 返回公钥本身。
+func (k *x25519PublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
+
+// oidPublicKeyX25519 是RFC 8410为X25519分配的对象标识符。
+var oidPublicKeyX25519 = asn1.ObjectIdentifier{1, 3, 101, 110}
+
+type x25519PublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type x25519PKCS8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// marshalX25519PKIXPublicKey 按照RFC 8410将X25519公钥编码成PKIX格式。
+func marshalX25519PKIXPublicKey(pub []byte) ([]byte, error) {
+	if len(pub) != x25519KeySize {
+		return nil, fmt.Errorf("invalid X25519 public key length [%d], must be %d bytes", len(pub), x25519KeySize)
+	}
+	return asn1.Marshal(x25519PublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyX25519},
+		PublicKey: asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+}
+
+// parseX25519PKIXPublicKey 解析按照RFC 8410编码的PKIX格式的X25519公钥。
+func parseX25519PKIXPublicKey(der []byte) ([]byte, error) {
+	var spki x25519PublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, fmt.Errorf("failed parsing PKIX public key [%w]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("failed parsing PKIX public key, trailing data")
+	}
+	if !spki.Algorithm.Algorithm.Equal(oidPublicKeyX25519) {
+		return nil, fmt.Errorf("unexpected public key algorithm [%s], expected X25519", spki.Algorithm.Algorithm)
+	}
+	pub := spki.PublicKey.RightAlign()
+	if len(pub) != x25519KeySize {
+		return nil, fmt.Errorf("invalid X25519 public key length [%d], must be %d bytes", len(pub), x25519KeySize)
+	}
+	return pub, nil
+}
+
+// marshalX25519PKCS8PrivateKey 按照RFC 8410将X25519私钥编码成PKCS#8格式。
+func marshalX25519PKCS8PrivateKey(priv []byte) ([]byte, error) {
+	if len(priv) != x25519KeySize {
+		return nil, fmt.Errorf("invalid X25519 private key length [%d], must be %d bytes", len(priv), x25519KeySize)
+	}
+	curvePrivateKey, err := asn1.Marshal(priv)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(x25519PKCS8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyX25519},
+		PrivateKey: curvePrivateKey,
+	})
+}
+
+// parseX25519PKCS8PrivateKey 解析按照RFC 8410编码的PKCS#8格式的X25519私钥。
+func parseX25519PKCS8PrivateKey(der []byte) ([]byte, error) {
+	var p x25519PKCS8
+	if _, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("failed parsing PKCS#8 private key [%w]", err)
+	}
+	if !p.Algo.Algorithm.Equal(oidPublicKeyX25519) {
+		return nil, fmt.Errorf("unexpected private key algorithm [%s], expected X25519", p.Algo.Algorithm)
+	}
+	var priv []byte
+	if _, err := asn1.Unmarshal(p.PrivateKey, &priv); err != nil {
+		return nil, fmt.Errorf("failed parsing X25519 private key [%w]", err)
+	}
+	if len(priv) != x25519KeySize {
+		return nil, fmt.Errorf("invalid X25519 private key length [%d], must be %d bytes", len(priv), x25519KeySize)
+	}
+	return priv, nil
+}
@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/232425wxy/lark/bccsp/gm"
+	"github.com/232425wxy/lark/bccsp/secp256k1"
+)
+
+// CurveInfo 描述了注册到曲线注册表中的一条椭圆曲线，包括曲线的实现、名称、对象标识符和密钥长度。
+// 标准库crypto/x509不支持的曲线还需要提供PKIX和PKCS#8格式的编解码函数。
+type CurveInfo struct {
+	// Curve 是椭圆曲线的实现。
+	Curve elliptic.Curve
+	// Name 是曲线的名称，为空时使用Curve.Params().Name。
+	Name string
+	// OID 是曲线在PKIX和PKCS#8编码中使用的对象标识符。
+	OID asn1.ObjectIdentifier
+	// KeySize 是密钥的比特长度，为0时使用Curve.Params().BitSize。
+	KeySize int
+
+	// 以下编解码函数为空时使用crypto/x509进行编解码。
+	MarshalPKIXPublicKey   func(*ecdsa.PublicKey) ([]byte, error)
+	ParsePKIXPublicKey     func([]byte) (*ecdsa.PublicKey, error)
+	MarshalPKCS8PrivateKey func(*ecdsa.PrivateKey) ([]byte, error)
+	ParsePKCS8PrivateKey   func([]byte) (*ecdsa.PrivateKey, error)
+
+	halfOrder *big.Int
+}
+
+// HalfOrder 返回曲线阶的一半N/2，低S值的签名要求S不大于这个值。
+func (info CurveInfo) HalfOrder() *big.Int {
+	return new(big.Int).Set(info.halfOrder)
+}
+
+type curveRegistry struct {
+	mutex   sync.RWMutex
+	byCurve map[elliptic.Curve]*CurveInfo
+	byName  map[string]*CurveInfo
+	byOID   map[string]*CurveInfo
+}
+
+var curves = &curveRegistry{
+	byCurve: make(map[elliptic.Curve]*CurveInfo),
+	byName:  make(map[string]*CurveInfo),
+	byOID:   make(map[string]*CurveInfo),
+}
+
+func init() {
+	for _, info := range []CurveInfo{
+		{Curve: elliptic.P224(), OID: asn1.ObjectIdentifier{1, 3, 132, 0, 33}},
+		{Curve: elliptic.P256(), OID: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}},
+		{Curve: elliptic.P384(), OID: asn1.ObjectIdentifier{1, 3, 132, 0, 34}},
+		{Curve: elliptic.P521(), OID: asn1.ObjectIdentifier{1, 3, 132, 0, 35}},
+		{
+			Curve:                  secp256k1.S256(),
+			OID:                    secp256k1.OIDNamedCurveSecp256k1,
+			MarshalPKIXPublicKey:   secp256k1.MarshalPKIXPublicKey,
+			ParsePKIXPublicKey:     secp256k1.ParsePKIXPublicKey,
+			MarshalPKCS8PrivateKey: secp256k1.MarshalPKCS8PrivateKey,
+			ParsePKCS8PrivateKey:   secp256k1.ParsePKCS8PrivateKey,
+		},
+		{
+			Curve:                  gm.P256SM2(),
+			OID:                    gm.OIDNamedCurveSM2,
+			MarshalPKIXPublicKey:   gm.MarshalPKIXPublicKey,
+			ParsePKIXPublicKey:     gm.ParsePKIXPublicKey,
+			MarshalPKCS8PrivateKey: gm.MarshalPKCS8PrivateKey,
+			ParsePKCS8PrivateKey:   gm.ParsePKCS8PrivateKey,
+		},
+	} {
+		if err := RegisterCurve(info); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterCurve 向曲线注册表中注册一条椭圆曲线，注册以后GetCurveHalfOrderAt、IsLowS以及ECDSA密钥的编解码
+// 都可以识别这条曲线。曲线、名称和对象标识符都不能与已注册的曲线重复。
+func RegisterCurve(info CurveInfo) error {
+	if info.Curve == nil {
+		return errors.New("invalid curve, it must be different from nil")
+	}
+	params := info.Curve.Params()
+	if params == nil || params.N == nil {
+		return errors.New("invalid curve, its parameters must be different from nil")
+	}
+	if len(info.OID) == 0 {
+		return errors.New("invalid curve OID, it must not be empty")
+	}
+	if info.Name == "" {
+		info.Name = params.Name
+	}
+	if info.Name == "" {
+		return errors.New("invalid curve name, it must not be empty")
+	}
+	if info.KeySize == 0 {
+		info.KeySize = params.BitSize
+	}
+	info.OID = append(asn1.ObjectIdentifier(nil), info.OID...)
+	info.halfOrder = new(big.Int).Rsh(params.N, 1)
+
+	curves.mutex.Lock()
+	defer curves.mutex.Unlock()
+
+	if _, ok := curves.byCurve[info.Curve]; ok {
+		return fmt.Errorf("curve [%s] already registered", info.Name)
+	}
+	if _, ok := curves.byName[info.Name]; ok {
+		return fmt.Errorf("curve name [%s] already registered", info.Name)
+	}
+	if _, ok := curves.byOID[info.OID.String()]; ok {
+		return fmt.Errorf("curve OID [%s] already registered", info.OID)
+	}
+
+	curves.byCurve[info.Curve] = &info
+	curves.byName[info.Name] = &info
+	curves.byOID[info.OID.String()] = &info
+	return nil
+}
+
+// LookupCurve 根据曲线的实现查找已注册的曲线。
+func LookupCurve(c elliptic.Curve) (CurveInfo, bool) {
+	curves.mutex.RLock()
+	defer curves.mutex.RUnlock()
+	info, ok := curves.byCurve[c]
+	if !ok {
+		return CurveInfo{}, false
+	}
+	return *info, true
+}
+
+// LookupCurveByName 根据曲线的名称查找已注册的曲线，例如"P-256"或"secp256k1"。
+func LookupCurveByName(name string) (CurveInfo, bool) {
+	curves.mutex.RLock()
+	defer curves.mutex.RUnlock()
+	info, ok := curves.byName[name]
+	if !ok {
+		return CurveInfo{}, false
+	}
+	return *info, true
+}
+
+// LookupCurveByOID 根据曲线的对象标识符查找已注册的曲线。
+func LookupCurveByOID(oid asn1.ObjectIdentifier) (CurveInfo, bool) {
+	curves.mutex.RLock()
+	defer curves.mutex.RUnlock()
+	info, ok := curves.byOID[oid.String()]
+	if !ok {
+		return CurveInfo{}, false
+	}
+	return *info, true
+}
+
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// lookupCurveByAlgorithm 根据PKIX或PKCS#8编码中算法标识符携带的曲线对象标识符查找已注册的曲线。
+func lookupCurveByAlgorithm(algo pkix.AlgorithmIdentifier) (CurveInfo, error) {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &oid); err != nil {
+		return CurveInfo{}, fmt.Errorf("failed parsing named curve [%w]", err)
+	}
+	info, ok := LookupCurveByOID(oid)
+	if !ok {
+		return CurveInfo{}, fmt.Errorf("curve not recognized [%s]", oid)
+	}
+	return info, nil
+}
+
+// MarshalECPKIXPublicKey 将ECDSA公钥编码成PKIX格式，公钥所在的曲线必须已经注册。
+func MarshalECPKIXPublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	if pub == nil {
+		return nil, errors.New("invalid ecdsa public key, it must be different from nil")
+	}
+	info, ok := LookupCurve(pub.Curve)
+	if !ok {
+		return nil, fmt.Errorf("curve not recognized [%s]", pub.Curve)
+	}
+	if info.MarshalPKIXPublicKey != nil {
+		return info.MarshalPKIXPublicKey(pub)
+	}
+	return x509.MarshalPKIXPublicKey(pub)
+}
+
+// ParseECPKIXPublicKey 解析PKIX格式的ECDSA公钥，公钥所在的曲线必须已经注册。
+func ParseECPKIXPublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki publicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, fmt.Errorf("failed parsing PKIX public key [%w]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("failed parsing PKIX public key, trailing data")
+	}
+	info, err := lookupCurveByAlgorithm(spki.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if info.ParsePKIXPublicKey != nil {
+		return info.ParsePKIXPublicKey(der)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid key type, expected *ecdsa.PublicKey, but got [%T]", key)
+	}
+	return pub, nil
+}
+
+// MarshalECPKCS8PrivateKey 将ECDSA私钥编码成PKCS#8格式，私钥所在的曲线必须已经注册。
+func MarshalECPKCS8PrivateKey(priv *ecdsa.PrivateKey) ([]byte, error) {
+	if priv == nil {
+		return nil, errors.New("invalid ecdsa private key, it must be different from nil")
+	}
+	info, ok := LookupCurve(priv.Curve)
+	if !ok {
+		return nil, fmt.Errorf("curve not recognized [%s]", priv.Curve)
+	}
+	if info.MarshalPKCS8PrivateKey != nil {
+		return info.MarshalPKCS8PrivateKey(priv)
+	}
+	return x509.MarshalPKCS8PrivateKey(priv)
+}
+
+// ParseECPKCS8PrivateKey 解析PKCS#8格式的ECDSA私钥，私钥所在的曲线必须已经注册。
+func ParseECPKCS8PrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	var p pkcs8
+	if _, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("failed parsing PKCS#8 private key [%w]", err)
+	}
+	info, err := lookupCurveByAlgorithm(p.Algo)
+	if err != nil {
+		return nil, err
+	}
+	if info.ParsePKCS8PrivateKey != nil {
+		return info.ParsePKCS8PrivateKey(der)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid key type, expected *ecdsa.PrivateKey, but got [%T]", key)
+	}
+	return priv, nil
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp/gm"
+	"github.com/232425wxy/lark/bccsp/secp256k1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCurve(t *testing.T) {
+	for _, tc := range []struct {
+		curve   elliptic.Curve
+		name    string
+		oid     asn1.ObjectIdentifier
+		keySize int
+	}{
+		{elliptic.P224(), "P-224", asn1.ObjectIdentifier{1, 3, 132, 0, 33}, 224},
+		{elliptic.P256(), "P-256", asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, 256},
+		{elliptic.P384(), "P-384", asn1.ObjectIdentifier{1, 3, 132, 0, 34}, 384},
+		{elliptic.P521(), "P-521", asn1.ObjectIdentifier{1, 3, 132, 0, 35}, 521},
+		{secp256k1.S256(), "secp256k1", secp256k1.OIDNamedCurveSecp256k1, 256},
+		{gm.P256SM2(), "SM2-P-256", gm.OIDNamedCurveSM2, 256},
+	} {
+		info, ok := LookupCurve(tc.curve)
+		require.True(t, ok, tc.name)
+		require.Equal(t, tc.name, info.Name)
+		require.Equal(t, tc.oid, info.OID)
+		require.Equal(t, tc.keySize, info.KeySize)
+		require.Equal(t, new(big.Int).Rsh(tc.curve.Params().N, 1), info.HalfOrder())
+
+		info, ok = LookupCurveByName(tc.name)
+		require.True(t, ok)
+		require.Equal(t, tc.curve, info.Curve)
+
+		info, ok = LookupCurveByOID(tc.oid)
+		require.True(t, ok)
+		require.Equal(t, tc.curve, info.Curve)
+	}
+
+	_, ok := LookupCurveByName("P-192")
+	require.False(t, ok)
+	_, ok = LookupCurveByOID(asn1.ObjectIdentifier{1, 2, 3})
+	require.False(t, ok)
+
+	// 修改返回的半阶不能影响注册表。
+	info, _ := LookupCurve(elliptic.P256())
+	info.HalfOrder().SetInt64(0)
+	require.NotZero(t, GetCurveHalfOrderAt(elliptic.P256()).Sign())
+	require.Nil(t, GetCurveHalfOrderAt(&elliptic.CurveParams{Name: "unknown"}))
+}
+
+func TestRegisterCurve(t *testing.T) {
+	err := RegisterCurve(CurveInfo{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid curve, it must be different from nil")
+
+	err = RegisterCurve(CurveInfo{Curve: elliptic.P256()})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid curve OID, it must not be empty")
+
+	err = RegisterCurve(CurveInfo{Curve: elliptic.P256(), OID: asn1.ObjectIdentifier{1, 2, 3}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "curve [P-256] already registered")
+
+	// 注册一条参数与P-256相同但名称和OID不同的曲线，注册以后低S值的检查就能识别它。
+	params := *elliptic.P256().Params()
+	params.Name = "test-curve"
+	custom := &params
+	err = RegisterCurve(CurveInfo{Curve: custom, OID: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "curve OID [1.2.840.10045.3.1.7] already registered")
+
+	_, err = IsLowS(&ecdsa.PublicKey{Curve: custom}, big.NewInt(1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "curve not recognized")
+
+	require.NoError(t, RegisterCurve(CurveInfo{Curve: custom, OID: asn1.ObjectIdentifier{1, 2, 3, 4}}))
+	t.Cleanup(func() { unregisterCurve(custom) })
+	lowS, err := IsLowS(&ecdsa.PublicKey{Curve: custom}, big.NewInt(1))
+	require.NoError(t, err)
+	require.True(t, lowS)
+
+	info, ok := LookupCurveByName("test-curve")
+	require.True(t, ok)
+	require.Equal(t, 256, info.KeySize)
+}
+
+// unregisterCurve 从注册表中删除曲线c，用于撤销测试中注册的曲线。
+func unregisterCurve(c elliptic.Curve) {
+	curves.mutex.Lock()
+	defer curves.mutex.Unlock()
+
+	info, ok := curves.byCurve[c]
+	if !ok {
+		return
+	}
+	delete(curves.byCurve, c)
+	delete(curves.byName, info.Name)
+	delete(curves.byOID, info.OID.String())
+}
+
+func TestECKeyEncoding(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P521(), secp256k1.S256(), gm.P256SM2()} {
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		require.NoError(t, err)
+
+		der, err := MarshalECPKIXPublicKey(&priv.PublicKey)
+		require.NoError(t, err)
+		pub, err := ParseECPKIXPublicKey(der)
+		require.NoError(t, err)
+		require.Equal(t, curve, pub.Curve)
+		require.Equal(t, priv.X, pub.X)
+
+		der, err = MarshalECPKCS8PrivateKey(priv)
+		require.NoError(t, err)
+		priv2, err := ParseECPKCS8PrivateKey(der)
+		require.NoError(t, err)
+		require.Equal(t, curve, priv2.Curve)
+		require.Equal(t, priv.D, priv2.D)
+	}
+
+	_, err := MarshalECPKIXPublicKey(&ecdsa.PublicKey{Curve: &elliptic.CurveParams{Name: "unknown"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "curve not recognized")
+
+	_, err = ParseECPKIXPublicKey([]byte{0, 1, 2})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed parsing PKIX public key")
+}
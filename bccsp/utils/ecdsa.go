@@ -13,18 +13,13 @@ type ECDSASignature struct {
 	R, S *big.Int
 }
 
-var (
-	// 这个被用来确保签名的S值低于或等于椭圆曲线阶的一半，fabric只接受低S值的签名
-	curveHalfOrders = map[elliptic.Curve]*big.Int{
-		elliptic.P224(): new(big.Int).Rsh(elliptic.P224().Params().N, 1),
-		elliptic.P256(): new(big.Int).Rsh(elliptic.P256().Params().N, 1),
-		elliptic.P384(): new(big.Int).Rsh(elliptic.P384().Params().N, 1),
-		elliptic.P521(): new(big.Int).Rsh(elliptic.P521().Params().N, 1),
-	}
-)
-
+// GetCurveHalfOrderAt 返回曲线阶的一半N/2，曲线没有注册到曲线注册表中时返回nil。
 func GetCurveHalfOrderAt(c elliptic.Curve) *big.Int {
-	return big.NewInt(0).Set(curveHalfOrders[c])
+	info, ok := LookupCurve(c)
+	if !ok {
+		return nil
+	}
+	return info.HalfOrder()
 }
 
 func MarshalECDSASignature(r, s *big.Int) ([]byte, error) {
@@ -73,11 +68,11 @@ func SignatureToLowS(k *ecdsa.PublicKey, signature []byte) ([]byte, error) {
 
 // IsLowS 判断ECDSA签名里的s是否不大于椭圆曲线的阶的一半N/2。
 func IsLowS(k *ecdsa.PublicKey, s *big.Int) (bool, error) {
-	halfOrder, ok := curveHalfOrders[k.Curve]
+	info, ok := LookupCurve(k.Curve)
 	if !ok {
 		return false, fmt.Errorf("curve not recognized [%s]", k.Curve)
 	}
-	return s.Cmp(halfOrder) != 1, nil
+	return s.Cmp(info.halfOrder) != 1, nil
 }
 
 // TODO 为什么要让签名s小于椭圆曲线阶的一半呢？
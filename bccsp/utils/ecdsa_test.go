@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/232425wxy/lark/bccsp/secp256k1"
 	"github.com/stretchr/testify/require"
 )
 
@@ -107,3 +108,25 @@ func TestSignatureToLowS(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, lowS)
 }
+
+func TestSignatureToLowSSecp256k1(t *testing.T) {
+	lowLevelKey, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+	require.NoError(t, err)
+
+	// 比特币和以太坊钱包可能产生高S值的签名，需要能够将其转换为低S值的签名。
+	s := new(big.Int).Add(GetCurveHalfOrderAt(secp256k1.S256()), big.NewInt(1))
+	lowS, err := IsLowS(&lowLevelKey.PublicKey, s)
+	require.NoError(t, err)
+	require.False(t, lowS)
+
+	sig, err := MarshalECDSASignature(big.NewInt(1), s)
+	require.NoError(t, err)
+	sig2, err := SignatureToLowS(&lowLevelKey.PublicKey, sig)
+	require.NoError(t, err)
+	_, s2, err := UnmarshalECDSASignature(sig2)
+	require.NoError(t, err)
+	require.Equal(t, new(big.Int).Sub(secp256k1.S256().Params().N, s), s2)
+	lowS, err = IsLowS(&lowLevelKey.PublicKey, s2)
+	require.NoError(t, err)
+	require.True(t, lowS)
+}
@@ -0,0 +1,8 @@
+package utils
+
+// Clone 返回src的一个副本。
+func Clone(src []byte) []byte {
+	clone := make([]byte, len(src))
+	copy(clone, src)
+	return clone
+}
@@ -0,0 +1,202 @@
+/*
+keyshare 利用Shamir秘密共享备份和恢复BCCSP KeyStore中的私钥：
+
+	keyshare split -keystore <dir> -ski <hex> -shares <n> -threshold <t> -out <dir>
+	keyshare combine -keystore <dir> <share.pem>...
+
+split将SKI对应的密钥拆分成n份，每一份写入out目录下单独的PEM文件，分发给不同的保管人；combine利用至少t份
+恢复出密钥，并将其导入到KeyStore中。指定-config时，BCCSP由YAML格式的工厂配置创建，而不是使用-keystore指定
+的基于文件的KeyStore。从KeyStore中读取的对称密钥是不可导出的，因此不能被拆分。
+*/
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/factory"
+	"github.com/232425wxy/lark/bccsp/shamir"
+)
+
+const usage = `Usage:
+  keyshare split -keystore <dir> -ski <hex> -shares <n> -threshold <t> -out <dir>
+  keyshare combine -keystore <dir> <share.pem>...
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "split":
+		err = split(args[1:], stdout, stderr)
+	case "combine":
+		err = combine(args[1:], stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command [%s]\n%s", args[0], usage)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// cspFlags 是split和combine共用的创建BCCSP的参数。
+type cspFlags struct {
+	keystore string
+	config   string
+}
+
+func (f *cspFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.keystore, "keystore", "", "path of the file based keystore")
+	fs.StringVar(&f.config, "config", "", "path of a YAML BCCSP factory configuration, overrides -keystore")
+}
+
+func (f *cspFlags) csp() (bccsp.BCCSP, error) {
+	var opts *factory.FactoryOpts
+	switch {
+	case f.config != "":
+		raw, err := os.ReadFile(f.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading BCCSP configuration [%w]", err)
+		}
+		if opts, err = factory.ReadFactoryOptsFromYAML(raw); err != nil {
+			return nil, err
+		}
+	case f.keystore != "":
+		opts = factory.GetDefaultOpts()
+		opts.SW.FileKeystore = &factory.FileKeystoreOpts{KeyStorePath: f.keystore}
+	default:
+		return nil, errors.New("either -keystore or -config must be specified")
+	}
+
+	return factory.GetBCCSPFromOpts(opts)
+}
+
+func split(args []string, stdout, stderr io.Writer) error {
+	var (
+		cf        cspFlags
+		ski       string
+		n, t      int
+		outputDir string
+	)
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf.register(fs)
+	fs.StringVar(&ski, "ski", "", "hex encoded SKI of the key to split")
+	fs.IntVar(&n, "shares", 0, "number of shares to generate")
+	fs.IntVar(&t, "threshold", 0, "number of shares required to recover the key")
+	fs.StringVar(&outputDir, "out", ".", "directory the share files are written to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	rawSKI, err := hex.DecodeString(ski)
+	if err != nil || len(rawSKI) == 0 {
+		return fmt.Errorf("invalid SKI [%s]", ski)
+	}
+	csp, err := cf.csp()
+	if err != nil {
+		return err
+	}
+	k, err := csp.GetKey(rawSKI)
+	if err != nil {
+		return err
+	}
+	shares, err := shamir.SplitKey(csp, k, n, t)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(outputDir, 0o700); err != nil {
+		return fmt.Errorf("failed creating output directory [%w]", err)
+	}
+	for _, share := range shares {
+		raw, err := shamir.ShareToPEM(share)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(outputDir, fmt.Sprintf("%s-share-%d-of-%d.pem", hex.EncodeToString(rawSKI), share.Index, share.Total))
+		if err = writeNewFile(path, raw); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, path)
+	}
+	return nil
+}
+
+func combine(args []string, stdout, stderr io.Writer) error {
+	var (
+		cf        cspFlags
+		temporary bool
+	)
+	fs := flag.NewFlagSet("combine", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf.register(fs)
+	fs.BoolVar(&temporary, "dry-run", false, "recover the key without storing it in the keystore")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("no share files specified")
+	}
+
+	var shares []*shamir.Share
+	for _, path := range fs.Args() {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s, err := shamir.PEMToShares(raw)
+		if err != nil {
+			return fmt.Errorf("invalid share file [%s]: [%w]", path, err)
+		}
+		shares = append(shares, s...)
+	}
+
+	csp, err := cf.csp()
+	if err != nil {
+		return err
+	}
+	k, err := shamir.RecoverKey(csp, shares, temporary)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, hex.EncodeToString(k.SKI()))
+	return nil
+}
+
+// writeNewFile 将份额写入新的文件，已经存在的文件不会被覆盖。
+func writeNewFile(path string, raw []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(raw); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
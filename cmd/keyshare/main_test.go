@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/232425wxy/lark/bccsp"
+	"github.com/232425wxy/lark/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCombine(t *testing.T) {
+	dir := t.TempDir()
+	keystore := filepath.Join(dir, "keystore")
+	csp, err := sw.NewDefaultSecurityLevel(keystore)
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	ski := hex.EncodeToString(k.SKI())
+
+	var stdout, stderr bytes.Buffer
+	out := filepath.Join(dir, "shares")
+	code := run([]string{"split", "-keystore", keystore, "-ski", ski, "-shares", "5", "-threshold", "3", "-out", out}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	files := strings.Fields(stdout.String())
+	require.Len(t, files, 5)
+
+	// 份额文件不会被覆盖。
+	stderr.Reset()
+	code = run([]string{"split", "-keystore", keystore, "-ski", ski, "-shares", "5", "-threshold", "3", "-out", out}, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr.String(), "file exists")
+
+	// 少于门限值的份额无法恢复密钥。
+	restored := filepath.Join(dir, "restored")
+	stderr.Reset()
+	code = run([]string{"combine", "-keystore", restored, files[0], files[3]}, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr.String(), "not enough shares")
+
+	stdout.Reset()
+	code = run(append([]string{"combine", "-keystore", restored}, files[1], files[2], files[4]), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	require.Equal(t, ski, strings.TrimSpace(stdout.String()))
+
+	restoredCSP, err := sw.NewDefaultSecurityLevel(restored)
+	require.NoError(t, err)
+	k2, err := restoredCSP.GetKey(k.SKI())
+	require.NoError(t, err)
+	require.True(t, k2.Private())
+
+	require.Equal(t, 2, run(nil, &stdout, &stderr))
+	require.Equal(t, 2, run([]string{"unknown"}, &stdout, &stderr))
+	stderr.Reset()
+	require.Equal(t, 1, run([]string{"split", "-ski", ski, "-shares", "3", "-threshold", "2"}, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "either -keystore or -config must be specified")
+}
@@ -0,0 +1,46 @@
+package disabled
+
+import "github.com/232425wxy/lark/common/metrics"
+
+// Provider 是不做任何统计的metrics.Provider，在没有配置统计功能时使用。
+type Provider struct{}
+
+var _ metrics.Provider = (*Provider)(nil)
+
+func (p *Provider) NewCounter(metrics.CounterOpts) metrics.Counter {
+	return &Counter{}
+}
+
+func (p *Provider) NewGauge(metrics.GaugeOpts) metrics.Gauge {
+	return &Gauge{}
+}
+
+func (p *Provider) NewHistogram(metrics.HistogramOpts) metrics.Histogram {
+	return &Histogram{}
+}
+
+type Counter struct{}
+
+func (c *Counter) Add(float64) {}
+
+func (c *Counter) With(...string) metrics.Counter {
+	return c
+}
+
+type Gauge struct{}
+
+func (g *Gauge) Add(float64) {}
+
+func (g *Gauge) Set(float64) {}
+
+func (g *Gauge) With(...string) metrics.Gauge {
+	return g
+}
+
+type Histogram struct{}
+
+func (h *Histogram) Observe(float64) {}
+
+func (h *Histogram) With(...string) metrics.Histogram {
+	return h
+}
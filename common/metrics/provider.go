@@ -4,6 +4,10 @@ package metrics
 type Provider interface {
 	// NewCounter 创建一个计数器。
 	NewCounter(CounterOpts) Counter
+	// NewGauge 创建一个仪表。
+	NewGauge(GaugeOpts) Gauge
+	// NewHistogram 创建一个直方图。
+	NewHistogram(HistogramOpts) Histogram
 }
 
 type Counter interface {